@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminCookie cookie с административным токеном: браузер не отправляет
+// заголовок Authorization при открытии страницы IDE
+const AdminCookie = "admin_token"
+
+// DefaultAdminName имя администратора для токена без имени
+const DefaultAdminName = "admin"
+
+// Tokens набор административных токенов
+type Tokens struct {
+	entries []tokenEntry
+}
+
+// tokenEntry токен и имя администратора, которое пишется в deletedBy и т.п.
+type tokenEntry struct {
+	name string
+	hash [sha256.Size]byte
+}
+
+// ParseTokens разбирает список токенов через запятую. Токен можно записать как
+// "имя:токен", тогда имя будет идентификатором администратора; без имени — DefaultAdminName.
+func ParseTokens(s string) Tokens {
+	var t Tokens
+	for _, token := range strings.Split(s, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		name := DefaultAdminName
+		if n, rest, ok := strings.Cut(token, ":"); ok && n != "" && rest != "" {
+			name, token = n, rest
+		}
+		t.entries = append(t.entries, tokenEntry{name: name, hash: sha256.Sum256([]byte(token))})
+	}
+	return t
+}
+
+// Empty не задано ни одного токена
+func (t Tokens) Empty() bool {
+	return len(t.entries) == 0
+}
+
+// lookup сравнивает токен со всеми известными за постоянное время и возвращает имя администратора
+func (t Tokens) lookup(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	name := ""
+	for _, e := range t.entries {
+		if subtle.ConstantTimeCompare(sum[:], e.hash[:]) == 1 {
+			name = e.name
+		}
+	}
+	return name, name != ""
+}
+
+type adminKey struct{}
+
+// IsAdmin выполняется ли запрос с административным токеном
+func IsAdmin(ctx context.Context) bool {
+	return AdminName(ctx) != ""
+}
+
+// AdminName имя администратора, чьим токеном выполняется запрос; пусто без токена
+func AdminName(ctx context.Context) string {
+	name, _ := ctx.Value(adminKey{}).(string)
+	return name
+}
+
+// WithAdmin помечает контекст как административный от имени name
+func WithAdmin(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, adminKey{}, name)
+}
+
+// Middleware проверяет токен из "Authorization: Bearer <token>" или cookie admin_token
+// и помечает контекст запроса; запросы без токена проходят как обычные
+func (t Tokens) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name, ok := t.lookup(requestToken(r)); ok {
+			r = r.WithContext(WithAdmin(r.Context(), name))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin пропускает только запросы с административным токеном
+func (t Tokens) RequireAdmin(next http.Handler) http.Handler {
+	return t.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r.Context()) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="forozon"`)
+			http.Error(w, "admin token required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// requestToken токен из заголовка Authorization или cookie
+func requestToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+
+	if c, err := r.Cookie(AdminCookie); err == nil {
+		return c.Value
+	}
+
+	return ""
+}
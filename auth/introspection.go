@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// AdminIntrospection разрешает интроспекцию только запросам с административным токеном
+type AdminIntrospection struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = AdminIntrospection{}
+
+// ExtensionName имя расширения
+func (AdminIntrospection) ExtensionName() string {
+	return "AdminIntrospection"
+}
+
+// Validate проверка схемы не требуется
+func (AdminIntrospection) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext включает интроспекцию для администраторов
+func (AdminIntrospection) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	opCtx.DisableIntrospection = !IsAdmin(ctx)
+	return nil
+}
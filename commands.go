@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/graph/repository"
+	"github.com/YakovlevIgA/forozon/transfer"
+)
+
+// command административная команда: go run . <команда> [аргументы].
+// Команда работает с хранилищем из STORAGE напрямую, без HTTP сервера.
+type command func(ctx context.Context, storage domain.Storage, args []string) error
+
+// commands доступные команды
+var commands = map[string]command{
+	"repair-counters": repairCounters,
+	"export":          exportRecords,
+	"import":          importRecords,
+}
+
+// runCommand открывает хранилище, выполняет команду name и закрывает хранилище
+func runCommand(ctx context.Context, cfg config, name string, args []string) {
+	cmd, ok := commands[name]
+	if !ok {
+		fatal("unknown command",
+			slog.String("command", name),
+			slog.String("available", strings.Join(slices.Sorted(maps.Keys(commands)), ", ")),
+		)
+	}
+
+	storage, closers := openStorage(ctx, cfg)
+	err := cmd(ctx, storage, args)
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	for _, c := range closers {
+		if err := c.close(closeCtx); err != nil {
+			slog.Warn("failed to close resource", slog.String("resource", c.name), slog.Any("error", err))
+		}
+	}
+
+	if err != nil {
+		fatal("command failed", slog.String("command", name), slog.Any("error", err))
+	}
+}
+
+// openStorage хранилище для команды: без реплик и декораторов сервера
+func openStorage(ctx context.Context, cfg config) (domain.Storage, []closer) {
+	switch cfg.Storage {
+	case "postgres":
+		repo, pool, _, _ := initPG(ctx, nil)
+		return repo, []closer{{"postgres", closePool(pool)}}
+	case "sqlite":
+		storage, db, _ := initSQLite(ctx, cfg.SQLitePath)
+		return storage, []closer{{"sqlite", func(context.Context) error { return db.Close() }}}
+	default:
+		// Без каталога данных in-memory хранилище пусто, и изменения команды пропадут
+		if cfg.InMemoryDataDir == "" {
+			fatal("commands require persistent storage: set STORAGE or INMEMORY_DATA_DIR")
+		}
+		repo := initInMemory(ctx, cfg)
+		return repo, []closer{{"inmemory", repo.Close}}
+	}
+}
+
+// repairCounters пересчитывает счетчики комментариев с нуля
+func repairCounters(ctx context.Context, storage domain.Storage, _ []string) error {
+	repairer, ok := storage.(domain.CounterRepairer)
+	if !ok {
+		return errors.New("storage does not keep counters")
+	}
+
+	start := time.Now()
+	repaired, err := repairer.RepairCounters(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("repair-counters finished", slog.Int64("repaired", repaired), slog.Duration("took", time.Since(start)))
+	return nil
+}
+
+// exportRecords выгружает все посты и комментарии в NDJSON:
+// export [-o файл] [-page-size N]; без -o выгрузка пишется в stdout
+func exportRecords(ctx context.Context, storage domain.Storage, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "output file; stdout by default")
+	pageSize := flags.Int("page-size", transfer.DefaultPageSize, "posts read from storage at a time")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	start := time.Now()
+	stats, err := transfer.Export(ctx, storage, out, *pageSize)
+	if err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil && out != os.Stdout {
+		return fmt.Errorf("failed to write %s: %w", *output, err)
+	}
+
+	slog.Info("export finished",
+		slog.Int64("posts", stats.Posts),
+		slog.Int64("comments", stats.Comments),
+		slog.Duration("took", time.Since(start)),
+	)
+	return nil
+}
+
+// importRecords загружает посты и комментарии в хранилище:
+// import [-dry-run] [-from ndjson|snapshot|postgres] [-batch-size N] <источник>.
+// Источник — файл NDJSON (- для stdin), файл снимка in-memory хранилища или строка подключения к postgres.
+func importRecords(ctx context.Context, storage domain.Storage, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only validate records")
+	from := flags.String("from", "ndjson", "source format: ndjson, snapshot or postgres")
+	batchSize := flags.Int("batch-size", transfer.DefaultBatchSize, "records saved in one transaction")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: import [-dry-run] [-from ndjson|snapshot|postgres] [-batch-size N] <path|-|postgres url>")
+	}
+
+	src, closeSource, err := openSource(ctx, *from, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+
+	start := time.Now()
+	result, err := transfer.Import(ctx, storage, src, transfer.Options{DryRun: *dryRun, BatchSize: *batchSize})
+	for _, recordErr := range result.Errors {
+		slog.Error("invalid record", slog.Any("error", recordErr))
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.Info("import finished",
+		slog.Bool("dry_run", *dryRun),
+		slog.Int64("posts", result.Posts),
+		slog.Int64("comments", result.Comments),
+		slog.Int64("imported", result.Imported),
+		slog.Int64("skipped", result.Skipped),
+		slog.Duration("took", time.Since(start)),
+	)
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d invalid records", len(result.Errors))
+	}
+	return nil
+}
+
+// openSource поток записей для import и функция, закрывающая источник
+func openSource(ctx context.Context, from, path string) (transfer.Source, func(), error) {
+	switch from {
+	case "ndjson":
+		if path == "-" {
+			return transfer.NewDecoder(os.Stdin), func() {}, nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return transfer.NewDecoder(f), func() { f.Close() }, nil
+	case "snapshot":
+		repo, err := repository.LoadInMemorySnapshot(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return transfer.FromStorage(repo, transfer.DefaultPageSize), func() {}, nil
+	case "postgres":
+		// Источник только читается: миграции к нему не применяются, поэтому схема
+		// должна быть не старше той, под которую написаны запросы выгрузки
+		pool, err := pgxpool.Connect(ctx, path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to source postgres: %w", err)
+		}
+		if err := checkSourceSchema(ctx, pool); err != nil {
+			pool.Close()
+			return nil, nil, err
+		}
+		repo, err := repository.NewPostgresRepository(pool)
+		if err != nil {
+			pool.Close()
+			return nil, nil, err
+		}
+		return transfer.FromStorage(repo, transfer.DefaultPageSize), pool.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown source format %q: want ndjson, snapshot or postgres", from)
+	}
+}
+
+// checkSourceSchema проверяет, что схема postgres источника доведена до последней миграции
+func checkSourceSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	expected, err := latestMigration("./migrations")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var version uint
+	var dirty bool
+	err = pool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("source postgres has no migration version, apply migrations to it first: %w", err)
+	}
+	if err := checkSchemaVersion(version, dirty, expected); err != nil {
+		return fmt.Errorf("source postgres: %w: apply migrations to it first", err)
+	}
+	return nil
+}
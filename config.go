@@ -0,0 +1,371 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/logging"
+)
+
+// config настройки приложения из переменных окружения
+type config struct {
+	Env     string
+	Port    string
+	Storage string
+
+	SQLitePath string
+
+	PostgresReplicaURLs          []string
+	PostgresReplicaCheckInterval time.Duration
+	ReadYourWritesWindow         time.Duration
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	ShutdownTimeout   time.Duration
+	ShutdownDrain     time.Duration
+
+	TLSCertFile string
+	TLSKeyFile  string
+
+	ReadinessCheckInterval    time.Duration
+	ReadinessFailureThreshold time.Duration
+
+	LogLevel         slog.Level
+	LogRedactContent bool
+
+	TracingExporter    string
+	TracingSampleRatio float64
+
+	MetricsOperations []string
+
+	MaxComplexity int
+	MaxDepth      int
+	MaxAliases    int
+
+	QueryCacheSize         int
+	APQEnabled             bool
+	APQCacheSize           int
+	APQStore               string
+	APQMaxQueryBytes       int
+	APQTTL                 time.Duration
+	APQMaxStored           int
+	TrustedDocumentsFile   string
+	TrustedDocumentsStrict bool
+
+	Introspection string
+	IDE           string
+	IDEPath       string
+	IDEAccess     string
+	AdminTokens   string
+
+	IdempotencyStore string
+	IdempotencyTTL   time.Duration
+	IdempotencyLease time.Duration
+
+	DeletedRetention time.Duration
+	PurgeInterval    time.Duration
+
+	ErasureMode string
+
+	InMemoryDataDir          string
+	InMemoryFsync            string
+	InMemoryFsyncInterval    time.Duration
+	InMemorySnapshotInterval time.Duration
+
+	FeedTitle    string
+	FeedBaseURL  string
+	FeedPostURL  string
+	FeedLimit    int
+	FeedMaxLimit int
+	FeedAuthors  bool
+
+	StorageTimeout          time.Duration
+	StorageTimeouts         map[string]time.Duration
+	StorageRetryAttempts    int
+	StorageRetryBaseDelay   time.Duration
+	StorageRetryMaxDelay    time.Duration
+	StorageBreakerThreshold int
+	StorageBreakerCooldown  time.Duration
+	StorageCacheSize        int
+	StorageCacheTTL         time.Duration
+}
+
+// loadConfig читает настройки из окружения, подставляя значения по умолчанию
+func loadConfig() config {
+	env := envString("APP_ENV", envDevelopment)
+	production := env == envProduction
+
+	cfg := config{
+		Env:     env,
+		Port:    envString("PORT", defaultPort),
+		Storage: os.Getenv("STORAGE"),
+
+		SQLitePath: envString("SQLITE_PATH", "forozon.db"),
+
+		PostgresReplicaURLs:          envList("POSTGRES_REPLICA_URLS"),
+		PostgresReplicaCheckInterval: envDuration("POSTGRES_REPLICA_CHECK_INTERVAL", 5*time.Second),
+		ReadYourWritesWindow:         envDuration("READ_YOUR_WRITES_WINDOW", 5*time.Second),
+
+		ReadTimeout:       envDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		ReadHeaderTimeout: envDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		WriteTimeout:      envDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       envDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		MaxHeaderBytes:    envInt("HTTP_MAX_HEADER_BYTES", 1<<20),
+		ShutdownTimeout:   envDuration("SHUTDOWN_TIMEOUT", 20*time.Second),
+		ShutdownDrain:     envDuration("SHUTDOWN_DRAIN_DELAY", drainDelay(production)),
+
+		TLSCertFile: os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:  os.Getenv("TLS_KEY_FILE"),
+
+		ReadinessCheckInterval:    envDuration("READINESS_CHECK_INTERVAL", 5*time.Second),
+		ReadinessFailureThreshold: envDuration("READINESS_FAILURE_THRESHOLD", 15*time.Second),
+
+		LogRedactContent: envBool("LOG_REDACT_CONTENT", true),
+
+		TracingExporter:    envString("TRACING_EXPORTER", "none"),
+		TracingSampleRatio: envFloat("TRACING_SAMPLE_RATIO", 1),
+
+		MetricsOperations: envList("METRICS_OPERATIONS"),
+
+		MaxComplexity: envInt("GRAPHQL_MAX_COMPLEXITY", 5000),
+		MaxDepth:      envInt("GRAPHQL_MAX_DEPTH", 10),
+		MaxAliases:    envInt("GRAPHQL_MAX_ALIASES", 20),
+
+		QueryCacheSize:         envInt("GRAPHQL_QUERY_CACHE_SIZE", 1000),
+		APQEnabled:             envBool("GRAPHQL_APQ", true),
+		APQCacheSize:           envInt("GRAPHQL_APQ_CACHE_SIZE", 1000),
+		APQStore:               envString("GRAPHQL_APQ_STORE", "memory"),
+		APQMaxQueryBytes:       envInt("GRAPHQL_APQ_MAX_QUERY_BYTES", 16<<10),
+		APQTTL:                 envDuration("GRAPHQL_APQ_TTL", 7*24*time.Hour),
+		APQMaxStored:           envInt("GRAPHQL_APQ_MAX_STORED", 10000),
+		TrustedDocumentsFile:   os.Getenv("TRUSTED_DOCUMENTS_FILE"),
+		TrustedDocumentsStrict: envBool("TRUSTED_DOCUMENTS_STRICT", false),
+
+		Introspection: envString("GRAPHQL_INTROSPECTION", pick(production, "off", "on")),
+		IDE:           envString("GRAPHQL_IDE", pick(production, "none", "graphiql")),
+		IDEPath:       envString("GRAPHQL_IDE_PATH", "/graphiql"),
+		IDEAccess:     envString("GRAPHQL_IDE_ACCESS", pick(production, "admin", "public")),
+		AdminTokens:   os.Getenv("ADMIN_TOKENS"),
+
+		IdempotencyTTL:   envDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLease: envDuration("IDEMPOTENCY_LEASE", time.Minute),
+
+		DeletedRetention: envDuration("DELETED_RETENTION", 30*24*time.Hour),
+		PurgeInterval:    envDuration("PURGE_INTERVAL", time.Hour),
+
+		ErasureMode: envString("GDPR_ERASURE_MODE", "anonymize"),
+
+		InMemoryDataDir:          os.Getenv("INMEMORY_DATA_DIR"),
+		InMemoryFsync:            envString("INMEMORY_FSYNC", "interval"),
+		InMemoryFsyncInterval:    envDuration("INMEMORY_FSYNC_INTERVAL", time.Second),
+		InMemorySnapshotInterval: envDuration("INMEMORY_SNAPSHOT_INTERVAL", 10*time.Minute),
+
+		FeedTitle:    envString("FEED_TITLE", "forozon"),
+		FeedBaseURL:  os.Getenv("FEED_BASE_URL"),
+		FeedPostURL:  os.Getenv("FEED_POST_URL"),
+		FeedLimit:    envInt("FEED_LIMIT", 20),
+		FeedMaxLimit: envInt("FEED_MAX_LIMIT", 100),
+		FeedAuthors:  envBool("FEED_AUTHORS", true),
+
+		StorageTimeout:          envDuration("STORAGE_TIMEOUT", 5*time.Second),
+		StorageTimeouts:         envDurationMap("STORAGE_TIMEOUTS", "PurgeDeleted=1m,EraseAuthor=1m,ExportAuthor=30s,ExportRecords=30s,ImportRecords=1m"),
+		StorageRetryAttempts:    envInt("STORAGE_RETRY_ATTEMPTS", 3),
+		StorageRetryBaseDelay:   envDuration("STORAGE_RETRY_BASE_DELAY", 50*time.Millisecond),
+		StorageRetryMaxDelay:    envDuration("STORAGE_RETRY_MAX_DELAY", time.Second),
+		StorageBreakerThreshold: envInt("STORAGE_BREAKER_THRESHOLD", 5),
+		StorageBreakerCooldown:  envDuration("STORAGE_BREAKER_COOLDOWN", 10*time.Second),
+		StorageCacheSize:        envInt("STORAGE_CACHE_SIZE", 0),
+		StorageCacheTTL:         envDuration("STORAGE_CACHE_TTL", 30*time.Second),
+	}
+	cfg.IdempotencyStore = envString("IDEMPOTENCY_STORE", pick(cfg.Storage == "postgres", "postgres", "memory"))
+
+	requireOneOf("APP_ENV", cfg.Env, envDevelopment, envProduction)
+	requireOneOf("GRAPHQL_INTROSPECTION", cfg.Introspection, "on", "off", "admin")
+	requireOneOf("GRAPHQL_IDE", cfg.IDE, "graphiql", "apollo-sandbox", "altair", "none")
+	requireOneOf("GRAPHQL_IDE_ACCESS", cfg.IDEAccess, "public", "admin")
+	requireOneOf("IDEMPOTENCY_STORE", cfg.IdempotencyStore, "memory", "postgres", "none")
+	requireOneOf("GDPR_ERASURE_MODE", cfg.ErasureMode, "anonymize", "delete_posts")
+	requireOneOf("INMEMORY_FSYNC", cfg.InMemoryFsync, "always", "interval", "never")
+
+	requirePositive("READINESS_CHECK_INTERVAL", cfg.ReadinessCheckInterval)
+	requirePositive("GRAPHQL_APQ_TTL", cfg.APQTTL)
+	requirePositive("IDEMPOTENCY_TTL", cfg.IdempotencyTTL)
+	requirePositive("INMEMORY_SNAPSHOT_INTERVAL", cfg.InMemorySnapshotInterval)
+	requirePositive("PURGE_INTERVAL", cfg.PurgeInterval)
+	if cfg.DeletedRetention < 0 {
+		fatal("invalid config value", slog.String("key", "DELETED_RETENTION"), slog.Duration("value", cfg.DeletedRetention))
+	}
+
+	if cfg.IDEPath == "/query" || !strings.HasPrefix(cfg.IDEPath, "/") {
+		fatal("invalid config value", slog.String("key", "GRAPHQL_IDE_PATH"), slog.String("value", cfg.IDEPath))
+	}
+
+	level, err := logging.ParseLevel(envString("LOG_LEVEL", "info"))
+	if err != nil {
+		fatal("invalid config value", slog.String("key", "LOG_LEVEL"), slog.Any("error", err))
+	}
+	cfg.LogLevel = level
+
+	if cfg.FeedLimit < 1 || cfg.FeedMaxLimit < cfg.FeedLimit {
+		fatal("FEED_LIMIT must be positive and not above FEED_MAX_LIMIT")
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		fatal("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	return cfg
+}
+
+// TLSEnabled включен ли TLS
+func (c config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// Окружения приложения
+const (
+	envDevelopment = "development"
+	envProduction  = "production"
+)
+
+// pick значение по условию
+func pick(cond bool, ifTrue, ifFalse string) string {
+	if cond {
+		return ifTrue
+	}
+	return ifFalse
+}
+
+// drainDelay пауза перед остановкой по умолчанию: в production балансировщику
+// нужно время, чтобы заметить /readyz 503 и перестать слать запросы
+func drainDelay(production bool) time.Duration {
+	if production {
+		return 5 * time.Second
+	}
+	return 0
+}
+
+// requireOneOf завершает процесс, если значение не из списка допустимых
+func requireOneOf(key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		fatal("invalid config value",
+			slog.String("key", key),
+			slog.String("value", value),
+			slog.String("allowed", strings.Join(allowed, ", ")),
+		)
+	}
+}
+
+// requirePositive завершает процесс, если длительность не больше нуля
+func requirePositive(key string, d time.Duration) {
+	if d <= 0 {
+		fatal("invalid config value", slog.String("key", key), slog.Duration("value", d))
+	}
+}
+
+// envString возвращает значение переменной или значение по умолчанию
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envList разбирает переменную как список через запятую
+func envList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// envDuration разбирает переменную как time.Duration (например, "30s")
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		fatal("invalid config value", slog.String("key", key), slog.String("value", v), slog.Any("error", err))
+	}
+
+	return d
+}
+
+// envDurationMap разбирает переменную вида "key=30s,other=1m"
+func envDurationMap(key, def string) map[string]time.Duration {
+	v := envString(key, def)
+
+	m := make(map[string]time.Duration)
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil {
+			fatal("invalid config value", slog.String("key", key), slog.String("value", pair))
+		}
+		m[strings.TrimSpace(name)] = d
+	}
+
+	return m
+}
+
+// envInt разбирает переменную как целое число
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		fatal("invalid config value", slog.String("key", key), slog.String("value", v), slog.Any("error", err))
+	}
+
+	return n
+}
+
+// envBool разбирает переменную как bool (true/false/1/0)
+func envBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		fatal("invalid config value", slog.String("key", key), slog.String("value", v), slog.Any("error", err))
+	}
+
+	return b
+}
+
+// envFloat разбирает переменную как число с плавающей точкой
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		fatal("invalid config value", slog.String("key", key), slog.String("value", v), slog.Any("error", err))
+	}
+
+	return f
+}
@@ -0,0 +1,43 @@
+package domain
+
+// Comment комментарий к посту; ParentID пустой у комментариев верхнего уровня,
+// UpdatedAt — у комментариев, которые не изменялись
+type Comment struct {
+	ID         string  `json:"id"`
+	PostID     string  `json:"postID"`
+	ParentID   *string `json:"parentID,omitempty"`
+	AuthorID   string  `json:"authorID"`
+	Content    string  `json:"content"`
+	CreatedAt  string  `json:"createdAt"`
+	Version    int32   `json:"version"`
+	DeletedAt  *string `json:"deletedAt,omitempty"`
+	DeletedBy  *string `json:"deletedBy,omitempty"`
+	ReplyCount int32   `json:"replyCount"`
+	UpdatedAt  *string `json:"updatedAt,omitempty"`
+}
+
+// CommentWithReplies комментарий с деревом ответов
+type CommentWithReplies struct {
+	ID         string                `json:"id"`
+	PostID     string                `json:"postID"`
+	ParentID   *string               `json:"parentID,omitempty"`
+	AuthorID   string                `json:"authorID"`
+	Content    string                `json:"content"`
+	CreatedAt  string                `json:"createdAt"`
+	Version    int32                 `json:"version"`
+	DeletedAt  *string               `json:"deletedAt,omitempty"`
+	DeletedBy  *string               `json:"deletedBy,omitempty"`
+	ReplyCount int32                 `json:"replyCount"`
+	UpdatedAt  *string               `json:"updatedAt,omitempty"`
+	Replies    []*CommentWithReplies `json:"replies"`
+}
+
+// Deleted удален ли комментарий (мягкое удаление)
+func (c *Comment) Deleted() bool {
+	return c.DeletedAt != nil
+}
+
+// Deleted удален ли комментарий (мягкое удаление)
+func (c *CommentWithReplies) Deleted() bool {
+	return c.DeletedAt != nil
+}
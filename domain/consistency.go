@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites помечает контекст: клиент недавно изменял данные, и чтения
+// должны видеть его изменения (хранилище с репликами читает с основного сервера)
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// ReadYourWrites должно ли чтение видеть недавние изменения клиента
+func ReadYourWrites(ctx context.Context) bool {
+	v, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return v
+}
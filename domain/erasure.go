@@ -0,0 +1,31 @@
+package domain
+
+// ErasedAuthor подставляется вместо автора и текста при анонимизации
+const ErasedAuthor = "[deleted user]"
+
+// ErasureMode способ удаления данных автора по запросу GDPR
+type ErasureMode string
+
+const (
+	// ErasureModeAnonymize посты и комментарии остаются на месте без автора и текста
+	ErasureModeAnonymize ErasureMode = "ANONYMIZE"
+	// ErasureModeDeletePosts посты удаляются целиком, комментарии анонимизируются
+	ErasureModeDeletePosts ErasureMode = "DELETE_POSTS"
+)
+
+// ErasureResult сколько записей удалено и анонимизировано
+type ErasureResult struct {
+	AuthorID           string      `json:"authorID"`
+	Mode               ErasureMode `json:"mode"`
+	PostsDeleted       int32       `json:"postsDeleted"`
+	PostsAnonymized    int32       `json:"postsAnonymized"`
+	CommentsAnonymized int32       `json:"commentsAnonymized"`
+}
+
+// AuthorArchive все данные автора для ответа на запрос о доступе к данным
+type AuthorArchive struct {
+	AuthorID   string     `json:"authorID"`
+	ExportedAt string     `json:"exportedAt"`
+	Posts      []*Post    `json:"posts"`
+	Comments   []*Comment `json:"comments"`
+}
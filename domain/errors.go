@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Коды доменных ошибок, которые видят клиенты в extensions.code
+const (
+	CodeNotFound         = "NOT_FOUND"
+	CodeCommentsDisabled = "COMMENTS_DISABLED"
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeConflict         = "CONFLICT"
+)
+
+// Шаблоны для errors.Is: совпадают с любой ошибкой того же кода
+var (
+	ErrNotFound         = &Error{Code: CodeNotFound}
+	ErrCommentsDisabled = &Error{Code: CodeCommentsDisabled}
+	ErrValidationFailed = &Error{Code: CodeValidationFailed}
+	ErrForbidden        = &Error{Code: CodeForbidden}
+	ErrConflict         = &Error{Code: CodeConflict}
+)
+
+// FieldError ошибка валидации конкретного поля
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error доменная ошибка; текст безопасно показывать клиенту
+type Error struct {
+	Code    string
+	Message string
+	// Fields поля, не прошедшие валидацию (для VALIDATION_FAILED)
+	Fields []FieldError
+	// Details дополнительные данные для клиента, например текущая версия при конфликте
+	Details map[string]any
+}
+
+// Error реализует error
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return strings.ToLower(strings.ReplaceAll(e.Code, "_", " "))
+	}
+	return e.Message
+}
+
+// Is сравнивает ошибки по коду
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// NotFound сущность не найдена
+func NotFound(entity, id string) *Error {
+	return &Error{
+		Code:    CodeNotFound,
+		Message: fmt.Sprintf("%s not found", entity),
+		Details: map[string]any{"entity": entity, "id": id},
+	}
+}
+
+// CommentsDisabled комментарии к посту отключены
+func CommentsDisabled(postID string) *Error {
+	return &Error{
+		Code:    CodeCommentsDisabled,
+		Message: "comments are disabled for this post",
+		Details: map[string]any{"postID": postID},
+	}
+}
+
+// ValidationFailed входные данные не прошли проверку
+func ValidationFailed(fields ...FieldError) *Error {
+	return &Error{
+		Code:    CodeValidationFailed,
+		Message: "validation failed",
+		Fields:  fields,
+	}
+}
+
+// Forbidden действие запрещено
+func Forbidden(message string) *Error {
+	return &Error{Code: CodeForbidden, Message: message}
+}
+
+// Conflict состояние изменилось или уже существует
+func Conflict(message string, details map[string]any) *Error {
+	return &Error{Code: CodeConflict, Message: message, Details: details}
+}
+
+// VersionConflict сущность изменилась после того, как клиент ее прочитал
+func VersionConflict(entity string, currentVersion int32) *Error {
+	return &Error{
+		Code:    CodeConflict,
+		Message: fmt.Sprintf("%s was modified concurrently, current version is %d", entity, currentVersion),
+		Fields:  []FieldError{{Field: "expectedVersion", Message: fmt.Sprintf("current version is %d", currentVersion)}},
+		Details: map[string]any{"entity": entity, "currentVersion": currentVersion},
+	}
+}
@@ -0,0 +1,24 @@
+package domain
+
+// Post пост; комментарии читаются отдельно (GetCommentsForPost), а их число
+// и время последнего хранятся в счетчиках CommentCount и LastCommentAt.
+// UpdatedAt время последней правки; nil, если пост не изменялся
+type Post struct {
+	ID               string  `json:"id"`
+	Title            string  `json:"title"`
+	Content          string  `json:"content"`
+	AuthorID         string  `json:"authorID"`
+	CreatedAt        string  `json:"createdAt"`
+	CommentsDisabled bool    `json:"commentsDisabled"`
+	Version          int32   `json:"version"`
+	DeletedAt        *string `json:"deletedAt,omitempty"`
+	DeletedBy        *string `json:"deletedBy,omitempty"`
+	CommentCount     int32   `json:"commentCount"`
+	LastCommentAt    *string `json:"lastCommentAt,omitempty"`
+	UpdatedAt        *string `json:"updatedAt,omitempty"`
+}
+
+// Deleted удален ли пост (мягкое удаление)
+func (p *Post) Deleted() bool {
+	return p.DeletedAt != nil
+}
@@ -0,0 +1,11 @@
+package domain
+
+// Revision предыдущая версия поста или комментария и правка, которая ее заменила
+type Revision struct {
+	Version  int32   `json:"version"`
+	Title    *string `json:"title,omitempty"`
+	Content  string  `json:"content"`
+	EditorID string  `json:"editorID"`
+	Reason   *string `json:"reason,omitempty"`
+	EditedAt string  `json:"editedAt"`
+}
@@ -0,0 +1,67 @@
+// Package domain модели, ошибки и интерфейс хранилища, общие для GraphQL слоя и репозиториев
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Storage интерфейс хранилища. Ожидаемые ошибки (не найдено, невалидные данные
+// и т.п.) возвращаются как *Error, остальные считаются внутренними.
+// Общие требования к реализациям проверяет пакет storagetest.
+type Storage interface {
+	// GetPosts все посты в порядке создания без комментариев
+	GetPosts(ctx context.Context) ([]*Post, error)
+	// GetLatestPosts до limit последних неудаленных постов от новых к старым;
+	// непустой authorID оставляет только посты этого автора
+	GetLatestPosts(ctx context.Context, authorID string, limit int) ([]*Post, error)
+	// GetPostByID пост без комментариев; NOT_FOUND, если поста нет
+	GetPostByID(ctx context.Context, id string) (*Post, error)
+	CreatePost(ctx context.Context, title, content, authorID string, commentsDisabled bool) (*Post, error)
+	AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*Comment, error)
+	// GetCommentsForPost до limit комментариев поста в порядке создания после комментария cursor,
+	// собранных в дерево
+	GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*CommentWithReplies, error)
+	// GetLatestComments до limit последних неудаленных комментариев поста всех уровней
+	// от новых к старым
+	GetLatestComments(ctx context.Context, postID string, limit int) ([]*Comment, error)
+	// UpdatePost изменяет пост, если его версия равна expectedVersion; nil-поля не меняются.
+	// Предыдущая версия сохраняется в истории с editorID и reason.
+	UpdatePost(ctx context.Context, id string, expectedVersion int32, title, content *string, editorID string, reason *string) (*Post, error)
+	// UpdateComment изменяет комментарий, если его версия равна expectedVersion
+	UpdateComment(ctx context.Context, id string, expectedVersion int32, content string, editorID string, reason *string) (*Comment, error)
+	// GetPostRevisions предыдущие версии поста по возрастанию, начиная после версии after
+	GetPostRevisions(ctx context.Context, postID string, limit int, after *int32) ([]*Revision, error)
+	// GetCommentRevisions предыдущие версии комментария по возрастанию, начиная после версии after
+	GetCommentRevisions(ctx context.Context, commentID string, limit int, after *int32) ([]*Revision, error)
+	// DeletePost мягко удаляет пост; комментарии остаются на месте
+	DeletePost(ctx context.Context, id, deletedBy string) (*Post, error)
+	// DeleteComment мягко удаляет комментарий; ответы на него остаются в ветке
+	DeleteComment(ctx context.Context, id, deletedBy string) (*Comment, error)
+	// RestorePost отменяет мягкое удаление поста
+	RestorePost(ctx context.Context, id string) (*Post, error)
+	// RestoreComment отменяет мягкое удаление комментария
+	RestoreComment(ctx context.Context, id string) (*Comment, error)
+	// PurgeDeleted окончательно удаляет посты и комментарии, удаленные раньше before.
+	// Комментарии, на которые остались ответы, не удаляются, чтобы не потерять ветку.
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	// EraseAuthor удаляет или анонимизирует все данные автора одной транзакцией
+	EraseAuthor(ctx context.Context, authorID string, mode ErasureMode) (*ErasureResult, error)
+	// ExportAuthor все посты и комментарии автора, включая удаленные, на один момент времени
+	ExportAuthor(ctx context.Context, authorID string) (*AuthorArchive, error)
+	// ExportRecords до limit постов после позиции after в порядке создания, включая удаленные;
+	// nil — с начала. За каждым постом идут все его комментарии, родитель раньше ответов.
+	ExportRecords(ctx context.Context, limit int, after *RecordCursor) ([]*Record, error)
+	// ImportRecords сохраняет записи одной транзакцией, сохраняя id, время и историю изменений.
+	// Записи с уже существующими id пропускаются, поэтому загрузку можно повторить
+	// с начала после сбоя. Возвращает число сохраненных записей.
+	ImportRecords(ctx context.Context, records []*Record) (int64, error)
+}
+
+// CounterRepairer хранилище с денормализованными счетчиками (Post.CommentCount,
+// Post.LastCommentAt, Comment.ReplyCount). Счетчики учитывают только неудаленные
+// комментарии и поддерживаются при каждом изменении; RepairCounters пересчитывает
+// их с нуля и возвращает число исправленных постов и комментариев.
+type CounterRepairer interface {
+	RepairCounters(ctx context.Context) (int64, error)
+}
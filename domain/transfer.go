@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RecordType вид записи выгрузки
+type RecordType string
+
+const (
+	RecordPost    RecordType = "post"
+	RecordComment RecordType = "comment"
+)
+
+// Record запись выгрузки (строка NDJSON): пост или комментарий вместе с историей изменений.
+// Счетчики комментариев при загрузке не переносятся, а считаются заново.
+type Record struct {
+	Type      RecordType  `json:"type"`
+	Post      *Post       `json:"post,omitempty"`
+	Comment   *Comment    `json:"comment,omitempty"`
+	Revisions []*Revision `json:"revisions,omitempty"`
+}
+
+// ID id поста или комментария записи
+func (r *Record) ID() string {
+	switch {
+	case r.Post != nil:
+		return r.Post.ID
+	case r.Comment != nil:
+		return r.Comment.ID
+	}
+	return ""
+}
+
+// RecordCursor позиция выгрузки: время создания и id последнего выгруженного поста.
+// Следующая страница начинается с первого поста после этих значений, а не после поста
+// с этим id, поэтому удаление поста во время выгрузки ее не обрывает.
+type RecordCursor struct {
+	CreatedAt string
+	ID        string
+}
+
+// CursorAfter позиция сразу после поста p
+func CursorAfter(p *Post) *RecordCursor {
+	return &RecordCursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+// Validate VALIDATION_FAILED, если курсор заполнен не полностью
+func (c *RecordCursor) Validate() error {
+	if c.CreatedAt == "" || c.ID == "" {
+		return ValidationFailed(FieldError{Field: "cursor", Message: "cursor must have createdAt and id"})
+	}
+	return nil
+}
+
+// timestampLayouts форматы времени в хранилищах: time.Time.String (in-memory, postgres)
+// и RFC 3339 (sqlite, время удаления)
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	time.RFC3339Nano,
+}
+
+// ParseTimestamp разбирает время в любом из форматов хранилищ
+func ParseTimestamp(s string) (time.Time, error) {
+	// Показание монотонных часов, если время было записано без UTC()
+	if i := strings.Index(s, " m="); i >= 0 {
+		s = s[:i]
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format %q", s)
+}
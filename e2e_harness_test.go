@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/YakovlevIgA/forozon/auth"
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/feed"
+	"github.com/YakovlevIgA/forozon/graph"
+	"github.com/YakovlevIgA/forozon/graph/repository"
+	"github.com/YakovlevIgA/forozon/metrics"
+	"github.com/YakovlevIgA/forozon/storagemw"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var update = flag.Bool("update", false, "перезаписать golden-файлы e2e тестов")
+
+const (
+	e2eDir        = "testdata/e2e"
+	e2eAdminToken = "e2e-admin"
+	e2eTimeout    = 5 * time.Second
+)
+
+// e2eBackends хранилища, против которых запускаются e2e тесты; postgres — если задан TEST_POSTGRES_URL
+func e2eBackends() []string {
+	backends := []string{"inmemory", "sqlite"}
+	if os.Getenv("TEST_POSTGRES_URL") != "" {
+		backends = append(backends, "postgres")
+	}
+	return backends
+}
+
+// forEachBackend запускает тест против каждого хранилища с новым сервером
+func forEachBackend(t *testing.T, test func(t *testing.T, h *e2eHarness)) {
+	for _, backend := range e2eBackends() {
+		t.Run(backend, func(t *testing.T) {
+			test(t, newE2EHarness(t, backend))
+		})
+	}
+}
+
+// e2eHarness настоящий GraphQL сервер поверх httptest.Server.
+// Идентификаторы в ответах заменяются на <id:N> в порядке появления,
+// поэтому номера совпадают во всех ответах одного теста.
+type e2eHarness struct {
+	server     *httptest.Server
+	ids        map[string]int
+	subscribed chan struct{}
+}
+
+// newE2EHarness поднимает сервер с хранилищем backend; все ресурсы освобождаются в t.Cleanup
+func newE2EHarness(t *testing.T, backend string) *e2eHarness {
+	t.Helper()
+
+	cfg := loadConfig()
+	cfg.Introspection = "on"
+	cfg.AdminTokens = e2eAdminToken
+	cfg.IdempotencyStore = "memory"
+	cfg.ErasureMode = "anonymize"
+	// Кэш включен, чтобы сценарии проверяли и его инвалидацию
+	cfg.StorageCacheSize = 100
+
+	appMetrics := metrics.New(prometheus.NewRegistry())
+	storage := storagemw.Chain(e2eStorage(t, backend), storageMiddlewares(cfg, appMetrics, backend, nil)...)
+
+	resolver := graph.NewResolver(storage)
+	resolver.Idempotency = newIdempotencyGuard(context.Background(), cfg, nil)
+	resolver.ErasureMode = domain.ErasureMode(strings.ToUpper(cfg.ErasureMode))
+
+	h := &e2eHarness{ids: map[string]int{}, subscribed: make(chan struct{}, 16)}
+
+	srv := newGraphQLServer(context.Background(), cfg, resolver, appMetrics, nil)
+	// Резолвер подписки уже вызван, когда next вернул управление: после сигнала
+	// опубликованные события не потеряются
+	srv.AroundOperations(func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		handler := next(ctx)
+		if op := graphql.GetOperationContext(ctx).Operation; op != nil && op.Operation == ast.Subscription {
+			h.subscribed <- struct{}{}
+		}
+		return handler
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	subscriptions := newSubscriptionTracker()
+
+	mux := http.NewServeMux()
+	mux.Handle("/query", queryHandler(logger, auth.ParseTokens(cfg.AdminTokens), subscriptions, cfg.ReadYourWritesWindow, srv))
+	mux.Handle("/feeds/", feed.Handler(storage, feedConfig(cfg)))
+
+	h.server = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		subscriptions.closeAll()
+		h.server.Close()
+	})
+
+	return h
+}
+
+// e2eStorage пустое хранилище выбранного типа
+func e2eStorage(t *testing.T, backend string) domain.Storage {
+	t.Helper()
+	ctx := context.Background()
+
+	switch backend {
+	case "inmemory":
+		return repository.NewInMemoryRepository()
+	case "sqlite":
+		db, err := repository.OpenSQLite(ctx, filepath.Join(t.TempDir(), "e2e.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		driver, err := sqlite.WithInstance(db, &sqlite.Config{})
+		if err != nil {
+			t.Fatalf("migrate driver: %v", err)
+		}
+		if _, err := applyMigrations("./migrations/sqlite", "sqlite", driver); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+
+		repo, err := repository.NewSQLiteRepository(db)
+		if err != nil {
+			t.Fatalf("NewSQLiteRepository: %v", err)
+		}
+		return repo
+	case "postgres":
+		url := os.Getenv("TEST_POSTGRES_URL")
+
+		db, err := sql.Open("postgres", url)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		defer db.Close()
+
+		driver, err := postgres.WithInstance(db, &postgres.Config{})
+		if err != nil {
+			t.Fatalf("migrate driver: %v", err)
+		}
+		if _, err := applyMigrations("./migrations", "postgres", driver); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+
+		pool, err := pgxpool.Connect(ctx, url)
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		t.Cleanup(pool.Close)
+
+		if _, err := pool.Exec(ctx, "TRUNCATE posts, comments, post_revisions, comment_revisions"); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+
+		repo, err := repository.NewPostgresRepository(pool)
+		if err != nil {
+			t.Fatalf("NewPostgresRepository: %v", err)
+		}
+		return repo
+	default:
+		t.Fatalf("unknown backend %q", backend)
+		return nil
+	}
+}
+
+// e2eRequest параметры запроса к /query
+type e2eRequest struct {
+	vars   map[string]any
+	header http.Header
+}
+
+// e2eOption настраивает запрос
+type e2eOption func(r *e2eRequest)
+
+// withVars переменные GraphQL запроса
+func withVars(vars map[string]any) e2eOption {
+	return func(r *e2eRequest) { r.vars = vars }
+}
+
+// asAdmin запрос с админ-токеном
+func asAdmin() e2eOption {
+	return withHeader("Authorization", "Bearer "+e2eAdminToken)
+}
+
+// withHeader дополнительный HTTP заголовок
+func withHeader(key, value string) e2eOption {
+	return func(r *e2eRequest) { r.header.Set(key, value) }
+}
+
+// run выполняет документ testdata/e2e/<doc>.graphql и сравнивает ответ с testdata/e2e/<golden>.json.
+// Возвращает ответ без нормализации, чтобы следующие шаги могли взять из него id.
+func (h *e2eHarness) run(t *testing.T, golden, doc string, opts ...e2eOption) map[string]any {
+	t.Helper()
+
+	resp := h.query(t, readDocument(t, doc), opts...)
+	h.assertGolden(t, golden, resp)
+
+	return resp
+}
+
+// query отправляет документ POST-запросом и возвращает разобранный ответ
+func (h *e2eHarness) query(t *testing.T, document string, opts ...e2eOption) map[string]any {
+	t.Helper()
+
+	req := e2eRequest{header: http.Header{}}
+	for _, opt := range opts {
+		opt(&req)
+	}
+
+	body, err := json.Marshal(map[string]any{"query": document, "variables": req.vars})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, h.server.URL+"/query", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	httpReq.Header = req.header
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := h.server.Client().Do(httpReq)
+	if err != nil {
+		t.Fatalf("POST /query: %v", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp map[string]any
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response (status %d): %v", httpResp.StatusCode, err)
+	}
+
+	return resp
+}
+
+// get GET-запрос к серверу с заголовками header
+func (h *e2eHarness) get(t *testing.T, path string, header http.Header) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, h.server.URL+path, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if header != nil {
+		req.Header = header
+	}
+
+	resp, err := h.server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return resp, string(body)
+}
+
+// readDocument текст GraphQL документа из testdata/e2e
+func readDocument(t *testing.T, name string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join(e2eDir, name+".graphql"))
+	if err != nil {
+		t.Fatalf("read document: %v", err)
+	}
+	return string(b)
+}
+
+// assertGolden сравнивает нормализованный ответ с golden-файлом; с -update перезаписывает файл
+func (h *e2eHarness) assertGolden(t *testing.T, golden string, resp any) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(h.normalize(resp)); err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	got := buf.Bytes()
+
+	path := filepath.Join(e2eDir, golden+".json")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden (run with -update to create): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s: response differs from golden file\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// normalize заменяет изменчивые значения: UUID — на <id:N>, непустые поля *At — на <time>.
+// Ключи объектов обходятся по порядку, чтобы номера id не зависели от порядка map.
+func (h *e2eHarness) normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		out := make(map[string]any, len(v))
+		for _, k := range keys {
+			if s, ok := v[k].(string); ok && strings.HasSuffix(k, "At") && s != "" {
+				out[k] = "<time>"
+				continue
+			}
+			out[k] = h.normalize(v[k])
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			out[i] = h.normalize(v[i])
+		}
+		return out
+	case string:
+		return uuidPattern.ReplaceAllStringFunc(v, func(id string) string {
+			n, ok := h.ids[id]
+			if !ok {
+				n = len(h.ids) + 1
+				h.ids[id] = n
+			}
+			return fmt.Sprintf("<id:%d>", n)
+		})
+	default:
+		return v
+	}
+}
+
+// field значение по пути через точку ("data.postCreate.post.id")
+func field(t *testing.T, resp map[string]any, path string) string {
+	t.Helper()
+
+	var v any = resp
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			t.Fatalf("%s: %q is not an object in %v", path, key, resp)
+		}
+		v = obj[key]
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		t.Fatalf("%s: expected string, got %v", path, v)
+	}
+	return s
+}
+
+// e2eSubscription клиент подписки по протоколу graphql-transport-ws
+type e2eSubscription struct {
+	h    *e2eHarness
+	conn *websocket.Conn
+}
+
+// wsMessage сообщение протокола graphql-transport-ws
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// subscribe открывает websocket, подписывается документом testdata/e2e/<doc>.graphql
+// и ждет, пока резолвер подписки будет вызван
+func (h *e2eHarness) subscribe(t *testing.T, doc string, vars map[string]any) *e2eSubscription {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}, HandshakeTimeout: e2eTimeout}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(h.server.URL, "http")+"/query", nil)
+	if err != nil {
+		t.Fatalf("dial websocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	s := &e2eSubscription{h: h, conn: conn}
+	s.send(t, wsMessage{Type: "connection_init"})
+	if msg := s.read(t); msg.Type != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %s: %s", msg.Type, msg.Payload)
+	}
+
+	payload, err := json.Marshal(map[string]any{"query": readDocument(t, doc), "variables": vars})
+	if err != nil {
+		t.Fatalf("marshal subscribe: %v", err)
+	}
+	s.send(t, wsMessage{ID: "1", Type: "subscribe", Payload: payload})
+
+	select {
+	case <-h.subscribed:
+	case <-time.After(e2eTimeout):
+		t.Fatalf("subscription was not started in %s", e2eTimeout)
+	}
+
+	return s
+}
+
+// next ждет следующее событие подписки и сравнивает его с testdata/e2e/<golden>.json
+func (s *e2eSubscription) next(t *testing.T, golden string) {
+	t.Helper()
+
+	for {
+		msg := s.read(t)
+		switch msg.Type {
+		case "ping":
+			s.send(t, wsMessage{Type: "pong"})
+		case "next":
+			var resp map[string]any
+			if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+				t.Fatalf("decode next: %v", err)
+			}
+			s.h.assertGolden(t, golden, resp)
+			return
+		default:
+			t.Fatalf("unexpected %s message: %s", msg.Type, msg.Payload)
+		}
+	}
+}
+
+// send отправляет сообщение протокола
+func (s *e2eSubscription) send(t *testing.T, msg wsMessage) {
+	t.Helper()
+
+	if err := s.conn.WriteJSON(msg); err != nil {
+		t.Fatalf("websocket write: %v", err)
+	}
+}
+
+// read читает сообщение протокола, не дольше e2eTimeout
+func (s *e2eSubscription) read(t *testing.T) wsMessage {
+	t.Helper()
+
+	if err := s.conn.SetReadDeadline(time.Now().Add(e2eTimeout)); err != nil {
+		t.Fatalf("set read deadline: %v", err)
+	}
+
+	var msg wsMessage
+	if err := s.conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("websocket read: %v", err)
+	}
+	return msg
+}
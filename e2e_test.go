@@ -0,0 +1,194 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Документы в testdata/e2e повторяют примеры из README; вместо "HERE" — переменные.
+// Ответы сравниваются с golden-файлами, обновить их: go test -run E2E -update
+
+func TestMain(m *testing.M) {
+	// Репозитории пишут в лог каждое изменение; в тестах это только шум
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+func TestE2EReadmeExamples(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, h *e2eHarness) {
+		postID := field(t, h.run(t, "create_post", "create_post"), "data.createPost.id")
+		h.run(t, "post_create", "post_create")
+
+		commentID := field(t, h.run(t, "comment_add", "comment_add",
+			withVars(map[string]any{"postID": postID})), "data.commentAdd.comment.id")
+		parentID := field(t, h.run(t, "add_comment", "add_comment",
+			withVars(map[string]any{"postID": postID})), "data.addComment.id")
+		h.run(t, "add_reply", "add_reply", withVars(map[string]any{"postID": postID, "parentID": parentID}))
+
+		h.run(t, "posts", "posts")
+		h.run(t, "post_with_comments", "post_with_comments", withVars(map[string]any{"id": postID}))
+		h.run(t, "comments", "comments", withVars(map[string]any{"postID": postID}))
+
+		h.run(t, "post_update", "post_update", withVars(map[string]any{"id": postID}))
+		h.run(t, "post_update_conflict", "post_update", withVars(map[string]any{"id": postID}))
+		h.run(t, "comment_update", "comment_update", withVars(map[string]any{"id": commentID}))
+		h.run(t, "post_history", "post_history", withVars(map[string]any{"id": postID}))
+
+		h.run(t, "post_delete_forbidden", "post_delete", withVars(map[string]any{"id": postID}))
+		h.run(t, "comment_delete_forbidden", "comment_delete", withVars(map[string]any{"id": commentID}))
+		h.run(t, "comment_delete", "comment_delete", withVars(map[string]any{"id": commentID}), asAdmin())
+		h.run(t, "comment_restore_forbidden", "comment_restore", withVars(map[string]any{"id": commentID}))
+		h.run(t, "comment_restore", "comment_restore", withVars(map[string]any{"id": commentID}), asAdmin())
+
+		h.run(t, "author_export", "author_export", asAdmin())
+		h.run(t, "author_erase", "author_erase", asAdmin())
+		h.run(t, "post_with_comments_erased", "post_with_comments", withVars(map[string]any{"id": postID}))
+	})
+}
+
+func TestE2EIdempotencyKey(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, h *e2eHarness) {
+		key := withHeader("Idempotency-Key", "5f1c")
+
+		// Повтор с тем же ключом возвращает тот же пост: golden-файл общий для обоих ответов
+		postID := field(t, h.run(t, "post_create_idempotent", "post_create_idempotent", key), "data.postCreate.post.id")
+		h.run(t, "post_create_idempotent", "post_create_idempotent", key)
+		h.run(t, "posts_idempotent", "posts")
+
+		// Сохраненный ответ содержал текст автора и удаляется вместе с его данными:
+		// повтор после authorErase выполняет мутацию заново
+		h.run(t, "author_erase_idempotent", "author_erase_idempotent", asAdmin())
+		replayed := h.query(t, readDocument(t, "post_create_idempotent"), key)
+		if id := field(t, replayed, "data.postCreate.post.id"); id == postID {
+			t.Errorf("replay after authorErase returned the erased post %s", id)
+		}
+	})
+}
+
+func TestE2EDataImport(t *testing.T) {
+	records := strings.Join([]string{
+		`{"type":"post","post":{"id":"imported-post","title":"t","content":"c","authorID":"a","createdAt":"2024-01-02T03:04:05Z","version":1}}`,
+		`{"type":"comment","comment":{"id":"imported-root","postID":"imported-post","authorID":"b","content":"root","createdAt":"2024-01-02T04:00:00Z","version":1}}`,
+		`{"type":"comment","comment":{"id":"imported-reply","postID":"imported-post","parentID":"imported-root","authorID":"c","content":"reply","createdAt":"2024-01-02T05:00:00Z","version":1}}`,
+	}, "\n")
+	// Ответ раньше родителя: проверка без сохранения находит ошибку порядка
+	misordered := strings.Join([]string{
+		`{"type":"post","post":{"id":"imported-post","title":"t","content":"c","authorID":"a","createdAt":"2024-01-02T03:04:05Z","version":1}}`,
+		`{"type":"comment","comment":{"id":"imported-reply","postID":"imported-post","parentID":"imported-root","authorID":"c","content":"reply","createdAt":"2024-01-02T05:00:00Z","version":1}}`,
+	}, "\n")
+
+	forEachBackend(t, func(t *testing.T, h *e2eHarness) {
+		vars := func(ndjson string, dryRun bool) e2eOption {
+			return withVars(map[string]any{"ndjson": ndjson, "dryRun": dryRun})
+		}
+
+		h.run(t, "data_import_forbidden", "data_import", vars(records, false))
+		h.run(t, "data_import_misordered", "data_import", vars(misordered, true), asAdmin())
+		h.run(t, "data_import", "data_import", vars(records, false), asAdmin())
+		// Повторная загрузка пропускает уже сохраненные записи
+		h.run(t, "data_import_repeated", "data_import", vars(records, false), asAdmin())
+		h.run(t, "post_with_comments_imported", "post_with_comments", withVars(map[string]any{"id": "imported-post"}))
+	})
+}
+
+func TestE2EFeeds(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, h *e2eHarness) {
+		postID := field(t, h.run(t, "create_post", "create_post"), "data.createPost.id")
+		addComment := readDocument(t, "add_comment")
+		commentID := field(t, h.query(t, addComment, withVars(map[string]any{"postID": postID})), "data.addComment.id")
+
+		resp, body := h.get(t, "/feeds/posts.atom", nil)
+		if resp.StatusCode != http.StatusOK || !strings.Contains(body, "urn:forozon:post:"+postID) {
+			t.Fatalf("posts.atom: status %d, body %s", resp.StatusCode, body)
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+			t.Errorf("posts.atom Content-Type = %q", ct)
+		}
+
+		// Условный GET: совпавший ETag и неизменившаяся лента — 304. Last-Modified не отдается:
+		// удаление не сдвигает время последней записи
+		etag := resp.Header.Get("ETag")
+		if etag == "" {
+			t.Fatalf("posts.atom has no ETag: %v", resp.Header)
+		}
+		if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+			t.Errorf("posts.atom Last-Modified = %q, want none", lastModified)
+		}
+		resp, _ = h.get(t, "/feeds/posts.atom", http.Header{"If-None-Match": {etag}})
+		if resp.StatusCode != http.StatusNotModified {
+			t.Errorf("If-None-Match: status %d, want 304", resp.StatusCode)
+		}
+
+		resp, body = h.get(t, "/feeds/authors/123/posts.rss?limit=1", nil)
+		if resp.StatusCode != http.StatusOK || strings.Count(body, "<item>") != 1 {
+			t.Errorf("author posts.rss: status %d, body %s", resp.StatusCode, body)
+		}
+		resp, body = h.get(t, "/feeds/authors/nobody/posts.rss", nil)
+		if resp.StatusCode != http.StatusOK || strings.Contains(body, "<item>") {
+			t.Errorf("posts.rss of an author without posts: status %d, body %s", resp.StatusCode, body)
+		}
+
+		resp, body = h.get(t, "/feeds/posts/"+postID+"/comments.rss", nil)
+		if resp.StatusCode != http.StatusOK || !strings.Contains(body, "urn:forozon:comment:"+commentID) {
+			t.Errorf("comments.rss: status %d, body %s", resp.StatusCode, body)
+		}
+
+		// Новый комментарий меняет ленту: старый ETag больше не подходит
+		commentsETag := resp.Header.Get("ETag")
+		h.query(t, addComment, withVars(map[string]any{"postID": postID}))
+		resp, _ = h.get(t, "/feeds/posts/"+postID+"/comments.rss", http.Header{"If-None-Match": {commentsETag}})
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("comments.rss after a new comment: status %d, want 200", resp.StatusCode)
+		}
+
+		// Удаление не самого нового комментария тоже меняет ленту
+		commentsETag = resp.Header.Get("ETag")
+		h.query(t, readDocument(t, "comment_delete"), withVars(map[string]any{"id": commentID}), asAdmin())
+		resp, body = h.get(t, "/feeds/posts/"+postID+"/comments.rss", http.Header{"If-None-Match": {commentsETag}})
+		if resp.StatusCode != http.StatusOK || strings.Contains(body, "urn:forozon:comment:"+commentID) {
+			t.Errorf("comments.rss after deleting a comment: status %d, body %s", resp.StatusCode, body)
+		}
+
+		// Как и удаление самой новой записи
+		h.query(t, readDocument(t, "post_delete"), withVars(map[string]any{"id": postID}), asAdmin())
+		resp, _ = h.get(t, "/feeds/posts.atom", http.Header{"If-None-Match": {etag}})
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("posts.atom after deleting a post: status %d, want 200", resp.StatusCode)
+		}
+
+		for path, want := range map[string]int{
+			"/feeds/posts/00000000-0000-0000-0000-000000000000/comments.atom": http.StatusNotFound,
+			"/feeds/posts.atom?limit=0":                                       http.StatusBadRequest,
+		} {
+			if resp, _ := h.get(t, path, nil); resp.StatusCode != want {
+				t.Errorf("%s: status %d, want %d", path, resp.StatusCode, want)
+			}
+		}
+	})
+}
+
+func TestE2ECommentAddedSubscription(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, h *e2eHarness) {
+		postID := field(t, h.run(t, "create_post", "create_post"), "data.createPost.id")
+
+		sub := h.subscribe(t, "comment_added", map[string]any{"postID": postID})
+
+		parentID := field(t, h.run(t, "subscription_add_comment", "add_comment",
+			withVars(map[string]any{"postID": postID})), "data.addComment.id")
+		sub.next(t, "subscription_comment_added")
+
+		h.run(t, "subscription_add_reply", "add_reply", withVars(map[string]any{"postID": postID, "parentID": parentID}))
+		sub.next(t, "subscription_reply_added")
+	})
+}
+
+func TestE2EComplexityOverflow(t *testing.T) {
+	// Произведение limit и first без ограничения переполнило бы int и прошло бы лимит сложности
+	forEachBackend(t, func(t *testing.T, h *e2eHarness) {
+		h.run(t, "complexity_overflow", "complexity_overflow")
+	})
+}
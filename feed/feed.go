@@ -0,0 +1,269 @@
+// Package feed ленты Atom и RSS 2.0 для читалок: последние посты, посты автора и комментарии
+// к посту. Ленты строятся из хранилища при каждом запросе и поддерживают условный GET.
+package feed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/logging"
+)
+
+// Config параметры лент
+type Config struct {
+	// Title название ленты постов
+	Title string
+	// BaseURL адрес сервиса для ссылок в лентах; пусто — схема и хост запроса
+	BaseURL string
+	// PostURL шаблон ссылки на пост с {id}; пусто — ссылка на ленту комментариев поста
+	PostURL string
+	// Limit записей в ленте, если клиент не передал ?limit=
+	Limit int
+	// MaxLimit наибольшее число записей в ленте
+	MaxLimit int
+	// Authors включает ленты постов отдельных авторов
+	Authors bool
+}
+
+// feed лента независимо от формата
+type feed struct {
+	ID      string
+	Title   string
+	Link    string
+	SelfURL string
+	Updated time.Time
+	Entries []*entry
+}
+
+// entry запись ленты: пост или комментарий
+type entry struct {
+	ID        string
+	Title     string
+	Link      string
+	Author    string
+	Content   string
+	Published time.Time
+	Updated   time.Time
+}
+
+// request запрос ленты в выбранном формате
+type request struct {
+	*http.Request
+	base   string
+	format *format
+	limit  int
+}
+
+type handler struct {
+	storage domain.Storage
+	cfg     Config
+}
+
+// Handler ленты по путям:
+//
+//	GET /feeds/posts.{atom,rss}                     последние посты
+//	GET /feeds/authors/{authorID}/posts.{atom,rss}  посты автора, если включены в Config.Authors
+//	GET /feeds/posts/{postID}/comments.{atom,rss}   комментарии к посту
+//
+// Удаленные посты и комментарии в ленты не попадают.
+func Handler(storage domain.Storage, cfg Config) http.Handler {
+	h := &handler{storage: storage, cfg: cfg}
+
+	mux := http.NewServeMux()
+	for _, f := range formats {
+		mux.Handle("GET /feeds/posts."+f.ext, h.serve(f, h.posts))
+		if cfg.Authors {
+			mux.Handle("GET /feeds/authors/{authorID}/posts."+f.ext, h.serve(f, h.posts))
+		}
+		mux.Handle("GET /feeds/posts/{postID}/comments."+f.ext, h.serve(f, h.comments))
+	}
+	return mux
+}
+
+// serve строит ленту и отдает ее через http.ServeContent: ETag — хэш тела, на совпадение
+// отвечает 304. Last-Modified не ставится: удаление записи, стирание автора и правка
+// заголовка поста меняют ленту, не сдвигая время последнего изменения записей
+func (h *handler) serve(f *format, build func(req *request) (*feed, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, err := h.limit(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req := &request{Request: r, base: h.baseURL(r), format: f, limit: limit}
+		fd, err := build(req)
+		if errors.Is(err, domain.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("failed to build feed", slog.String("path", r.URL.Path), slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		fd.SelfURL = req.base + r.URL.RequestURI()
+
+		body, err := f.render(fd)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("failed to render feed", slog.String("path", r.URL.Path), slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", f.contentType)
+		w.Header().Set("ETag", etag(body))
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	})
+}
+
+// limit число записей из ?limit=, не больше MaxLimit
+func (h *handler) limit(r *http.Request) (int, error) {
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return h.cfg.Limit, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	return min(n, h.cfg.MaxLimit), nil
+}
+
+// baseURL адрес сервиса для абсолютных ссылок
+func (h *handler) baseURL(r *http.Request) string {
+	if h.cfg.BaseURL != "" {
+		return strings.TrimSuffix(h.cfg.BaseURL, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// postLink ссылка на пост: по шаблону PostURL или на ленту его комментариев
+func (h *handler) postLink(req *request, postID string) string {
+	if h.cfg.PostURL != "" {
+		return strings.ReplaceAll(h.cfg.PostURL, "{id}", url.PathEscape(postID))
+	}
+	return req.base + "/feeds/posts/" + url.PathEscape(postID) + "/comments." + req.format.ext
+}
+
+// posts последние посты, от новых к старым; на пути автора — только его посты
+func (h *handler) posts(req *request) (*feed, error) {
+	authorID := req.PathValue("authorID")
+
+	posts, err := h.storage.GetLatestPosts(req.Context(), authorID, req.limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	fd := &feed{ID: urn("posts"), Title: h.cfg.Title, Link: req.base}
+	if authorID != "" {
+		fd.ID = urn("author", authorID, "posts")
+		fd.Title = h.cfg.Title + ": " + authorID
+	}
+
+	for _, post := range posts {
+		published, updated, err := entryTimes(post.CreatedAt, post.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("post %s: %w", post.ID, err)
+		}
+
+		fd.add(&entry{
+			ID:        urn("post", post.ID),
+			Title:     post.Title,
+			Link:      h.postLink(req, post.ID),
+			Author:    post.AuthorID,
+			Content:   post.Content,
+			Published: published,
+			Updated:   updated,
+		})
+	}
+
+	return fd, nil
+}
+
+// comments последние комментарии к посту всех уровней, от новых к старым
+func (h *handler) comments(req *request) (*feed, error) {
+	ctx := req.Context()
+	postID := req.PathValue("postID")
+
+	post, err := h.storage.GetPostByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Deleted() {
+		return nil, domain.NotFound("post", postID)
+	}
+
+	comments, err := h.storage.GetLatestComments(ctx, postID, req.limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	link := h.postLink(req, post.ID)
+	fd := &feed{ID: urn("post", post.ID, "comments"), Title: "Comments: " + post.Title, Link: link}
+	for _, c := range comments {
+		published, updated, err := entryTimes(c.CreatedAt, c.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("comment %s: %w", c.ID, err)
+		}
+
+		fd.add(&entry{
+			ID:        urn("comment", c.ID),
+			Title:     c.AuthorID + " on " + post.Title,
+			Link:      link,
+			Author:    c.AuthorID,
+			Content:   c.Content,
+			Published: published,
+			Updated:   updated,
+		})
+	}
+
+	if fd.Updated.IsZero() {
+		fd.Updated, err = domain.ParseTimestamp(post.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("post %s: %w", post.ID, err)
+		}
+	}
+	return fd, nil
+}
+
+// add добавляет запись и сдвигает время изменения ленты
+func (f *feed) add(e *entry) {
+	f.Entries = append(f.Entries, e)
+	if e.Updated.After(f.Updated) {
+		f.Updated = e.Updated
+	}
+}
+
+// entryTimes время создания и последней правки записи; неизмененная запись
+// считается измененной в момент создания
+func entryTimes(createdAt string, updatedAt *string) (published, updated time.Time, err error) {
+	published, err = domain.ParseTimestamp(createdAt)
+	if err != nil || updatedAt == nil {
+		return published, published, err
+	}
+	updated, err = domain.ParseTimestamp(*updatedAt)
+	return published, updated, err
+}
+
+// urn постоянный идентификатор ленты или записи
+func urn(parts ...string) string {
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return "urn:forozon:" + strings.Join(parts, ":")
+}
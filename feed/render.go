@@ -0,0 +1,158 @@
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"time"
+)
+
+// format формат ленты: расширение в пути, Content-Type и сериализация
+type format struct {
+	ext         string
+	contentType string
+	render      func(f *feed) ([]byte, error)
+}
+
+const (
+	atomContentType = "application/atom+xml; charset=utf-8"
+	rssContentType  = "application/rss+xml; charset=utf-8"
+)
+
+// formats поддерживаемые форматы
+var formats = []*format{
+	{ext: "atom", contentType: atomContentType, render: renderAtom},
+	{ext: "rss", contentType: rssContentType, render: renderRSS},
+}
+
+// etag сильный ETag по содержимому ленты
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Updated string       `xml:"updated"`
+	Links   []atomLink   `xml:"link"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Link      atomLink    `xml:"link"`
+	Author    atomPerson  `xml:"author"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// renderAtom лента в формате Atom (RFC 4287); тексты отдаются как есть, type="text"
+func renderAtom(f *feed) ([]byte, error) {
+	doc := atomFeed{
+		ID:      f.ID,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: atomContentType, Href: f.SelfURL},
+			{Rel: "alternate", Href: f.Link},
+		},
+	}
+	for _, e := range f.Entries {
+		doc.Entries = append(doc.Entries, &atomEntry{
+			ID:        e.ID,
+			Title:     e.Title,
+			Link:      atomLink{Rel: "alternate", Href: e.Link},
+			Author:    atomPerson{Name: e.Author},
+			Published: e.Published.UTC().Format(time.RFC3339),
+			Updated:   e.Updated.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "text", Text: e.Content},
+		})
+	}
+
+	return marshal(doc)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	Creator     string  `xml:"dc:creator"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// renderRSS лента в формате RSS 2.0; автор — dc:creator, потому что author в RSS — email
+func renderRSS(f *feed) ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: f.Title,
+		},
+	}
+	if !f.Updated.IsZero() {
+		doc.Channel.LastBuildDate = f.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, e := range f.Entries {
+		doc.Channel.Items = append(doc.Channel.Items, &rssItem{
+			Title:       e.Title,
+			Link:        e.Link,
+			Description: e.Content,
+			Creator:     e.Author,
+			GUID:        rssGUID{ID: e.ID},
+			PubDate:     e.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	return marshal(doc)
+}
+
+// marshal XML документ с заголовком
+func marshal(doc any) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"sync"
+
+	"github.com/YakovlevIgA/forozon/graph/model"
+)
+
+// commentBroker рассылает новые комментарии подписчикам поста
+type commentBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan *model.Comment]struct{}
+}
+
+// newCommentBroker создает новый экземпляр commentBroker
+func newCommentBroker() *commentBroker {
+	return &commentBroker{
+		subscribers: make(map[string]map[chan *model.Comment]struct{}),
+	}
+}
+
+// subscribe регистрирует подписчика на комментарии поста
+func (b *commentBroker) subscribe(postID string) chan *model.Comment {
+	ch := make(chan *model.Comment, 1)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[postID] == nil {
+		b.subscribers[postID] = make(map[chan *model.Comment]struct{})
+	}
+	b.subscribers[postID][ch] = struct{}{}
+
+	return ch
+}
+
+// unsubscribe удаляет подписчика и закрывает его канал
+func (b *commentBroker) unsubscribe(postID string, ch chan *model.Comment) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[postID][ch]; !ok {
+		return
+	}
+
+	delete(b.subscribers[postID], ch)
+	if len(b.subscribers[postID]) == 0 {
+		delete(b.subscribers, postID)
+	}
+	close(ch)
+}
+
+// publish отправляет комментарий подписчикам; медленные подписчики пропускают событие
+func (b *commentBroker) publish(comment *model.Comment) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[comment.PostID] {
+		select {
+		case ch <- comment:
+		default:
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package graph
+
+import "math"
+
+// Ожидаемые размеры списков для оценки сложности запроса. Вложенные поля
+// списка стоят столько, сколько элементов он вернет, поэтому стоимость
+// рекурсивных replies растет с каждым уровнем вложенности.
+const (
+	// defaultCommentsLimit лимит комментариев, если клиент его не указал
+	defaultCommentsLimit = 1000
+	// maxCommentsLimit наибольший limit комментариев; больший урезается до него
+	maxCommentsLimit = 1000
+	// estimatedPosts ожидаемое число постов в posts (аргумента limit у поля нет)
+	estimatedPosts = 20
+	// estimatedPostComments ожидаемое число комментариев в Post.comments без limit
+	estimatedPostComments = 50
+	// estimatedReplies ожидаемое число ответов на один комментарий
+	estimatedReplies = 3
+	// defaultRevisionsLimit размер страницы истории изменений без first
+	defaultRevisionsLimit = 20
+	// maxRevisionsLimit наибольший размер страницы истории изменений
+	maxRevisionsLimit = 100
+	// diffCost стоимость построения diff: загрузка всей истории и LCS
+	diffCost = 50
+)
+
+// NewComplexity стоимость полей схемы для extension.FixedComplexityLimit
+func NewComplexity() ComplexityRoot {
+	var c ComplexityRoot
+
+	c.Query.Posts = func(childComplexity int) int {
+		return listCost(childComplexity, estimatedPosts)
+	}
+	c.Query.Comments = func(childComplexity int, _ string, limit *int32, _ *string) int {
+		return listCost(childComplexity, listSize(limit, defaultCommentsLimit, maxCommentsLimit))
+	}
+	c.Post.Comments = func(childComplexity int, limit *int32, _ *string) int {
+		return listCost(childComplexity, listSize(limit, estimatedPostComments, maxCommentsLimit))
+	}
+	c.CommentWithReplies.Replies = func(childComplexity int) int {
+		return listCost(childComplexity, estimatedReplies)
+	}
+
+	c.Post.Revisions = func(childComplexity int, first *int32, _ *string) int {
+		return listCost(childComplexity, listSize(first, defaultRevisionsLimit, maxRevisionsLimit))
+	}
+	c.Post.Diff = func(childComplexity int, _ int32, _ int32) int {
+		return diffCost + childComplexity
+	}
+	c.CommentWithReplies.Revisions = func(childComplexity int, first *int32, _ *string) int {
+		return listCost(childComplexity, listSize(first, defaultRevisionsLimit, maxRevisionsLimit))
+	}
+	c.CommentWithReplies.Diff = func(childComplexity int, _ int32, _ int32) int {
+		return diffCost + childComplexity
+	}
+
+	return c
+}
+
+// listSize размер списка по аргументу limit или значению по умолчанию, не больше max
+func listSize(limit *int32, def, max int) int {
+	if limit == nil || *limit <= 0 {
+		return def
+	}
+	return min(int(*limit), max)
+}
+
+// listCost стоимость списка из size элементов стоимостью childComplexity.
+// Умножение насыщается до math.MaxInt: переполнение дало бы отрицательную
+// стоимость, и FixedComplexityLimit пропустил бы запрос.
+func listCost(childComplexity, size int) int {
+	if childComplexity > 0 && size > (math.MaxInt-1)/childComplexity {
+		return math.MaxInt
+	}
+	return 1 + childComplexity*size
+}
@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/graph/model"
+)
+
+// Доменные модели хранилища превращаются в модели GraphQL только здесь,
+// чтобы репозитории не зависели от схемы
+
+// modelPost пост GraphQL
+func modelPost(p *domain.Post) *model.Post {
+	if p == nil {
+		return nil
+	}
+
+	return &model.Post{
+		ID:               p.ID,
+		Title:            p.Title,
+		Content:          p.Content,
+		AuthorID:         p.AuthorID,
+		CreatedAt:        p.CreatedAt,
+		CommentsDisabled: p.CommentsDisabled,
+		Version:          p.Version,
+		DeletedAt:        p.DeletedAt,
+		DeletedBy:        p.DeletedBy,
+		CommentCount:     p.CommentCount,
+		LastCommentAt:    p.LastCommentAt,
+	}
+}
+
+// modelPosts список постов GraphQL
+func modelPosts(posts []*domain.Post) []*model.Post {
+	result := make([]*model.Post, 0, len(posts))
+	for _, p := range posts {
+		result = append(result, modelPost(p))
+	}
+	return result
+}
+
+// modelComment комментарий GraphQL
+func modelComment(c *domain.Comment) *model.Comment {
+	if c == nil {
+		return nil
+	}
+
+	return &model.Comment{
+		ID:         c.ID,
+		PostID:     c.PostID,
+		ParentID:   c.ParentID,
+		AuthorID:   c.AuthorID,
+		Content:    c.Content,
+		CreatedAt:  c.CreatedAt,
+		Version:    c.Version,
+		DeletedAt:  c.DeletedAt,
+		DeletedBy:  c.DeletedBy,
+		ReplyCount: c.ReplyCount,
+	}
+}
+
+// modelComments ветка комментариев GraphQL; nil остается nil
+func modelComments(comments []*domain.CommentWithReplies) []*model.CommentWithReplies {
+	if comments == nil {
+		return nil
+	}
+
+	result := make([]*model.CommentWithReplies, 0, len(comments))
+	for _, c := range comments {
+		result = append(result, &model.CommentWithReplies{
+			ID:         c.ID,
+			PostID:     c.PostID,
+			ParentID:   c.ParentID,
+			AuthorID:   c.AuthorID,
+			Content:    c.Content,
+			CreatedAt:  c.CreatedAt,
+			Version:    c.Version,
+			DeletedAt:  c.DeletedAt,
+			DeletedBy:  c.DeletedBy,
+			ReplyCount: c.ReplyCount,
+			Replies:    modelComments(c.Replies),
+		})
+	}
+	return result
+}
+
+// modelRevisions история изменений GraphQL
+func modelRevisions(revs []*domain.Revision) []*model.Revision {
+	result := make([]*model.Revision, 0, len(revs))
+	for _, r := range revs {
+		result = append(result, &model.Revision{
+			Version:  r.Version,
+			Title:    r.Title,
+			Content:  r.Content,
+			EditorID: r.EditorID,
+			Reason:   r.Reason,
+			EditedAt: r.EditedAt,
+		})
+	}
+	return result
+}
+
+// modelErasureResult итог удаления данных автора
+func modelErasureResult(r *domain.ErasureResult) *model.ErasureResult {
+	return &model.ErasureResult{
+		AuthorID:           r.AuthorID,
+		Mode:               model.ErasureMode(r.Mode),
+		PostsDeleted:       r.PostsDeleted,
+		PostsAnonymized:    r.PostsAnonymized,
+		CommentsAnonymized: r.CommentsAnonymized,
+	}
+}
+
+// modelAuthorArchive архив данных автора
+func modelAuthorArchive(a *domain.AuthorArchive) *model.AuthorArchive {
+	comments := make([]*model.Comment, 0, len(a.Comments))
+	for _, c := range a.Comments {
+		comments = append(comments, modelComment(c))
+	}
+
+	return &model.AuthorArchive{
+		AuthorID:   a.AuthorID,
+		ExportedAt: a.ExportedAt,
+		Posts:      modelPosts(a.Posts),
+		Comments:   comments,
+	}
+}
@@ -0,0 +1,179 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+// Header заголовок с ключом идемпотентности
+const Header = "Idempotency-Key"
+
+// maxKeyLength максимальная длина ключа
+const maxKeyLength = 255
+
+// DefaultLease срок резервации ключа, пока запрос выполняется
+const DefaultLease = time.Minute
+
+// finishTimeout сколько ждать сохранения результата или освобождения ключа.
+// Они выполняются и после отмены запроса: клиент, оборвавший соединение,
+// повторит мутацию с тем же ключом.
+const finishTimeout = 5 * time.Second
+
+// Record сохраненный запрос с ключом идемпотентности
+type Record struct {
+	RequestHash string
+	// Result результат в JSON; nil, пока запрос выполняется
+	Result []byte
+}
+
+// Store хранилище ключей идемпотентности
+type Store interface {
+	// Begin резервирует ключ на lease. Если ключ уже занят и не истек,
+	// возвращает сохраненную запись, иначе nil.
+	Begin(ctx context.Context, key, requestHash string, lease time.Duration) (*Record, error)
+	// Complete сохраняет результат выполненного запроса на ttl
+	Complete(ctx context.Context, key string, result []byte, ttl time.Duration) error
+	// Release освобождает ключ после ошибки, чтобы клиент мог повторить запрос
+	Release(ctx context.Context, key string) error
+	// Forget удаляет сохраненные результаты с authorID автора: в них его текст,
+	// который после удаления данных автора нельзя возвращать повтором запроса
+	Forget(ctx context.Context, authorID string) error
+}
+
+// Guard выполняет мутации не больше одного раза для ключа
+type Guard struct {
+	Store Store
+	// TTL сколько хранится результат
+	TTL time.Duration
+	// Lease сколько ключ занят выполняющимся запросом; короче TTL, чтобы ключ
+	// освободился сам, если процесс упал до Complete или Release. 0 — DefaultLease.
+	Lease time.Duration
+}
+
+// lease срок резервации ключа
+func (g *Guard) lease() time.Duration {
+	if g.Lease <= 0 {
+		return DefaultLease
+	}
+	return g.Lease
+}
+
+// Forget удаляет сохраненные результаты мутаций автора; nil Guard ничего не хранит
+func (g *Guard) Forget(ctx context.Context, authorID string) error {
+	if g == nil {
+		return nil
+	}
+	return g.Store.Forget(ctx, authorID)
+}
+
+// Do выполняет fn, если ключ новый. Повтор с тем же ключом и теми же данными
+// возвращает сохраненный результат, с другими данными — ошибку CONFLICT.
+// Пустой ключ или nil Guard означает обычное выполнение.
+func Do[T any](ctx context.Context, g *Guard, key, operation string, request any, fn func() (T, error)) (T, error) {
+	var zero T
+
+	if key == "" {
+		key = KeyFromContext(ctx)
+	}
+	if g == nil || key == "" {
+		return fn()
+	}
+
+	if len(key) > maxKeyLength {
+		return zero, domain.ValidationFailed(domain.FieldError{
+			Field:   "idempotencyKey",
+			Message: fmt.Sprintf("idempotency key must be at most %d characters", maxKeyLength),
+		})
+	}
+
+	hash, err := requestHash(operation, request)
+	if err != nil {
+		return zero, err
+	}
+
+	// Ключ действует в рамках операции: один заголовок можно использовать
+	// для нескольких разных мутаций в одном документе
+	storeKey := operation + ":" + key
+
+	existing, err := g.Store.Begin(ctx, storeKey, hash, g.lease())
+	if err != nil {
+		return zero, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	if existing != nil {
+		if existing.RequestHash != hash {
+			return zero, domain.Conflict("idempotency key was already used with a different request", map[string]any{"idempotencyKey": key})
+		}
+		if existing.Result == nil {
+			return zero, domain.Conflict("request with this idempotency key is still in progress", map[string]any{"idempotencyKey": key})
+		}
+
+		var result T
+		if err := json.Unmarshal(existing.Result, &result); err != nil {
+			return zero, fmt.Errorf("failed to decode stored result: %w", err)
+		}
+		return result, nil
+	}
+
+	result, err := fn()
+
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finishTimeout)
+	defer cancel()
+
+	if err != nil {
+		if releaseErr := g.Store.Release(finishCtx, storeKey); releaseErr != nil {
+			return zero, fmt.Errorf("%w (and failed to release idempotency key: %v)", err, releaseErr)
+		}
+		return zero, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return zero, fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	if err := g.Store.Complete(finishCtx, storeKey, data, g.TTL); err != nil {
+		return zero, fmt.Errorf("failed to store idempotent result: %w", err)
+	}
+
+	return result, nil
+}
+
+// requestHash sha256 операции и ее аргументов
+func requestHash(operation string, request any) (string, error) {
+	data, err := json.Marshal(struct {
+		Operation string `json:"operation"`
+		Request   any    `json:"request"`
+	}{operation, request})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type ctxKey struct{}
+
+// KeyFromContext ключ из заголовка Idempotency-Key текущего запроса
+func KeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(ctxKey{}).(string)
+	return key
+}
+
+// Middleware кладет заголовок Idempotency-Key в контекст запроса
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(Header); key != "" {
+			r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, key))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,85 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// ctxStore MemoryStore, который, как postgres, не работает с отмененным контекстом
+type ctxStore struct {
+	*MemoryStore
+}
+
+func (s ctxStore) Complete(ctx context.Context, key string, result []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.Complete(ctx, key, result, ttl)
+}
+
+func (s ctxStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.Release(ctx, key)
+}
+
+func TestDoReleasesKeyAfterCanceledRequest(t *testing.T) {
+	g := &Guard{Store: ctxStore{NewMemoryStore()}, TTL: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Клиент оборвал соединение во время мутации
+	_, err := Do(ctx, g, "k", "createPost", "req", func() (string, error) {
+		cancel()
+		return "", ctx.Err()
+	})
+	if err != context.Canceled {
+		t.Fatalf("Do error = %v, want context.Canceled", err)
+	}
+
+	got, err := Do(context.Background(), g, "k", "createPost", "req", func() (string, error) {
+		return "created", nil
+	})
+	if err != nil || got != "created" {
+		t.Fatalf("retry = %q, %v; want the mutation to run again", got, err)
+	}
+}
+
+func TestDoAbandonedReservationExpiresAfterLease(t *testing.T) {
+	store := NewMemoryStore()
+	g := &Guard{Store: store, TTL: time.Hour, Lease: 10 * time.Millisecond}
+
+	// Процесс упал между Begin и Complete: резервация осталась без результата
+	hash, err := requestHash("createPost", "req")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Begin(context.Background(), "createPost:k", hash, g.Lease); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Do(context.Background(), g, "k", "createPost", "req", func() (string, error) {
+		return "created", nil
+	}); err == nil {
+		t.Fatal("Do succeeded while the key is reserved")
+	}
+
+	time.Sleep(2 * g.Lease)
+
+	got, err := Do(context.Background(), g, "k", "createPost", "req", func() (string, error) {
+		return "created", nil
+	})
+	if err != nil || got != "created" {
+		t.Fatalf("Do after lease = %q, %v; want the mutation to run", got, err)
+	}
+
+	// Результат хранится TTL, а не Lease
+	time.Sleep(2 * g.Lease)
+	got, err = Do(context.Background(), g, "k", "createPost", "req", func() (string, error) {
+		return "created twice", nil
+	})
+	if err != nil || got != "created" {
+		t.Fatalf("Do after completion = %q, %v; want the stored result", got, err)
+	}
+}
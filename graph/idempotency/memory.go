@@ -0,0 +1,102 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// pruneInterval как часто удалять истекшие ключи
+const pruneInterval = time.Minute
+
+// MemoryStore хранилище ключей в памяти процесса
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]*memoryRecord
+	lastPrune time.Time
+}
+
+type memoryRecord struct {
+	Record
+	expiresAt time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore создает новый экземпляр MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*memoryRecord)}
+}
+
+// Begin резервирует ключ
+func (s *MemoryStore) Begin(_ context.Context, key, requestHash string, lease time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	if rec, ok := s.records[key]; ok && now.Before(rec.expiresAt) {
+		existing := rec.Record
+		return &existing, nil
+	}
+
+	s.records[key] = &memoryRecord{
+		Record:    Record{RequestHash: requestHash},
+		expiresAt: now.Add(lease),
+	}
+
+	return nil, nil
+}
+
+// Complete сохраняет результат
+func (s *MemoryStore) Complete(_ context.Context, key string, result []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[key]; ok {
+		rec.Result = result
+		rec.expiresAt = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+// Release освобождает ключ
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// Forget удаляет результаты, у которых authorID равен authorID
+func (s *MemoryStore) Forget(_ context.Context, authorID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, rec := range s.records {
+		var result struct {
+			AuthorID string `json:"authorID"`
+		}
+		if rec.Result != nil && json.Unmarshal(rec.Result, &result) == nil && result.AuthorID == authorID {
+			delete(s.records, key)
+		}
+	}
+	return nil
+}
+
+// prune удаляет истекшие ключи не чаще pruneInterval
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < pruneInterval {
+		return
+	}
+	s.lastPrune = now
+
+	for key, rec := range s.records {
+		if !now.Before(rec.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PostgresStore хранилище ключей в таблице idempotency_keys
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore создает новый экземпляр PostgresStore
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+// Begin резервирует ключ; истекший ключ, в том числе брошенная резервация, перезаписывается
+func (s *PostgresStore) Begin(ctx context.Context, key, requestHash string, lease time.Duration) (*Record, error) {
+	expiresAt := time.Now().UTC().Add(lease)
+
+	var inserted bool
+	var rec Record
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO idempotency_keys (key, requestHash, expiresAt) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE
+			SET requestHash = EXCLUDED.requestHash, result = NULL, expiresAt = EXCLUDED.expiresAt
+			WHERE idempotency_keys.expiresAt <= NOW()
+		RETURNING true, requestHash, result`,
+		key, requestHash, expiresAt,
+	).Scan(&inserted, &rec.RequestHash, &rec.Result)
+	if err == nil {
+		return nil, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	// Ключ занят и не истек
+	err = s.pool.QueryRow(ctx, "SELECT requestHash, result FROM idempotency_keys WHERE key=$1", key).Scan(&rec.RequestHash, &rec.Result)
+	if err == pgx.ErrNoRows {
+		// Запись успели освободить между запросами, пробуем еще раз
+		return s.Begin(ctx, key, requestHash, lease)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rec, nil
+}
+
+// Complete сохраняет результат и продлевает ключ со срока резервации до ttl
+func (s *PostgresStore) Complete(ctx context.Context, key string, result []byte, ttl time.Duration) error {
+	_, err := s.pool.Exec(ctx, "UPDATE idempotency_keys SET result=$2, expiresAt=$3 WHERE key=$1", key, result, time.Now().UTC().Add(ttl))
+	return err
+}
+
+// Release освобождает ключ
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	_, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE key=$1 AND result IS NULL", key)
+	return err
+}
+
+// Forget удаляет результаты, у которых authorID равен authorID
+func (s *PostgresStore) Forget(ctx context.Context, authorID string) error {
+	_, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE result->>'authorID' = $1", authorID)
+	return err
+}
+
+// Purge удаляет истекшие ключи
+func (s *PostgresStore) Purge(ctx context.Context) (int64, error) {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE expiresAt <= NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
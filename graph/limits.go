@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Коды ошибок для клиентов
+const (
+	errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+	errAliasLimit = "ALIAS_LIMIT_EXCEEDED"
+)
+
+// QueryLimits ограничивает глубину запроса и число алиасов.
+// Нулевое значение лимита отключает соответствующую проверку.
+type QueryLimits struct {
+	MaxDepth   int
+	MaxAliases int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = QueryLimits{}
+
+// ExtensionName имя расширения
+func (QueryLimits) ExtensionName() string {
+	return "QueryLimits"
+}
+
+// Validate проверка схемы не требуется
+func (QueryLimits) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext отклоняет слишком глубокие запросы и запросы с большим числом алиасов
+func (l QueryLimits) MutateOperationContext(_ context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	if opCtx.Operation == nil {
+		return nil
+	}
+
+	depth, aliases := measureSelectionSet(opCtx.Operation.SelectionSet)
+
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d; request fewer nested levels of replies", depth, l.MaxDepth)
+		errcode.Set(err, errDepthLimit)
+		return err
+	}
+
+	if l.MaxAliases > 0 && aliases > l.MaxAliases {
+		err := gqlerror.Errorf("operation uses %d aliases, which exceeds the limit of %d", aliases, l.MaxAliases)
+		errcode.Set(err, errAliasLimit)
+		return err
+	}
+
+	return nil
+}
+
+// measureSelectionSet глубина вложенности полей и число алиасов.
+// Поля интроспекции (__schema, __type) не учитываются. Циклы фрагментов
+// отсекаются валидацией документа до вызова расширения.
+func measureSelectionSet(set ast.SelectionSet) (depth int, aliases int) {
+	for _, sel := range set {
+		var d, a int
+
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name, "__") {
+				continue
+			}
+			d, a = measureSelectionSet(sel.SelectionSet)
+			d++
+			if sel.Alias != "" && sel.Alias != sel.Name {
+				a++
+			}
+		case *ast.InlineFragment:
+			d, a = measureSelectionSet(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				d, a = measureSelectionSet(sel.Definition.SelectionSet)
+			}
+		}
+
+		depth = max(depth, d)
+		aliases += a
+	}
+
+	return depth, aliases
+}
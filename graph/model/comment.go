@@ -1,12 +1,16 @@
 package model
 
 type Comment struct {
-	ID        string  `json:"id"`
-	PostID    string  `json:"postID"`
-	ParentID  *string `json:"parentID,omitempty"`
-	AuthorID  string  `json:"authorID"`
-	Content   string  `json:"content"`
-	CreatedAt string  `json:"createdAt"`
+	ID         string  `json:"id"`
+	PostID     string  `json:"postID"`
+	ParentID   *string `json:"parentID,omitempty"`
+	AuthorID   string  `json:"authorID"`
+	Content    string  `json:"content"`
+	CreatedAt  string  `json:"createdAt"`
+	Version    int32   `json:"version"`
+	DeletedAt  *string `json:"deletedAt,omitempty"`
+	DeletedBy  *string `json:"deletedBy,omitempty"`
+	ReplyCount int32   `json:"replyCount"`
 }
 
 type CommentConnection struct {
@@ -15,16 +19,40 @@ type CommentConnection struct {
 }
 
 type CommentWithReplies struct {
-	ID        string                `json:"id"`
-	PostID    string                `json:"postID"`
-	ParentID  *string               `json:"parentID,omitempty"`
-	AuthorID  string                `json:"authorID"`
-	Content   string                `json:"content"`
-	CreatedAt string                `json:"createdAt"`
-	Replies   []*CommentWithReplies `json:"replies"`
+	ID         string                `json:"id"`
+	PostID     string                `json:"postID"`
+	ParentID   *string               `json:"parentID,omitempty"`
+	AuthorID   string                `json:"authorID"`
+	Content    string                `json:"content"`
+	CreatedAt  string                `json:"createdAt"`
+	Version    int32                 `json:"version"`
+	DeletedAt  *string               `json:"deletedAt,omitempty"`
+	DeletedBy  *string               `json:"deletedBy,omitempty"`
+	ReplyCount int32                 `json:"replyCount"`
+	Replies    []*CommentWithReplies `json:"replies"`
 }
 
 type PageInfo struct {
 	HasNextPage bool    `json:"hasNextPage"`
 	EndCursor   *string `json:"endCursor,omitempty"`
 }
+
+// Edited изменялся ли комментарий после создания
+func (c *Comment) Edited() bool {
+	return c.Version > 1
+}
+
+// Edited изменялся ли комментарий после создания
+func (c *CommentWithReplies) Edited() bool {
+	return c.Version > 1
+}
+
+// Deleted удален ли комментарий (мягкое удаление)
+func (c *Comment) Deleted() bool {
+	return c.DeletedAt != nil
+}
+
+// Deleted удален ли комментарий (мягкое удаление)
+func (c *CommentWithReplies) Deleted() bool {
+	return c.DeletedAt != nil
+}
@@ -0,0 +1,39 @@
+package model
+
+// ErasureMode способ удаления данных автора по запросу GDPR
+type ErasureMode string
+
+const (
+	// ErasureModeAnonymize посты и комментарии остаются на месте без автора и текста
+	ErasureModeAnonymize ErasureMode = "ANONYMIZE"
+	// ErasureModeDeletePosts посты удаляются целиком, комментарии анонимизируются
+	ErasureModeDeletePosts ErasureMode = "DELETE_POSTS"
+)
+
+type ErasureResult struct {
+	AuthorID           string      `json:"authorID"`
+	Mode               ErasureMode `json:"mode"`
+	PostsDeleted       int32       `json:"postsDeleted"`
+	PostsAnonymized    int32       `json:"postsAnonymized"`
+	CommentsAnonymized int32       `json:"commentsAnonymized"`
+}
+
+type AuthorEraseInput struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	AuthorID         string       `json:"authorID"`
+	Mode             *ErasureMode `json:"mode,omitempty"`
+}
+
+type AuthorErasePayload struct {
+	ClientMutationID *string        `json:"clientMutationId,omitempty"`
+	Result           *ErasureResult `json:"result,omitempty"`
+	UserErrors       []*UserError   `json:"userErrors"`
+}
+
+// AuthorArchive все данные автора для ответа на запрос о доступе к данным
+type AuthorArchive struct {
+	AuthorID   string     `json:"authorID"`
+	ExportedAt string     `json:"exportedAt"`
+	Posts      []*Post    `json:"posts"`
+	Comments   []*Comment `json:"comments"`
+}
@@ -0,0 +1,105 @@
+package model
+
+type UserError struct {
+	Field   *string `json:"field,omitempty"`
+	Message string  `json:"message"`
+	Code    string  `json:"code"`
+}
+
+type CreatePostInput struct {
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
+	IdempotencyKey   *string `json:"idempotencyKey,omitempty"`
+	Title            string  `json:"title"`
+	Content          string  `json:"content"`
+	AuthorID         string  `json:"authorID"`
+	CommentsDisabled bool    `json:"commentsDisabled"`
+}
+
+type CreatePostPayload struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	Post             *Post        `json:"post,omitempty"`
+	UserErrors       []*UserError `json:"userErrors"`
+}
+
+type AddCommentInput struct {
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
+	IdempotencyKey   *string `json:"idempotencyKey,omitempty"`
+	PostID           string  `json:"postID"`
+	ParentID         *string `json:"parentID,omitempty"`
+	AuthorID         string  `json:"authorID"`
+	Content          string  `json:"content"`
+}
+
+type AddCommentPayload struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	Comment          *Comment     `json:"comment,omitempty"`
+	UserErrors       []*UserError `json:"userErrors"`
+}
+
+type UpdatePostInput struct {
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
+	ID               string  `json:"id"`
+	ExpectedVersion  int32   `json:"expectedVersion"`
+	EditorID         string  `json:"editorID"`
+	Reason           *string `json:"reason,omitempty"`
+	Title            *string `json:"title,omitempty"`
+	Content          *string `json:"content,omitempty"`
+}
+
+type UpdatePostPayload struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	Post             *Post        `json:"post,omitempty"`
+	CurrentVersion   *int32       `json:"currentVersion,omitempty"`
+	UserErrors       []*UserError `json:"userErrors"`
+}
+
+type UpdateCommentInput struct {
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
+	ID               string  `json:"id"`
+	ExpectedVersion  int32   `json:"expectedVersion"`
+	EditorID         string  `json:"editorID"`
+	Reason           *string `json:"reason,omitempty"`
+	Content          string  `json:"content"`
+}
+
+type UpdateCommentPayload struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	Comment          *Comment     `json:"comment,omitempty"`
+	CurrentVersion   *int32       `json:"currentVersion,omitempty"`
+	UserErrors       []*UserError `json:"userErrors"`
+}
+
+type DeleteInput struct {
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
+	ID               string  `json:"id"`
+	DeletedBy        *string `json:"deletedBy,omitempty"`
+}
+
+type RestoreInput struct {
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
+	ID               string  `json:"id"`
+}
+
+type DeletePostPayload struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	Post             *Post        `json:"post,omitempty"`
+	UserErrors       []*UserError `json:"userErrors"`
+}
+
+type RestorePostPayload struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	Post             *Post        `json:"post,omitempty"`
+	UserErrors       []*UserError `json:"userErrors"`
+}
+
+type DeleteCommentPayload struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	Comment          *Comment     `json:"comment,omitempty"`
+	UserErrors       []*UserError `json:"userErrors"`
+}
+
+type RestoreCommentPayload struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	Comment          *Comment     `json:"comment,omitempty"`
+	UserErrors       []*UserError `json:"userErrors"`
+}
@@ -1,11 +1,25 @@
 package model
 
 type Post struct {
-	ID               string                `json:"id"`
-	Title            string                `json:"title"`
-	Content          string                `json:"content"`
-	AuthorID         string                `json:"authorID"`
-	CreatedAt        string                `json:"createdAt"`
-	CommentsDisabled bool                  `json:"commentsDisabled"`
-	Comments         []*CommentWithReplies `json:"comments,omitempty"`
+	ID               string  `json:"id"`
+	Title            string  `json:"title"`
+	Content          string  `json:"content"`
+	AuthorID         string  `json:"authorID"`
+	CreatedAt        string  `json:"createdAt"`
+	CommentsDisabled bool    `json:"commentsDisabled"`
+	Version          int32   `json:"version"`
+	DeletedAt        *string `json:"deletedAt,omitempty"`
+	DeletedBy        *string `json:"deletedBy,omitempty"`
+	CommentCount     int32   `json:"commentCount"`
+	LastCommentAt    *string `json:"lastCommentAt,omitempty"`
+}
+
+// Edited изменялся ли пост после создания
+func (p *Post) Edited() bool {
+	return p.Version > 1
+}
+
+// Deleted удален ли пост (мягкое удаление)
+func (p *Post) Deleted() bool {
+	return p.DeletedAt != nil
 }
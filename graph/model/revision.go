@@ -0,0 +1,29 @@
+package model
+
+// Revision предыдущая версия поста или комментария и правка, которая ее заменила
+type Revision struct {
+	Version  int32   `json:"version"`
+	Title    *string `json:"title,omitempty"`
+	Content  string  `json:"content"`
+	EditorID string  `json:"editorID"`
+	Reason   *string `json:"reason,omitempty"`
+	EditedAt string  `json:"editedAt"`
+}
+
+type RevisionConnection struct {
+	Edges    []*Revision `json:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo"`
+}
+
+type DiffOp string
+
+const (
+	DiffOpEqual  DiffOp = "EQUAL"
+	DiffOpInsert DiffOp = "INSERT"
+	DiffOpDelete DiffOp = "DELETE"
+)
+
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
@@ -0,0 +1,17 @@
+package model
+
+type DataImportInput struct {
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
+	Ndjson           string  `json:"ndjson"`
+	DryRun           bool    `json:"dryRun"`
+}
+
+// DataImportPayload итог загрузки; счетчики заполнены и при ошибках записей
+type DataImportPayload struct {
+	ClientMutationID *string      `json:"clientMutationId,omitempty"`
+	Posts            int32        `json:"posts"`
+	Comments         int32        `json:"comments"`
+	Imported         int32        `json:"imported"`
+	Skipped          int32        `json:"skipped"`
+	UserErrors       []*UserError `json:"userErrors"`
+}
@@ -0,0 +1,42 @@
+package persisted
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/YakovlevIgA/forozon/logging"
+)
+
+// APQCache хранилище APQ для extension.AutomaticPersistedQuery. Документы манифеста
+// отдаются всегда и не вытесняются; регистрировать запросы может любой клиент,
+// поэтому в Store попадают только запросы не длиннее MaxQueryBytes.
+type APQCache struct {
+	Manifest      Manifest
+	Store         graphql.Cache[string]
+	MaxQueryBytes int
+}
+
+var _ graphql.Cache[string] = APQCache{}
+
+// Get ищет запрос в манифесте, затем в Store
+func (c APQCache) Get(ctx context.Context, hash string) (string, bool) {
+	if query, ok := c.Manifest[hash]; ok {
+		return query, true
+	}
+	return c.Store.Get(ctx, hash)
+}
+
+// Add регистрирует запрос; слишком длинный выполняется, но не сохраняется,
+// и клиенту придется присылать его текст каждый раз
+func (c APQCache) Add(ctx context.Context, hash string, query string) {
+	if _, ok := c.Manifest[hash]; ok {
+		return
+	}
+	if c.MaxQueryBytes > 0 && len(query) > c.MaxQueryBytes {
+		logging.FromContext(ctx).Debug("persisted query is too large to store",
+			slog.String("hash", hash), slog.Int("bytes", len(query)))
+		return
+	}
+	c.Store.Add(ctx, hash, query)
+}
@@ -0,0 +1,89 @@
+package persisted
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// Manifest доверенные документы: хэш -> текст операции
+type Manifest map[string]string
+
+// LoadManifest читает манифест, сгенерированный сборкой фронтенда.
+// Поддерживаются два формата:
+//   - apollo-persisted-query-manifest: {"operations": [{"id": "<sha256>", "body": "query ..."}]}
+//   - простой объект {"<sha256>": "query ..."}
+//
+// Каждая операция доступна и по ключу из манифеста, и по sha256 своего текста.
+func LoadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var apollo struct {
+		Operations []struct {
+			ID   string `json:"id"`
+			Body string `json:"body"`
+		} `json:"operations"`
+	}
+	if err := json.Unmarshal(data, &apollo); err == nil && apollo.Operations != nil {
+		m := make(Manifest, len(apollo.Operations))
+		for _, op := range apollo.Operations {
+			m.add(op.ID, op.Body)
+		}
+		return m, nil
+	}
+
+	var plain map[string]string
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	m := make(Manifest, len(plain))
+	for id, body := range plain {
+		m.add(id, body)
+	}
+
+	return m, nil
+}
+
+// add добавляет операцию под ее идентификатором и хэшем текста
+func (m Manifest) add(id, body string) {
+	if body == "" {
+		return
+	}
+	if id != "" {
+		m[id] = body
+	}
+	m[Hash(body)] = body
+}
+
+// OperationNames имена именованных операций манифеста без повторов
+func (m Manifest) OperationNames() []string {
+	var names []string
+	for _, body := range m {
+		doc, err := parser.ParseQuery(&ast.Source{Input: body})
+		if err != nil {
+			continue
+		}
+		for _, op := range doc.Operations {
+			if op.Name != "" && !slices.Contains(names, op.Name) {
+				names = append(names, op.Name)
+			}
+		}
+	}
+	return names
+}
+
+// Hash sha256 текста запроса в hex, как его считают APQ-клиенты
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,90 @@
+package persisted
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/YakovlevIgA/forozon/logging"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// touchInterval как часто обновляется lastUsedAt запроса: чтение из таблицы
+// не должно каждый раз превращаться в запись
+const touchInterval = time.Hour
+
+// PostgresCache хранилище APQ в postgres с локальным кэшем перед ним,
+// чтобы зарегистрированные запросы переживали перезапуск и были общими для реплик.
+// Таблица ограничивается через Purge: по сроку с последнего использования и по числу строк.
+type PostgresCache struct {
+	pool  *pgxpool.Pool
+	local graphql.Cache[string]
+}
+
+var _ graphql.Cache[string] = (*PostgresCache)(nil)
+
+// NewPostgresCache создает новый экземпляр PostgresCache
+func NewPostgresCache(pool *pgxpool.Pool, local graphql.Cache[string]) *PostgresCache {
+	return &PostgresCache{pool: pool, local: local}
+}
+
+// Get ищет запрос в локальном кэше, затем в таблице persisted_queries
+func (c *PostgresCache) Get(ctx context.Context, hash string) (string, bool) {
+	if query, ok := c.local.Get(ctx, hash); ok {
+		return query, true
+	}
+
+	var query string
+	err := c.pool.QueryRow(ctx, "SELECT query FROM persisted_queries WHERE hash=$1", hash).Scan(&query)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			logging.FromContext(ctx).Warn("failed to load persisted query", slog.String("hash", hash), slog.Any("error", err))
+		}
+		return "", false
+	}
+
+	_, err = c.pool.Exec(ctx,
+		"UPDATE persisted_queries SET lastUsedAt=NOW() WHERE hash=$1 AND lastUsedAt < $2",
+		hash, time.Now().UTC().Add(-touchInterval),
+	)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to touch persisted query", slog.String("hash", hash), slog.Any("error", err))
+	}
+
+	c.local.Add(ctx, hash, query)
+	return query, true
+}
+
+// Add сохраняет запрос; ошибка записи не мешает выполнению, запрос остается в локальном кэше
+func (c *PostgresCache) Add(ctx context.Context, hash string, query string) {
+	c.local.Add(ctx, hash, query)
+
+	_, err := c.pool.Exec(ctx,
+		"INSERT INTO persisted_queries (hash, query) VALUES ($1, $2) ON CONFLICT (hash) DO NOTHING",
+		hash, query,
+	)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to store persisted query", slog.String("hash", hash), slog.Any("error", err))
+	}
+}
+
+// Purge удаляет запросы, не использованные дольше ttl, а затем самые давно
+// использованные сверх maxStored
+func (c *PostgresCache) Purge(ctx context.Context, ttl time.Duration, maxStored int) (int64, error) {
+	expired, err := c.pool.Exec(ctx, "DELETE FROM persisted_queries WHERE lastUsedAt < $1", time.Now().UTC().Add(-ttl))
+	if err != nil {
+		return 0, err
+	}
+
+	evicted, err := c.pool.Exec(ctx, `
+		DELETE FROM persisted_queries WHERE hash IN (
+			SELECT hash FROM persisted_queries ORDER BY lastUsedAt DESC OFFSET $1
+		)`, maxStored)
+	if err != nil {
+		return expired.RowsAffected(), err
+	}
+
+	return expired.RowsAffected() + evicted.RowsAffected(), nil
+}
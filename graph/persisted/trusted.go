@@ -0,0 +1,78 @@
+package persisted
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// errOperationNotTrusted код ошибки для операций вне манифеста
+const errOperationNotTrusted = "OPERATION_NOT_TRUSTED"
+
+// TrustedDocuments строгий режим: выполняются только операции из манифеста.
+// Клиент отправляет хэш в extensions.persistedQuery.sha256Hash (как в APQ)
+// или полный текст, который должен совпадать с документом из манифеста.
+type TrustedDocuments struct {
+	Manifest Manifest
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = TrustedDocuments{}
+
+// ExtensionName имя расширения
+func (TrustedDocuments) ExtensionName() string {
+	return "TrustedDocuments"
+}
+
+// Validate проверка схемы не требуется
+func (TrustedDocuments) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationParameters подставляет текст операции по хэшу и отклоняет неизвестные операции
+func (t TrustedDocuments) MutateOperationParameters(_ context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	hash := requestHash(rawParams)
+
+	if hash == "" {
+		if rawParams.Query == "" {
+			return notTrusted("operation must reference a trusted document")
+		}
+		hash = Hash(rawParams.Query)
+	}
+
+	query, ok := t.Manifest[hash]
+	if !ok {
+		return notTrusted("operation is not in the trusted documents manifest")
+	}
+
+	if rawParams.Query != "" && rawParams.Query != query {
+		return notTrusted("operation text does not match the trusted document")
+	}
+
+	rawParams.Query = query
+	// extensions.persistedQuery уже обработан, APQ не должен сохранять документ повторно
+	delete(rawParams.Extensions, "persistedQuery")
+
+	return nil
+}
+
+// requestHash хэш из extensions.persistedQuery, если клиент его прислал
+func requestHash(rawParams *graphql.RawParams) string {
+	pq, ok := rawParams.Extensions["persistedQuery"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	hash, _ := pq["sha256Hash"].(string)
+	return hash
+}
+
+// notTrusted ошибка с кодом OPERATION_NOT_TRUSTED
+func notTrusted(msg string) *gqlerror.Error {
+	err := gqlerror.Errorf("%s", msg)
+	errcode.Set(err, errOperationNotTrusted)
+	return err
+}
@@ -0,0 +1,361 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/logging"
+)
+
+// Файлы в каталоге данных InMemoryRepository
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+)
+
+// DurabilityOptions настройки журнала InMemoryRepository
+type DurabilityOptions struct {
+	Fsync         FsyncPolicy
+	FsyncInterval time.Duration
+}
+
+// durability журнал и каталог снимков
+type durability struct {
+	dir string
+	wal *wal
+}
+
+// NewDurableInMemoryRepository создает InMemoryRepository, который переживает перезапуск:
+// каждое изменение дописывается в журнал, периодический снимок (Snapshot) сокращает его.
+// При запуске загружается снимок и проигрывается журнал.
+func NewDurableInMemoryRepository(ctx context.Context, dir string, opts DurabilityOptions) (*InMemoryRepository, error) {
+	switch opts.Fsync {
+	case FsyncAlways, FsyncNever:
+	case FsyncInterval:
+		if opts.FsyncInterval <= 0 {
+			return nil, fmt.Errorf("fsync interval must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("unknown fsync policy %q", opts.Fsync)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data dir: %w", err)
+	}
+
+	s := NewInMemoryRepository()
+	logger := logging.FromContext(ctx)
+
+	loaded, err := s.loadSnapshot(filepath.Join(dir, snapshotFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+
+	records := 0
+	w, torn, err := openWAL(filepath.Join(dir, walFileName), opts.Fsync, opts.FsyncInterval, func(data []byte) error {
+		var ops []walOp
+		if err := json.Unmarshal(data, &ops); err != nil {
+			return err
+		}
+		for _, op := range ops {
+			s.apply(op)
+		}
+		records++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+
+	if torn > 0 {
+		logger.Warn("truncated torn log tail", slog.Int64("bytes", torn))
+	}
+	logger.Info("in-memory storage restored",
+		slog.String("dir", dir),
+		slog.Bool("snapshot", loaded),
+		slog.Int("log_records", records),
+		slog.Int("posts", len(s.posts)),
+		slog.Int("comments", len(s.comments)),
+	)
+
+	s.durable = &durability{dir: dir, wal: w}
+	return s, nil
+}
+
+// Операции журнала. Операции описывают результат изменения, а не вызов метода,
+// поэтому повторное применение безопасно (например, после сбоя между снимком и очисткой журнала).
+const (
+	opPutPost               = "put_post"
+	opPutComment            = "put_comment"
+	opDeletePost            = "delete_post"
+	opDeleteComment         = "delete_comment"
+	opAppendPostRevision    = "append_post_revision"
+	opAppendCommentRevision = "append_comment_revision"
+	opSetPostRevisions      = "set_post_revisions"
+	opSetCommentRevisions   = "set_comment_revisions"
+)
+
+// walOp одно изменение состояния
+type walOp struct {
+	Op        string             `json:"op"`
+	ID        string             `json:"id,omitempty"`
+	Post      *domain.Post       `json:"post,omitempty"`
+	Comment   *domain.Comment    `json:"comment,omitempty"`
+	Revisions []*domain.Revision `json:"revisions,omitempty"`
+}
+
+func putPost(post *domain.Post) walOp {
+	return walOp{Op: opPutPost, Post: post}
+}
+
+func putComment(comment *domain.Comment) walOp {
+	return walOp{Op: opPutComment, Comment: comment}
+}
+
+func deletePost(id string) walOp {
+	return walOp{Op: opDeletePost, ID: id}
+}
+
+func deleteComment(id string) walOp {
+	return walOp{Op: opDeleteComment, ID: id}
+}
+
+func appendPostRevision(id string, revision *domain.Revision) walOp {
+	return walOp{Op: opAppendPostRevision, ID: id, Revisions: []*domain.Revision{revision}}
+}
+
+func appendCommentRevision(id string, revision *domain.Revision) walOp {
+	return walOp{Op: opAppendCommentRevision, ID: id, Revisions: []*domain.Revision{revision}}
+}
+
+func setPostRevisions(id string, revisions []*domain.Revision) walOp {
+	return walOp{Op: opSetPostRevisions, ID: id, Revisions: revisions}
+}
+
+func setCommentRevisions(id string, revisions []*domain.Revision) walOp {
+	return walOp{Op: opSetCommentRevisions, ID: id, Revisions: revisions}
+}
+
+// commit записывает изменения в журнал одной записью и применяет их.
+// Вызывается под s.mu.Lock.
+func (s *InMemoryRepository) commit(ops ...walOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	if s.durable != nil {
+		data, err := json.Marshal(ops)
+		if err != nil {
+			return fmt.Errorf("failed to encode log record: %w", err)
+		}
+		if err := s.durable.wal.append(data); err != nil {
+			return fmt.Errorf("failed to write log record: %w", err)
+		}
+	}
+
+	for _, op := range ops {
+		s.apply(op)
+	}
+
+	return nil
+}
+
+// apply применяет одно изменение к состоянию в памяти
+func (s *InMemoryRepository) apply(op walOp) {
+	switch op.Op {
+	case opPutPost:
+		s.posts[op.Post.ID] = op.Post
+	case opPutComment:
+		s.comments[op.Comment.ID] = op.Comment
+	case opDeletePost:
+		delete(s.posts, op.ID)
+		delete(s.postRevisions, op.ID)
+	case opDeleteComment:
+		delete(s.comments, op.ID)
+		delete(s.commentRevisions, op.ID)
+	case opAppendPostRevision:
+		appendRevisions(s.postRevisions, op.ID, op.Revisions)
+	case opAppendCommentRevision:
+		appendRevisions(s.commentRevisions, op.ID, op.Revisions)
+	case opSetPostRevisions:
+		setRevisions(s.postRevisions, op.ID, op.Revisions)
+	case opSetCommentRevisions:
+		setRevisions(s.commentRevisions, op.ID, op.Revisions)
+	}
+}
+
+// appendRevisions дописывает ревизии в историю, пропуская уже записанные версии
+func appendRevisions(m map[string][]*domain.Revision, id string, revisions []*domain.Revision) {
+	history := m[id]
+	for _, revision := range revisions {
+		if n := len(history); n > 0 && history[n-1].Version >= revision.Version {
+			continue
+		}
+		history = append(history, revision)
+	}
+	m[id] = history
+}
+
+// setRevisions заменяет историю; пустая история удаляется из карты
+func setRevisions(m map[string][]*domain.Revision, id string, revisions []*domain.Revision) {
+	if len(revisions) == 0 {
+		delete(m, id)
+		return
+	}
+	m[id] = revisions
+}
+
+// snapshot содержимое файла снимка
+type snapshot struct {
+	Posts            []*domain.Post                `json:"posts"`
+	Comments         []*domain.Comment             `json:"comments"`
+	PostRevisions    map[string][]*domain.Revision `json:"postRevisions"`
+	CommentRevisions map[string][]*domain.Revision `json:"commentRevisions"`
+}
+
+// Snapshot записывает состояние в снимок и очищает журнал.
+// Для репозитория без каталога данных ничего не делает.
+func (s *InMemoryRepository) Snapshot(ctx context.Context) error {
+	if s.durable == nil {
+		return nil
+	}
+
+	// Изменения пишут в журнал под s.mu.Lock, поэтому под RLock
+	// журнал не растет, а чтение продолжает работать
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.snapshot(ctx)
+}
+
+// snapshot записывает снимок и очищает журнал. Вызывается под s.mu.RLock или s.mu.Lock.
+func (s *InMemoryRepository) snapshot(ctx context.Context) error {
+	snap := snapshot{
+		Posts:            make([]*domain.Post, 0, len(s.posts)),
+		Comments:         make([]*domain.Comment, 0, len(s.comments)),
+		PostRevisions:    s.postRevisions,
+		CommentRevisions: s.commentRevisions,
+	}
+	for _, post := range s.posts {
+		snap.Posts = append(snap.Posts, post)
+	}
+	for _, comment := range s.comments {
+		snap.Comments = append(snap.Comments, comment)
+	}
+
+	path := filepath.Join(s.durable.dir, snapshotFileName)
+	if err := writeFileAtomic(path, snap); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := s.durable.wal.reset(); err != nil {
+		return fmt.Errorf("failed to reset log: %w", err)
+	}
+
+	logging.FromContext(ctx).Debug("in-memory snapshot written",
+		slog.Int("posts", len(snap.Posts)),
+		slog.Int("comments", len(snap.Comments)),
+	)
+
+	return nil
+}
+
+// Close записывает финальный снимок и закрывает журнал
+func (s *InMemoryRepository) Close(ctx context.Context) error {
+	if s.durable == nil {
+		return nil
+	}
+
+	return errors.Join(s.Snapshot(ctx), s.durable.wal.close())
+}
+
+// LoadInMemorySnapshot репозиторий без журнала с данными из файла снимка другого
+// экземпляра: журнал этого экземпляра не проигрывается
+func LoadInMemorySnapshot(path string) (*InMemoryRepository, error) {
+	s := NewInMemoryRepository()
+	loaded, err := s.loadSnapshot(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	if !loaded {
+		return nil, fmt.Errorf("failed to load snapshot: %w", os.ErrNotExist)
+	}
+	return s, nil
+}
+
+// loadSnapshot загружает снимок, если он есть
+func (s *InMemoryRepository) loadSnapshot(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return false, err
+	}
+
+	for _, post := range snap.Posts {
+		s.posts[post.ID] = post
+	}
+	for _, comment := range snap.Comments {
+		s.comments[comment.ID] = comment
+	}
+	for id, revisions := range snap.PostRevisions {
+		s.postRevisions[id] = revisions
+	}
+	for id, revisions := range snap.CommentRevisions {
+		s.commentRevisions[id] = revisions
+	}
+
+	return true, nil
+}
+
+// writeFileAtomic пишет JSON во временный файл и переименовывает его,
+// чтобы при сбое остался либо старый, либо новый снимок целиком
+func writeFileAtomic(path string, v any) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := json.NewEncoder(tmp).Encode(v); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// fsync каталога, чтобы переименование пережило сбой питания
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -1,42 +1,55 @@
 package repository
 
 import (
+	"cmp"
 	"context"
 	"fmt"
-	"github.com/YakovlevIgA/forozon/graph/model"
-	"log"
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/logging"
+	"log/slog"
+	"slices"
 	"sort"
+	"sync"
 	"time"
 )
 
-// InMemoryRepository репозиторий на основе in memory
+// InMemoryRepository репозиторий на основе in memory.
+// Сохраненные посты и комментарии не изменяются на месте: изменение
+// заменяет запись копией, поэтому ранее возвращенные указатели безопасны.
+// Все изменения проходят через commit, чтобы их можно было записать в журнал.
 type InMemoryRepository struct {
-	posts    map[string]*model.Post
-	comments map[string]*model.Comment
+	mu       sync.RWMutex
+	posts    map[string]*domain.Post
+	comments map[string]*domain.Comment
+
+	postRevisions    map[string][]*domain.Revision
+	commentRevisions map[string][]*domain.Revision
+
+	// durable журнал и снимки; nil, если данные живут только в памяти
+	durable *durability
 }
 
 // NewInMemoryRepository создает новый экземпляр InMemoryRepository
 func NewInMemoryRepository() *InMemoryRepository {
 	return &InMemoryRepository{
-		posts:    make(map[string]*model.Post),
-		comments: make(map[string]*model.Comment),
+		posts:    make(map[string]*domain.Post),
+		comments: make(map[string]*domain.Comment),
+
+		postRevisions:    make(map[string][]*domain.Revision),
+		commentRevisions: make(map[string][]*domain.Revision),
 	}
 }
 
 // CreatePost создание поста
-func (s *InMemoryRepository) CreatePost(_ context.Context, title, content, authorID string, commentsDisabled bool) (*model.Post, error) {
+func (s *InMemoryRepository) CreatePost(ctx context.Context, title, content, authorID string, commentsDisabled bool) (*domain.Post, error) {
 	// Валидация
 
-	if title == "" {
-		return nil, fmt.Errorf("title is required")
-	}
-
-	if content == "" {
-		return nil, fmt.Errorf("content is required")
-	}
-
-	if authorID == "" {
-		return nil, fmt.Errorf("authorID is required")
+	var v validator
+	v.required("title", title)
+	v.required("content", content)
+	v.required("authorID", authorID)
+	if err := v.err(); err != nil {
+		return nil, err
 	}
 
 	// Исполнение
@@ -44,44 +57,61 @@ func (s *InMemoryRepository) CreatePost(_ context.Context, title, content, autho
 	id := generateID()
 	createdAt := time.Now().UTC()
 
-	post := &model.Post{
+	post := &domain.Post{
 		ID:               id,
 		Title:            title,
 		Content:          content,
 		AuthorID:         authorID,
 		CreatedAt:        createdAt.String(),
 		CommentsDisabled: commentsDisabled,
+		Version:          1,
 	}
 
-	s.posts[id] = post
-	log.Printf("Post created: %+v", post)
+	s.mu.Lock()
+	err := s.commit(putPost(post))
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("post created",
+		slog.String("post_id", post.ID),
+		slog.String("author_id", post.AuthorID),
+		logging.Content("title", post.Title),
+	)
 
 	return post, nil
 }
 
 // AddComment добавление комментария
-func (s *InMemoryRepository) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*model.Comment, error) {
+func (s *InMemoryRepository) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*domain.Comment, error) {
 	// Валидация
 
-	if authorID == "" {
-		return nil, fmt.Errorf("authorID is required")
+	var v validator
+	v.required("authorID", authorID)
+	v.required("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
 	}
 
-	if content == "" {
-		return nil, fmt.Errorf("content is required")
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	post, exists := s.posts[postID]
-	if !exists {
-		return nil, fmt.Errorf("post not found")
+	if !exists || post.Deleted() {
+		return nil, domain.NotFound("post", postID)
 	}
 
 	if post.CommentsDisabled {
-		return nil, fmt.Errorf("comments are disabled for this post")
+		return nil, domain.CommentsDisabled(postID)
 	}
 
-	if parentID != nil && s.comments[*parentID] == nil {
-		return nil, fmt.Errorf("comments parenID is not found")
+	if parentID != nil {
+		parent := s.comments[*parentID]
+		if parent == nil || parent.PostID != postID || parent.Deleted() {
+			v.add("parentID", "parent comment not found in this post")
+			return nil, v.err()
+		}
 	}
 
 	// Исполнение
@@ -89,49 +119,837 @@ func (s *InMemoryRepository) AddComment(ctx context.Context, postID string, pare
 	id := generateID()
 	createdAt := time.Now().UTC()
 
-	comment := &model.Comment{
+	comment := &domain.Comment{
 		ID:        id,
 		PostID:    postID,
 		ParentID:  parentID,
 		AuthorID:  authorID,
 		Content:   content,
 		CreatedAt: createdAt.String(),
+		Version:   1,
 	}
 
-	s.comments[id] = comment
-	log.Printf("Comment added: %+v", comment)
+	ops := append([]walOp{putComment(comment)}, s.counterOps(comment, 1)...)
+	if err := s.commit(ops...); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("comment added",
+		slog.String("comment_id", comment.ID),
+		slog.String("post_id", comment.PostID),
+		slog.String("author_id", comment.AuthorID),
+		logging.Content("content", comment.Content),
+	)
 
 	return comment, nil
 }
 
-// GetCommentsForPost получает комментарии с пагинацией для поста
-func (s *InMemoryRepository) GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*model.CommentWithReplies, error) {
-	var comments []*model.CommentWithReplies
+// UpdatePost изменение поста с проверкой версии
+func (s *InMemoryRepository) UpdatePost(ctx context.Context, id string, expectedVersion int32, title, content *string, editorID string, reason *string) (*domain.Post, error) {
+	// Валидация
+
+	var v validator
+	v.required("editorID", editorID)
+	v.notEmpty("title", title)
+	v.notEmpty("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// Исполнение
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.posts[id]
+	if !exists || stored.Deleted() {
+		return nil, domain.NotFound("post", id)
+	}
+
+	if stored.Version != expectedVersion {
+		return nil, domain.VersionConflict("post", stored.Version)
+	}
+
+	revision := &domain.Revision{
+		Version:  stored.Version,
+		Title:    &stored.Title,
+		Content:  stored.Content,
+		EditorID: editorID,
+		Reason:   reason,
+		EditedAt: time.Now().UTC().String(),
+	}
+
+	post := *stored
+	if title != nil {
+		post.Title = *title
+	}
+	if content != nil {
+		post.Content = *content
+	}
+	post.Version++
+	post.UpdatedAt = &revision.EditedAt
+
+	if err := s.commit(appendPostRevision(id, revision), putPost(&post)); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("post updated",
+		slog.String("post_id", post.ID),
+		slog.String("editor_id", editorID),
+		slog.Int("version", int(post.Version)),
+	)
+
+	return &post, nil
+}
+
+// UpdateComment изменение комментария с проверкой версии
+func (s *InMemoryRepository) UpdateComment(ctx context.Context, id string, expectedVersion int32, content string, editorID string, reason *string) (*domain.Comment, error) {
+	// Валидация
+
+	var v validator
+	v.required("editorID", editorID)
+	v.required("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// Исполнение
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.comments[id]
+	if !exists || stored.Deleted() {
+		return nil, domain.NotFound("comment", id)
+	}
+
+	if stored.Version != expectedVersion {
+		return nil, domain.VersionConflict("comment", stored.Version)
+	}
+
+	revision := &domain.Revision{
+		Version:  stored.Version,
+		Content:  stored.Content,
+		EditorID: editorID,
+		Reason:   reason,
+		EditedAt: time.Now().UTC().String(),
+	}
+
+	comment := *stored
+	comment.Content = content
+	comment.Version++
+	comment.UpdatedAt = &revision.EditedAt
+
+	if err := s.commit(appendCommentRevision(id, revision), putComment(&comment)); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("comment updated",
+		slog.String("comment_id", comment.ID),
+		slog.String("editor_id", editorID),
+		slog.Int("version", int(comment.Version)),
+		logging.Content("content", comment.Content),
+	)
+
+	return &comment, nil
+}
+
+// DeletePost мягкое удаление поста; повторное удаление ничего не меняет
+func (s *InMemoryRepository) DeletePost(ctx context.Context, id, deletedBy string) (*domain.Post, error) {
+	var v validator
+	v.required("deletedBy", deletedBy)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.posts[id]
+	if !exists {
+		return nil, domain.NotFound("post", id)
+	}
+	if stored.Deleted() {
+		return stored, nil
+	}
+
+	post := *stored
+	deletedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	post.DeletedAt = &deletedAt
+	post.DeletedBy = &deletedBy
+
+	if err := s.commit(putPost(&post)); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("post deleted", slog.String("post_id", id), slog.String("deleted_by", deletedBy))
+
+	return &post, nil
+}
+
+// DeleteComment мягкое удаление комментария; повторное удаление ничего не меняет
+func (s *InMemoryRepository) DeleteComment(ctx context.Context, id, deletedBy string) (*domain.Comment, error) {
+	var v validator
+	v.required("deletedBy", deletedBy)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.comments[id]
+	if !exists {
+		return nil, domain.NotFound("comment", id)
+	}
+	if stored.Deleted() {
+		return stored, nil
+	}
+
+	comment := *stored
+	deletedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	comment.DeletedAt = &deletedAt
+	comment.DeletedBy = &deletedBy
+
+	ops := append([]walOp{putComment(&comment)}, s.counterOps(&comment, -1)...)
+	if err := s.commit(ops...); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("comment deleted", slog.String("comment_id", id), slog.String("deleted_by", deletedBy))
+
+	return &comment, nil
+}
+
+// RestorePost отмена мягкого удаления поста
+func (s *InMemoryRepository) RestorePost(ctx context.Context, id string) (*domain.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.posts[id]
+	if !exists {
+		return nil, domain.NotFound("post", id)
+	}
+	if !stored.Deleted() {
+		return stored, nil
+	}
+
+	post := *stored
+	post.DeletedAt = nil
+	post.DeletedBy = nil
+
+	if err := s.commit(putPost(&post)); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("post restored", slog.String("post_id", id))
+
+	return &post, nil
+}
+
+// RestoreComment отмена мягкого удаления комментария
+func (s *InMemoryRepository) RestoreComment(ctx context.Context, id string) (*domain.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.comments[id]
+	if !exists {
+		return nil, domain.NotFound("comment", id)
+	}
+	if !stored.Deleted() {
+		return stored, nil
+	}
+
+	comment := *stored
+	comment.DeletedAt = nil
+	comment.DeletedBy = nil
+
+	ops := append([]walOp{putComment(&comment)}, s.counterOps(&comment, 1)...)
+	if err := s.commit(ops...); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("comment restored", slog.String("comment_id", id))
+
+	return &comment, nil
+}
+
+// counterOps изменения счетчиков поста и родительского комментария, когда comment
+// появился среди видимых (delta = 1) или был удален (delta = -1). Вызывается под s.mu.Lock
+// до commit, поэтому s.comments еще содержит прежнее состояние comment.
+func (s *InMemoryRepository) counterOps(comment *domain.Comment, delta int32) []walOp {
+	var ops []walOp
+
+	if stored := s.posts[comment.PostID]; stored != nil {
+		post := *stored
+		post.CommentCount += delta
+		if delta > 0 {
+			post.LastCommentAt = latest(post.LastCommentAt, &comment.CreatedAt)
+		} else {
+			post.LastCommentAt = s.lastCommentAt(comment.PostID, comment.ID)
+		}
+		ops = append(ops, putPost(&post))
+	}
+
+	if comment.ParentID != nil {
+		if stored := s.comments[*comment.ParentID]; stored != nil {
+			parent := *stored
+			parent.ReplyCount += delta
+			ops = append(ops, putComment(&parent))
+		}
+	}
+
+	return ops
+}
+
+// lastCommentAt время последнего видимого комментария поста, не считая комментария skipID
+func (s *InMemoryRepository) lastCommentAt(postID, skipID string) *string {
+	var last *string
+	for id, c := range s.comments {
+		if c.PostID == postID && id != skipID && !c.Deleted() {
+			last = latest(last, &c.CreatedAt)
+		}
+	}
+	return last
+}
+
+// latest более позднее из двух времен; nil меньше любого
+func latest(a, b *string) *string {
+	if a == nil || (b != nil && *b > *a) {
+		return b
+	}
+	return a
+}
+
+// RepairCounters пересчитывает счетчики комментариев с нуля
+func (s *InMemoryRepository) RepairCounters(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	commentCounts := make(map[string]int32)
+	lastComments := make(map[string]*string)
+	replyCounts := make(map[string]int32)
+	for _, c := range s.comments {
+		if c.Deleted() {
+			continue
+		}
+		commentCounts[c.PostID]++
+		lastComments[c.PostID] = latest(lastComments[c.PostID], &c.CreatedAt)
+		if c.ParentID != nil {
+			replyCounts[*c.ParentID]++
+		}
+	}
+
+	var ops []walOp
+	for id, stored := range s.posts {
+		last := lastComments[id]
+		if stored.CommentCount == commentCounts[id] && sameTime(stored.LastCommentAt, last) {
+			continue
+		}
+		post := *stored
+		post.CommentCount = commentCounts[id]
+		if last != nil {
+			lastCommentAt := *last
+			post.LastCommentAt = &lastCommentAt
+		} else {
+			post.LastCommentAt = nil
+		}
+		ops = append(ops, putPost(&post))
+	}
+	for id, stored := range s.comments {
+		if stored.ReplyCount == replyCounts[id] {
+			continue
+		}
+		comment := *stored
+		comment.ReplyCount = replyCounts[id]
+		ops = append(ops, putComment(&comment))
+	}
+
+	if err := s.commit(ops...); err != nil {
+		return 0, err
+	}
+
+	repaired := int64(len(ops))
+	if repaired > 0 {
+		logging.FromContext(ctx).Info("counters repaired", slog.Int64("count", repaired))
+	}
+
+	return repaired, nil
+}
+
+// sameTime равны ли два необязательных времени
+func sameTime(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// PurgeDeleted окончательное удаление постов и комментариев, удаленных раньше before
+func (s *InMemoryRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ops []walOp
+	removed := make(map[string]bool)
+
+	for id, post := range s.posts {
+		if !deletedBefore(post.DeletedAt, before) {
+			continue
+		}
+		for commentID, comment := range s.comments {
+			if comment.PostID == id {
+				ops = append(ops, deleteComment(commentID))
+				removed[commentID] = true
+			}
+		}
+		ops = append(ops, deletePost(id))
+	}
+
+	// Удаляем только комментарии без ответов; повторяем, пока удаляются
+	// целые ветки из удаленных комментариев
+	for {
+		hasReplies := make(map[string]bool)
+		for id, comment := range s.comments {
+			if !removed[id] && comment.ParentID != nil {
+				hasReplies[*comment.ParentID] = true
+			}
+		}
+
+		count := 0
+		for id, comment := range s.comments {
+			if !removed[id] && !hasReplies[id] && deletedBefore(comment.DeletedAt, before) {
+				ops = append(ops, deleteComment(id))
+				removed[id] = true
+				count++
+			}
+		}
+		if count == 0 {
+			break
+		}
+	}
+
+	if len(ops) == 0 {
+		return 0, nil
+	}
+
+	if err := s.commit(ops...); err != nil {
+		return 0, err
+	}
+
+	purged := int64(len(ops))
+	logging.FromContext(ctx).Info("deleted records purged", slog.Int64("count", purged))
+
+	return purged, nil
+}
+
+// deletedBefore удалена ли запись раньше before
+func deletedBefore(deletedAt *string, before time.Time) bool {
+	if deletedAt == nil {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339Nano, *deletedAt)
+	return err == nil && t.Before(before)
+}
+
+// EraseAuthor удаление или анонимизация всех данных автора
+func (s *InMemoryRepository) EraseAuthor(ctx context.Context, authorID string, mode domain.ErasureMode) (*domain.ErasureResult, error) {
+	if err := validateErasure(authorID, mode); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &domain.ErasureResult{AuthorID: authorID, Mode: mode}
+	erased := domain.ErasedAuthor
+
+	var ops []walOp
+	removedPosts := make(map[string]bool)
+	removedComments := make(map[string]bool)
+
+	for id, stored := range s.posts {
+		if stored.AuthorID != authorID {
+			continue
+		}
+
+		if mode == domain.ErasureModeDeletePosts {
+			for commentID, comment := range s.comments {
+				if comment.PostID == id {
+					ops = append(ops, deleteComment(commentID))
+					removedComments[commentID] = true
+				}
+			}
+			ops = append(ops, deletePost(id))
+			removedPosts[id] = true
+			result.PostsDeleted++
+			continue
+		}
+
+		post := *stored
+		post.AuthorID = domain.ErasedAuthor
+		post.Title = domain.ErasedAuthor
+		post.Content = domain.ErasedAuthor
+		if post.DeletedBy != nil && *post.DeletedBy == authorID {
+			post.DeletedBy = &erased
+		}
+		ops = append(ops, putPost(&post), setPostRevisions(id, nil))
+		removedPosts[id] = true
+		result.PostsAnonymized++
+	}
+
+	for id, stored := range s.comments {
+		if stored.AuthorID != authorID || removedComments[id] {
+			continue
+		}
+
+		comment := *stored
+		comment.AuthorID = domain.ErasedAuthor
+		comment.Content = domain.ErasedAuthor
+		if comment.DeletedBy != nil && *comment.DeletedBy == authorID {
+			comment.DeletedBy = &erased
+		}
+		ops = append(ops, putComment(&comment), setCommentRevisions(id, nil))
+		removedComments[id] = true
+		result.CommentsAnonymized++
+	}
+
+	// Автор мог редактировать и удалять чужие записи; свои анонимизированные записи уже исправлены выше
+	for id, revisions := range s.postRevisions {
+		if !removedPosts[id] {
+			if replaced, ok := eraseEditor(revisions, authorID); ok {
+				ops = append(ops, setPostRevisions(id, replaced))
+			}
+		}
+	}
+	for id, revisions := range s.commentRevisions {
+		if !removedComments[id] {
+			if replaced, ok := eraseEditor(revisions, authorID); ok {
+				ops = append(ops, setCommentRevisions(id, replaced))
+			}
+		}
+	}
+	for id, post := range s.posts {
+		if !removedPosts[id] && post.DeletedBy != nil && *post.DeletedBy == authorID {
+			p := *post
+			p.DeletedBy = &erased
+			ops = append(ops, putPost(&p))
+		}
+	}
+	for id, comment := range s.comments {
+		if !removedComments[id] && comment.DeletedBy != nil && *comment.DeletedBy == authorID {
+			c := *comment
+			c.DeletedBy = &erased
+			ops = append(ops, putComment(&c))
+		}
+	}
+
+	if err := s.commit(ops...); err != nil {
+		return nil, err
+	}
+
+	// Прежние версии записей остаются в журнале до следующего снимка;
+	// стертые данные не должны пережить перезапуск, поэтому снимок пишется сразу
+	if s.durable != nil {
+		if err := s.snapshot(ctx); err != nil {
+			return nil, fmt.Errorf("author erased, but failed to compact log: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// eraseEditor копия истории, в которой автор правок заменен на ErasedAuthor;
+// false, если автор в истории не встречается
+func eraseEditor(revisions []*domain.Revision, authorID string) ([]*domain.Revision, bool) {
+	var replaced []*domain.Revision
+	for i, r := range revisions {
+		if r.EditorID != authorID {
+			continue
+		}
+		if replaced == nil {
+			replaced = append([]*domain.Revision(nil), revisions...)
+		}
+		revision := *r
+		revision.EditorID = domain.ErasedAuthor
+		replaced[i] = &revision
+	}
+	return replaced, replaced != nil
+}
+
+// ExportAuthor все посты и комментарии автора
+func (s *InMemoryRepository) ExportAuthor(ctx context.Context, authorID string) (*domain.AuthorArchive, error) {
+	var v validator
+	v.required("authorID", authorID)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	archive := &domain.AuthorArchive{
+		AuthorID:   authorID,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Posts:      []*domain.Post{},
+		Comments:   []*domain.Comment{},
+	}
+
+	for _, post := range s.posts {
+		if post.AuthorID == authorID {
+			archive.Posts = append(archive.Posts, post)
+		}
+	}
+	for _, comment := range s.comments {
+		if comment.AuthorID == authorID {
+			archive.Comments = append(archive.Comments, comment)
+		}
+	}
+
+	sort.Slice(archive.Posts, func(i, j int) bool { return archive.Posts[i].CreatedAt < archive.Posts[j].CreatedAt })
+	sort.Slice(archive.Comments, func(i, j int) bool { return archive.Comments[i].CreatedAt < archive.Comments[j].CreatedAt })
+
+	return archive, nil
+}
+
+// ExportRecords страница выгрузки. Хранимые записи не изменяются на месте,
+// поэтому отдаются без копирования.
+func (s *InMemoryRepository) ExportRecords(ctx context.Context, limit int, after *domain.RecordCursor) ([]*domain.Record, error) {
+	if after != nil {
+		if err := after.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	posts := make([]*domain.Post, 0, len(s.posts))
+	for _, post := range s.posts {
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt != posts[j].CreatedAt {
+			return posts[i].CreatedAt < posts[j].CreatedAt
+		}
+		return posts[i].ID < posts[j].ID
+	})
+
+	if after != nil {
+		i, _ := slices.BinarySearchFunc(posts, after, func(p *domain.Post, c *domain.RecordCursor) int {
+			return cmp.Or(cmp.Compare(p.CreatedAt, c.CreatedAt), cmp.Compare(p.ID, c.ID))
+		})
+		for i < len(posts) && posts[i].CreatedAt == after.CreatedAt && posts[i].ID == after.ID {
+			i++
+		}
+		posts = posts[i:]
+	}
+	posts = posts[:min(limit, len(posts))]
+
+	page := make(map[string]bool, len(posts))
+	for _, post := range posts {
+		page[post.ID] = true
+	}
+
+	comments := make(map[string][]*domain.Comment)
 	for _, c := range s.comments {
-		if c.PostID == postID {
-			comments = append(comments, &model.CommentWithReplies{
-				ID:        c.ID,
-				PostID:    c.PostID,
-				ParentID:  c.ParentID,
-				AuthorID:  c.AuthorID,
-				Content:   c.Content,
-				CreatedAt: c.CreatedAt,
-			})
+		if page[c.PostID] {
+			comments[c.PostID] = append(comments[c.PostID], c)
 		}
 	}
+	for _, list := range comments {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].CreatedAt != list[j].CreatedAt {
+				return list[i].CreatedAt < list[j].CreatedAt
+			}
+			return list[i].ID < list[j].ID
+		})
+	}
 
-	sort.Slice(comments, func(i, j int) bool { return comments[i].CreatedAt < comments[j].CreatedAt })
+	return exportPage(posts, comments, s.postRevisions, s.commentRevisions), nil
+}
 
-	comments = paginateComments(comments, limit, cursor)
-	log.Printf("Comments fetched for post %s: %d comments", postID, len(comments))
+// ImportRecords сохраняет записи одной записью журнала. Счетчики постов и родительских
+// комментариев увеличиваются на загруженные неудаленные комментарии.
+func (s *InMemoryRepository) ImportRecords(ctx context.Context, records []*domain.Record) (int64, error) {
+	imported := make([]*domain.Record, 0, len(records))
+	for _, r := range records {
+		record, err := importedRecord(r, rfc3339)
+		if err != nil {
+			return 0, err
+		}
+		imported = append(imported, record)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Новые записи и копии существующих, у которых меняются счетчики, в порядке появления
+	posts := make(map[string]*domain.Post)
+	comments := make(map[string]*domain.Comment)
+	var postIDs, commentIDs []string
+	var revisionOps []walOp
+
+	post := func(id string) *domain.Post {
+		if p, ok := posts[id]; ok {
+			return p
+		}
+		stored := s.posts[id]
+		if stored == nil {
+			return nil
+		}
+		p := *stored
+		posts[id] = &p
+		postIDs = append(postIDs, id)
+		return &p
+	}
+	comment := func(id string) *domain.Comment {
+		if c, ok := comments[id]; ok {
+			return c
+		}
+		stored := s.comments[id]
+		if stored == nil {
+			return nil
+		}
+		c := *stored
+		comments[id] = &c
+		commentIDs = append(commentIDs, id)
+		return &c
+	}
+
+	var count int64
+	for _, r := range imported {
+		if p := r.Post; p != nil {
+			if s.posts[p.ID] != nil || posts[p.ID] != nil {
+				continue
+			}
+			posts[p.ID] = p
+			postIDs = append(postIDs, p.ID)
+			if len(r.Revisions) > 0 {
+				revisionOps = append(revisionOps, setPostRevisions(p.ID, r.Revisions))
+			}
+			count++
+			continue
+		}
+
+		c := r.Comment
+		if s.comments[c.ID] != nil || comments[c.ID] != nil {
+			continue
+		}
+
+		parentPost := post(c.PostID)
+		var parent *domain.Comment
+		if c.ParentID != nil {
+			parent = comment(*c.ParentID)
+		}
+		if err := importedCommentErr(parentPost != nil, c.ParentID == nil || (parent != nil && parent.PostID == c.PostID)); err != nil {
+			return 0, err
+		}
+
+		comments[c.ID] = c
+		commentIDs = append(commentIDs, c.ID)
+		if len(r.Revisions) > 0 {
+			revisionOps = append(revisionOps, setCommentRevisions(c.ID, r.Revisions))
+		}
+		if !c.Deleted() {
+			parentPost.CommentCount++
+			parentPost.LastCommentAt = latest(parentPost.LastCommentAt, &c.CreatedAt)
+			if parent != nil {
+				parent.ReplyCount++
+			}
+		}
+		count++
+	}
+
+	ops := make([]walOp, 0, len(postIDs)+len(commentIDs)+len(revisionOps))
+	for _, id := range postIDs {
+		ops = append(ops, putPost(posts[id]))
+	}
+	for _, id := range commentIDs {
+		ops = append(ops, putComment(comments[id]))
+	}
+	ops = append(ops, revisionOps...)
+
+	if err := s.commit(ops...); err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		logging.FromContext(ctx).Info("records imported", slog.Int64("count", count))
+	}
+
+	return count, nil
+}
+
+// GetPostRevisions история изменений поста
+func (s *InMemoryRepository) GetPostRevisions(ctx context.Context, postID string, limit int, after *int32) ([]*domain.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return paginateRevisions(s.postRevisions[postID], limit, after), nil
+}
+
+// GetCommentRevisions история изменений комментария
+func (s *InMemoryRepository) GetCommentRevisions(ctx context.Context, commentID string, limit int, after *int32) ([]*domain.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return paginateRevisions(s.commentRevisions[commentID], limit, after), nil
+}
+
+// paginateRevisions версии после after, не больше limit; revisions упорядочены по версии
+func paginateRevisions(revisions []*domain.Revision, limit int, after *int32) []*domain.Revision {
+	result := make([]*domain.Revision, 0, min(len(revisions), limit))
+	for _, r := range revisions {
+		if after != nil && r.Version <= *after {
+			continue
+		}
+		if len(result) == limit {
+			break
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// GetCommentsForPost получает комментарии с пагинацией для поста
+func (s *InMemoryRepository) GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*domain.CommentWithReplies, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := paginateComments(s.commentsByPost()[postID], limit, cursor)
+	logging.FromContext(ctx).Debug("comments fetched", slog.String("post_id", postID), slog.Int("count", len(comments)))
 	return buildCommentTree(comments), nil
 }
 
+// GetLatestComments последние неудаленные комментарии поста, от новых к старым
+func (s *InMemoryRepository) GetLatestComments(ctx context.Context, postID string, limit int) ([]*domain.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var comments []*domain.Comment
+	for _, stored := range s.comments {
+		if stored.PostID == postID && !stored.Deleted() {
+			comment := *stored
+			comments = append(comments, &comment)
+		}
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		if comments[i].CreatedAt != comments[j].CreatedAt {
+			return comments[i].CreatedAt > comments[j].CreatedAt
+		}
+		return comments[i].ID > comments[j].ID
+	})
+
+	return comments[:min(len(comments), limit)], nil
+}
+
 // paginateComments выполняет пагинацию комментариев
-func paginateComments(comments []*model.CommentWithReplies, limit int, cursor *string) []*model.CommentWithReplies {
+func paginateComments(comments []*domain.CommentWithReplies, limit int, cursor *string) []*domain.CommentWithReplies {
 	if cursor != nil && *cursor != "" {
 		// Если курсор существует, начинаем с комментария, следующего за ним
-		var filteredComments []*model.CommentWithReplies
+		var filteredComments []*domain.CommentWithReplies
 		found := false
 		for _, c := range comments {
 			if found {
@@ -152,73 +970,105 @@ func paginateComments(comments []*model.CommentWithReplies, limit int, cursor *s
 	return comments
 }
 
-// GetPosts получает все посты из памяти
-func (s *InMemoryRepository) GetPosts(ctx context.Context) ([]*model.Post, error) {
-	var posts []*model.Post
-	for _, p := range s.posts {
-		posts = append(posts, p)
+// GetPosts получает все посты из памяти в порядке создания
+func (s *InMemoryRepository) GetPosts(ctx context.Context) ([]*domain.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	posts := make([]*domain.Post, 0, len(s.posts))
+	for _, stored := range s.posts {
+		post := *stored
+		posts = append(posts, &post)
 	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt != posts[j].CreatedAt {
+			return posts[i].CreatedAt < posts[j].CreatedAt
+		}
+		return posts[i].ID < posts[j].ID
+	})
+
 	return posts, nil
 }
 
-// GetPostByID получает пост по ID из памяти
-func (s *InMemoryRepository) GetPostByID(ctx context.Context, id string) (*model.Post, error) {
-	post, exists := s.posts[id]
-	if !exists {
-		return nil, fmt.Errorf("post not found")
+// GetLatestPosts последние неудаленные посты, от новых к старым; с authorID — только посты автора
+func (s *InMemoryRepository) GetLatestPosts(ctx context.Context, authorID string, limit int) ([]*domain.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var posts []*domain.Post
+	for _, stored := range s.posts {
+		if stored.Deleted() || authorID != "" && stored.AuthorID != authorID {
+			continue
+		}
+		post := *stored
+		posts = append(posts, &post)
 	}
 
-	// Загружаем комментарии и их ответы для поста
-	comments := make([]*model.CommentWithReplies, 0)
-	for _, comment := range s.comments {
-		if comment.PostID == id {
-			// Добавляем комментарий с ответами
-			commentWithReplies := &model.CommentWithReplies{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				ParentID:  comment.ParentID,
-				AuthorID:  comment.AuthorID,
-				Content:   comment.Content,
-				CreatedAt: comment.CreatedAt,
-				Replies:   s.getRepliesForComment(comment.ID), // Загружаем ответы для комментария
-			}
-			comments = append(comments, commentWithReplies)
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt != posts[j].CreatedAt {
+			return posts[i].CreatedAt > posts[j].CreatedAt
 		}
+		return posts[i].ID > posts[j].ID
+	})
+
+	return posts[:min(len(posts), limit)], nil
+}
+
+// GetPostByID получает пост по ID из памяти
+func (s *InMemoryRepository) GetPostByID(ctx context.Context, id string) (*domain.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, exists := s.posts[id]
+	if !exists {
+		return nil, domain.NotFound("post", id)
 	}
 
-	// Добавляем комментарии в пост
-	post.Comments = comments
+	post := *stored
 
-	return post, nil
+	return &post, nil
 }
 
-// Функция для получения ответов на комментарий
-func (s *InMemoryRepository) getRepliesForComment(commentID string) []*model.CommentWithReplies {
-	replies := make([]*model.CommentWithReplies, 0)
-	for _, comment := range s.comments {
-		// Разыменовываем ParentID, если он не nil, и сравниваем
-		if comment.ParentID != nil && *comment.ParentID == commentID {
-			replies = append(replies, &model.CommentWithReplies{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				ParentID:  comment.ParentID,
-				AuthorID:  comment.AuthorID,
-				Content:   comment.Content,
-				CreatedAt: comment.CreatedAt,
-				Replies:   []*model.CommentWithReplies{}, // Ответы для этого комментария
-			})
-		}
+// commentsByPost копии комментариев, сгруппированные по постам, в порядке создания.
+// Копии можно связывать в дерево, не затрагивая хранимые записи. Вызывается под s.mu.
+func (s *InMemoryRepository) commentsByPost() map[string][]*domain.CommentWithReplies {
+	byPost := make(map[string][]*domain.CommentWithReplies)
+	for _, c := range s.comments {
+		byPost[c.PostID] = append(byPost[c.PostID], &domain.CommentWithReplies{
+			ID:         c.ID,
+			PostID:     c.PostID,
+			ParentID:   c.ParentID,
+			AuthorID:   c.AuthorID,
+			Content:    c.Content,
+			CreatedAt:  c.CreatedAt,
+			Version:    c.Version,
+			DeletedAt:  c.DeletedAt,
+			DeletedBy:  c.DeletedBy,
+			ReplyCount: c.ReplyCount,
+			UpdatedAt:  c.UpdatedAt,
+		})
 	}
-	return replies
+
+	for _, comments := range byPost {
+		sort.Slice(comments, func(i, j int) bool {
+			if comments[i].CreatedAt != comments[j].CreatedAt {
+				return comments[i].CreatedAt < comments[j].CreatedAt
+			}
+			return comments[i].ID < comments[j].ID
+		})
+	}
+
+	return byPost
 }
 
 // buildCommentTree строит иерархию комментариев
-func buildCommentTree(comments []*model.CommentWithReplies) []*model.CommentWithReplies {
-	commentMap := make(map[string]*model.CommentWithReplies)
+func buildCommentTree(comments []*domain.CommentWithReplies) []*domain.CommentWithReplies {
+	commentMap := make(map[string]*domain.CommentWithReplies)
 	for _, c := range comments {
 		commentMap[c.ID] = c
 	}
-	var roots []*model.CommentWithReplies
+	var roots []*domain.CommentWithReplies
 	for _, c := range comments {
 		if c.ParentID == nil || *c.ParentID == "" { // Корневой комментарий
 			roots = append(roots, c)
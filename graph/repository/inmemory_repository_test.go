@@ -0,0 +1,278 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/graph/repository"
+	"github.com/YakovlevIgA/forozon/graph/storagetest"
+)
+
+func TestInMemoryRepository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) domain.Storage {
+		return repository.NewInMemoryRepository()
+	})
+}
+
+func TestDurableInMemoryRepository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) domain.Storage {
+		repo, err := repository.NewDurableInMemoryRepository(context.Background(), t.TempDir(), repository.DurabilityOptions{
+			Fsync: repository.FsyncNever,
+		})
+		if err != nil {
+			t.Fatalf("NewDurableInMemoryRepository: %v", err)
+		}
+		t.Cleanup(func() { repo.Close(context.Background()) })
+
+		return repo
+	})
+}
+
+// openDurable открывает репозиторий в каталоге dir; журнал пишется без fsync
+func openDurable(t *testing.T, dir string) *repository.InMemoryRepository {
+	t.Helper()
+
+	repo, err := repository.NewDurableInMemoryRepository(context.Background(), dir, repository.DurabilityOptions{
+		Fsync: repository.FsyncNever,
+	})
+	if err != nil {
+		t.Fatalf("NewDurableInMemoryRepository: %v", err)
+	}
+	return repo
+}
+
+// crashCopy копирует файлы каталога данных в новый каталог, как если бы процесс
+// упал в этот момент: репозиторий в dir не закрывается и снимок не пишется
+func crashCopy(t *testing.T, dir string) string {
+	t.Helper()
+
+	crashed := t.TempDir()
+	for _, name := range []string{"wal.log", "snapshot.json"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(crashed, name), data, 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return crashed
+}
+
+// dump состояние репозитория в JSON для сравнения
+func dump(t *testing.T, repo *repository.InMemoryRepository) string {
+	t.Helper()
+
+	records, err := repo.ExportRecords(context.Background(), 1000, nil)
+	if err != nil {
+		t.Fatalf("ExportRecords: %v", err)
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		t.Fatalf("encode records: %v", err)
+	}
+	return string(data)
+}
+
+// fillRepository создание, правка и удаление: в журнале оказываются все виды операций
+func fillRepository(t *testing.T, repo *repository.InMemoryRepository) *domain.Post {
+	t.Helper()
+	ctx := context.Background()
+
+	post, err := repo.CreatePost(ctx, "title", "content", "author", false)
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	root, err := repo.AddComment(ctx, post.ID, nil, "reader", "root")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	reply, err := repo.AddComment(ctx, post.ID, &root.ID, "author", "reply")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	content := "edited content"
+	if _, err := repo.UpdatePost(ctx, post.ID, post.Version, nil, &content, "author", nil); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if _, err := repo.UpdateComment(ctx, root.ID, root.Version, "edited root", "reader", nil); err != nil {
+		t.Fatalf("UpdateComment: %v", err)
+	}
+	if _, err := repo.DeleteComment(ctx, reply.ID, "admin"); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+
+	return post
+}
+
+func TestDurableInMemoryReplaysLogAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	repo := openDurable(t, dir)
+	defer repo.Close(context.Background())
+
+	fillRepository(t, repo)
+	want := dump(t, repo)
+
+	reopened := openDurable(t, crashCopy(t, dir))
+	defer reopened.Close(context.Background())
+
+	if got := dump(t, reopened); got != want {
+		t.Errorf("state after replay:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDurableInMemoryTruncatesTornTail(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := openDurable(t, dir)
+	defer repo.Close(ctx)
+
+	fillRepository(t, repo)
+	want := dump(t, repo)
+
+	walPath := filepath.Join(dir, "wal.log")
+	before, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatalf("stat wal: %v", err)
+	}
+	if _, err := repo.CreatePost(ctx, "lost", "lost", "author", false); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+	}{
+		{
+			// Сбой посреди записи: на диске только начало последней записи
+			name:    "truncated record",
+			corrupt: func(data []byte) []byte { return data[:len(data)-5] },
+		},
+		{
+			name:    "truncated header",
+			corrupt: func(data []byte) []byte { return data[:before.Size()+3] },
+		},
+		{
+			// Запись целиком, но содержимое не совпадает с crc32
+			name: "checksum mismatch",
+			corrupt: func(data []byte) []byte {
+				data[len(data)-2] ^= 0xff
+				return data
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crashed := crashCopy(t, dir)
+			path := filepath.Join(crashed, "wal.log")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read wal: %v", err)
+			}
+			if err := os.WriteFile(path, tt.corrupt(data), 0o644); err != nil {
+				t.Fatalf("write wal: %v", err)
+			}
+
+			reopened := openDurable(t, crashed)
+			defer reopened.Close(ctx)
+
+			if got := dump(t, reopened); got != want {
+				t.Errorf("state after torn tail:\n%s\nwant:\n%s", got, want)
+			}
+
+			// Хвост обрезан до последней целой записи, новые записи дописываются после нее
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("stat wal: %v", err)
+			}
+			if info.Size() != before.Size() {
+				t.Errorf("wal size after reopen = %d, want %d", info.Size(), before.Size())
+			}
+
+			post, err := reopened.CreatePost(ctx, "after", "after", "author", false)
+			if err != nil {
+				t.Fatalf("CreatePost: %v", err)
+			}
+			after := dump(t, reopened)
+
+			again := openDurable(t, crashCopy(t, crashed))
+			defer again.Close(ctx)
+			if got := dump(t, again); got != after {
+				t.Errorf("state after second reopen:\n%s\nwant:\n%s", got, after)
+			}
+			if _, err := again.GetPostByID(ctx, post.ID); err != nil {
+				t.Errorf("post written after truncation is lost: %v", err)
+			}
+		})
+	}
+}
+
+func TestDurableInMemoryCrashBetweenSnapshotAndLogReset(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := openDurable(t, dir)
+	defer repo.Close(ctx)
+
+	fillRepository(t, repo)
+	want := dump(t, repo)
+
+	wal, err := os.ReadFile(filepath.Join(dir, "wal.log"))
+	if err != nil {
+		t.Fatalf("read wal: %v", err)
+	}
+	if err := repo.Snapshot(ctx); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	// Снимок уже переименован, а журнал не очищен: его записи проигрываются поверх снимка
+	crashed := crashCopy(t, dir)
+	if err := os.WriteFile(filepath.Join(crashed, "wal.log"), wal, 0o644); err != nil {
+		t.Fatalf("write wal: %v", err)
+	}
+
+	reopened := openDurable(t, crashed)
+	defer reopened.Close(ctx)
+
+	if got := dump(t, reopened); got != want {
+		t.Errorf("state after replaying the log over the snapshot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDurableInMemoryEraseAuthorCompactsLog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := openDurable(t, dir)
+	defer repo.Close(ctx)
+
+	post, err := repo.CreatePost(ctx, "title", "secret post", "author", false)
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	if _, err := repo.AddComment(ctx, post.ID, nil, "author", "secret comment"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	if _, err := repo.EraseAuthor(ctx, "author", domain.ErasureModeAnonymize); err != nil {
+		t.Fatalf("EraseAuthor: %v", err)
+	}
+
+	// Стертые данные не должны оставаться ни в журнале, ни в снимке
+	for _, name := range []string{"wal.log", "snapshot.json"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if strings.Contains(string(data), "secret") || strings.Contains(string(data), `"author"`) {
+			t.Errorf("%s still contains erased data: %s", name, data)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Репозитории пишут в лог каждое изменение; в тестах это только шум
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
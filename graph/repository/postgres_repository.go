@@ -3,168 +3,1052 @@ package repository
 import (
 	"context"
 	"fmt"
-	"github.com/YakovlevIgA/forozon/graph"
-	"github.com/YakovlevIgA/forozon/graph/model"
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/logging"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log/slog"
 	"time"
 )
 
-// PostgresRepository репозиторий на основе postgres
+// PostgresRepository репозиторий на основе postgres. Изменения идут на основной сервер conn,
+// чтение постов и комментариев — на реплики, если они заданы
 type PostgresRepository struct {
-	conn *pgx.Conn
+	conn     *pgxpool.Pool
+	replicas *replicaSet
 }
 
-// NewPostgresRepository создает новый экземпляр PostgresRepository
-func NewPostgresRepository(conn *pgx.Conn) (graph.Storage, error) {
-	return &PostgresRepository{conn: conn}, nil
+// NewPostgresRepository создает новый экземпляр PostgresRepository; replicas — пулы реплик для чтения
+func NewPostgresRepository(conn *pgxpool.Pool, replicas ...*pgxpool.Pool) (*PostgresRepository, error) {
+	s := &PostgresRepository{conn: conn}
+	if len(replicas) > 0 {
+		s.replicas = newReplicaSet(replicas)
+	}
+	return s, nil
 }
 
 // CreatePost создание поста
-func (s *PostgresRepository) CreatePost(ctx context.Context, title, content, authorID string, commentsDisabled bool) (*model.Post, error) {
+func (s *PostgresRepository) CreatePost(ctx context.Context, title, content, authorID string, commentsDisabled bool) (*domain.Post, error) {
 	// Валидация
 
-	if title == "" {
-		return nil, fmt.Errorf("title is required")
-	}
-
-	if content == "" {
-		return nil, fmt.Errorf("content is required")
-	}
-
-	if authorID == "" {
-		return nil, fmt.Errorf("authorID is required")
+	var v validator
+	v.required("title", title)
+	v.required("content", content)
+	v.required("authorID", authorID)
+	if err := v.err(); err != nil {
+		return nil, err
 	}
 
 	// Исполнение
 
 	tx, err := s.conn.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("unable to begin transaction: %v", err)
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	id := generateID()
-	createdAt := time.Now().UTC()
-	_, err = tx.Exec(
+	createdAt := time.Now().UTC().String()
+	_, err = traced(tx).Exec(
 		ctx,
 		"INSERT INTO posts (id, title, content, authorID, commentsDisabled, createdAt) VALUES ($1, $2, $3, $4, $5, $6)",
 		id, title, content, authorID, commentsDisabled, createdAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert post: %v", err)
+		return nil, fmt.Errorf("failed to insert post: %w", err)
 	}
 
-	post := &model.Post{
+	post := &domain.Post{
 		ID:               id,
 		Title:            title,
 		Content:          content,
 		AuthorID:         authorID,
-		CreatedAt:        createdAt.String(),
+		CreatedAt:        createdAt,
 		CommentsDisabled: commentsDisabled,
+		Version:          1,
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("unable to commit transaction: %v", err)
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
 	}
 
+	logging.FromContext(ctx).Info("post created",
+		slog.String("post_id", post.ID),
+		slog.String("author_id", post.AuthorID),
+		logging.Content("title", post.Title),
+	)
+
 	return post, nil
 }
 
 // AddComment добавление комментария
-func (s *PostgresRepository) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*model.Comment, error) {
+func (s *PostgresRepository) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*domain.Comment, error) {
 	// Валидация
 
-	if authorID == "" {
-		return nil, fmt.Errorf("authorID is required")
+	var v validator
+	v.required("authorID", authorID)
+	v.required("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
 	}
 
-	if content == "" {
-		return nil, fmt.Errorf("content is required")
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	post, err := s.GetPostByID(ctx, postID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get post for comment: %v", err)
+	q := traced(tx)
+
+	// Проверки выполняются под блокировкой поста, как в lockPostCounters: удаление поста
+	// и комментариев берет ту же блокировку, поэтому не может проскочить до вставки
+	var commentsDisabled, deleted bool
+	err = q.QueryRow(ctx, "SELECT commentsDisabled, deletedAt IS NOT NULL FROM posts WHERE id = $1 FOR NO KEY UPDATE", postID).Scan(&commentsDisabled, &deleted)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to lock post: %w", err)
+	}
+	if err == pgx.ErrNoRows || deleted {
+		return nil, domain.NotFound("post", postID)
 	}
 
-	if post == nil {
-		return nil, fmt.Errorf("post for comment not found")
+	if commentsDisabled {
+		return nil, domain.CommentsDisabled(postID)
 	}
 
-	if post.CommentsDisabled {
-		return nil, fmt.Errorf("comments are disabled for this post")
+	if parentID != nil {
+		var parentPostID string
+		err := q.QueryRow(ctx, "SELECT postID FROM comments WHERE id=$1 AND deletedAt IS NULL", *parentID).Scan(&parentPostID)
+		if err != nil && err != pgx.ErrNoRows {
+			return nil, fmt.Errorf("failed to get parent comment: %w", err)
+		}
+		if err == pgx.ErrNoRows || parentPostID != postID {
+			v.add("parentID", "parent comment not found in this post")
+			return nil, v.err()
+		}
 	}
 
 	// Исполнение
 
 	id := generateID()
-
-	tx, err := s.conn.Begin(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("unable to begin transaction: %v", err)
-	}
-	defer tx.Rollback(ctx)
-
 	createdAt := time.Now().UTC().String()
-	_, err = tx.Exec(
+	_, err = q.Exec(
 		ctx,
 		"INSERT INTO comments (id, postID, parentID, authorID, content, createdAt) VALUES ($1, $2, $3, $4, $5, $6)",
 		id, postID, parentID, authorID, content, createdAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert comment: %v", err)
+		return nil, fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	if err := adjustPostgresCounters(ctx, q, postID, parentID, 1); err != nil {
+		return nil, err
 	}
 
-	comment := &model.Comment{
+	comment := &domain.Comment{
 		ID:        id,
 		PostID:    postID,
 		ParentID:  parentID,
 		AuthorID:  authorID,
 		Content:   content,
 		CreatedAt: createdAt,
+		Version:   1,
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("unable to commit transaction: %v", err)
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
 	}
 
+	logging.FromContext(ctx).Info("comment added",
+		slog.String("comment_id", comment.ID),
+		slog.String("post_id", comment.PostID),
+		slog.String("author_id", comment.AuthorID),
+		logging.Content("content", comment.Content),
+	)
+
 	return comment, nil
 }
 
-func (s *PostgresRepository) GetPostByID(ctx context.Context, id string) (*model.Post, error) {
-	var post model.Post
-	err := s.conn.QueryRow(ctx, "SELECT id, title, content, authorID, createdAt, commentsDisabled FROM posts WHERE id=$1", id).Scan(
-		&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.CommentsDisabled,
+// UpdatePost изменение поста; версия проверяется и увеличивается одним условным UPDATE,
+// в том же запросе предыдущая версия сохраняется в post_revisions
+func (s *PostgresRepository) UpdatePost(ctx context.Context, id string, expectedVersion int32, title, content *string, editorID string, reason *string) (*domain.Post, error) {
+	// Валидация
+
+	var v validator
+	v.required("editorID", editorID)
+	v.notEmpty("title", title)
+	v.notEmpty("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// Исполнение
+
+	var post domain.Post
+	err := traced(s.conn).QueryRow(ctx, `
+		WITH old AS (
+			SELECT id, title, content, version FROM posts WHERE id = $1 AND version = $2 AND deletedAt IS NULL FOR UPDATE
+		), revision AS (
+			INSERT INTO post_revisions (postID, version, title, content, editorID, reason, editedAt)
+			SELECT id, version, title, content, $5, $6, $7 FROM old
+		)
+		UPDATE posts p SET title = COALESCE($3, p.title), content = COALESCE($4, p.content), version = p.version + 1, updatedAt = $7
+		FROM old WHERE p.id = old.id
+		RETURNING p.id, p.title, p.content, p.authorID, p.createdAt, p.commentsDisabled, p.version, p.commentCount, p.lastCommentAt, p.updatedAt`,
+		id, expectedVersion, title, content, editorID, reason, time.Now().UTC().String(),
+	).Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.CommentsDisabled, &post.Version, &post.CommentCount, &post.LastCommentAt, &post.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, s.versionError(ctx, "post", "SELECT version FROM posts WHERE id=$1 AND deletedAt IS NULL", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("post updated",
+		slog.String("post_id", post.ID),
+		slog.String("editor_id", editorID),
+		slog.Int("version", int(post.Version)),
 	)
+
+	return &post, nil
+}
+
+// UpdateComment изменение комментария; версия проверяется и увеличивается одним условным UPDATE,
+// в том же запросе предыдущая версия сохраняется в comment_revisions
+func (s *PostgresRepository) UpdateComment(ctx context.Context, id string, expectedVersion int32, content string, editorID string, reason *string) (*domain.Comment, error) {
+	// Валидация
+
+	var v validator
+	v.required("editorID", editorID)
+	v.required("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// Исполнение
+
+	var comment domain.Comment
+	err := traced(s.conn).QueryRow(ctx, `
+		WITH old AS (
+			SELECT id, content, version FROM comments WHERE id = $1 AND version = $2 AND deletedAt IS NULL FOR UPDATE
+		), revision AS (
+			INSERT INTO comment_revisions (commentID, version, content, editorID, reason, editedAt)
+			SELECT id, version, content, $4, $5, $6 FROM old
+		)
+		UPDATE comments c SET content = $3, version = c.version + 1, updatedAt = $6
+		FROM old WHERE c.id = old.id
+		RETURNING c.id, c.postID, c.parentID, c.authorID, c.content, c.createdAt, c.version, c.replyCount, c.updatedAt`,
+		id, expectedVersion, content, editorID, reason, time.Now().UTC().String(),
+	).Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.AuthorID, &comment.Content, &comment.CreatedAt, &comment.Version, &comment.ReplyCount, &comment.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, s.versionError(ctx, "comment", "SELECT version FROM comments WHERE id=$1 AND deletedAt IS NULL", id)
+	}
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("comment updated",
+		slog.String("comment_id", comment.ID),
+		slog.String("editor_id", editorID),
+		slog.Int("version", int(comment.Version)),
+		logging.Content("content", comment.Content),
+	)
+
+	return &comment, nil
+}
+
+// DeletePost мягкое удаление поста; повторное удаление ничего не меняет
+func (s *PostgresRepository) DeletePost(ctx context.Context, id, deletedBy string) (*domain.Post, error) {
+	var v validator
+	v.required("deletedBy", deletedBy)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	tag, err := traced(s.conn).Exec(ctx,
+		"UPDATE posts SET deletedAt = NOW(), deletedBy = $2 WHERE id = $1 AND deletedAt IS NULL",
+		id, deletedBy,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete post: %w", err)
+	}
+
+	if tag.RowsAffected() > 0 {
+		logging.FromContext(ctx).Info("post deleted", slog.String("post_id", id), slog.String("deleted_by", deletedBy))
+	}
+
+	return s.getPost(ctx, id)
+}
+
+// DeleteComment мягкое удаление комментария; повторное удаление ничего не меняет
+func (s *PostgresRepository) DeleteComment(ctx context.Context, id, deletedBy string) (*domain.Comment, error) {
+	var v validator
+	v.required("deletedBy", deletedBy)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	changed, err := s.setCommentDeleted(ctx, id,
+		"UPDATE comments SET deletedAt = NOW(), deletedBy = $2 WHERE id = $1 AND deletedAt IS NULL RETURNING parentID",
+		-1, id, deletedBy,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	if changed {
+		logging.FromContext(ctx).Info("comment deleted", slog.String("comment_id", id), slog.String("deleted_by", deletedBy))
+	}
+
+	return s.getComment(ctx, id)
+}
+
+// RestorePost отмена мягкого удаления поста
+func (s *PostgresRepository) RestorePost(ctx context.Context, id string) (*domain.Post, error) {
+	tag, err := traced(s.conn).Exec(ctx, "UPDATE posts SET deletedAt = NULL, deletedBy = NULL WHERE id = $1 AND deletedAt IS NOT NULL", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore post: %w", err)
+	}
+
+	if tag.RowsAffected() > 0 {
+		logging.FromContext(ctx).Info("post restored", slog.String("post_id", id))
+	}
+
+	return s.getPost(ctx, id)
+}
+
+// RestoreComment отмена мягкого удаления комментария
+func (s *PostgresRepository) RestoreComment(ctx context.Context, id string) (*domain.Comment, error) {
+	changed, err := s.setCommentDeleted(ctx, id,
+		"UPDATE comments SET deletedAt = NULL, deletedBy = NULL WHERE id = $1 AND deletedAt IS NOT NULL RETURNING parentID",
+		1, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore comment: %w", err)
+	}
+
+	if changed {
+		logging.FromContext(ctx).Info("comment restored", slog.String("comment_id", id))
+	}
+
+	return s.getComment(ctx, id)
+}
+
+// setCommentDeleted выполняет удаление или восстановление комментария (query возвращает
+// parentID измененной строки) и в той же транзакции меняет счетчики на delta;
+// false, если комментарий уже в нужном состоянии или его нет
+func (s *PostgresRepository) setCommentDeleted(ctx context.Context, id, query string, delta int, args ...any) (bool, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := traced(tx)
+
+	var postID string
+	err = q.QueryRow(ctx, "SELECT postID FROM comments WHERE id = $1", id).Scan(&postID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := lockPostCounters(ctx, q, postID); err != nil {
+		return false, err
+	}
+
+	var parentID *string
+	err = q.QueryRow(ctx, query, args...).Scan(&parentID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := adjustPostgresCounters(ctx, q, postID, parentID, delta); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// lockPostCounters блокирует строку поста до конца транзакции, прежде чем менять его
+// комментарии: изменения комментариев поста выполняются по очереди, и пересчет времени
+// последнего комментария видит комментарии, добавленные параллельно. NO KEY UPDATE
+// не конфликтует с блокировкой внешнего ключа при вставке комментария.
+func lockPostCounters(ctx context.Context, q querier, postID string) error {
+	if _, err := q.Exec(ctx, "SELECT 1 FROM posts WHERE id = $1 FOR NO KEY UPDATE", postID); err != nil {
+		return fmt.Errorf("failed to lock post: %w", err)
+	}
+	return nil
+}
+
+// adjustPostgresCounters меняет на delta число комментариев поста и ответов родителя
+// и пересчитывает время последнего комментария поста. Вызывается в транзакции
+// после lockPostCounters и изменения комментария.
+func adjustPostgresCounters(ctx context.Context, q querier, postID string, parentID *string, delta int) error {
+	_, err := q.Exec(ctx, `
+		UPDATE posts SET commentCount = commentCount + $2,
+			lastCommentAt = (SELECT MAX(createdAt) FROM comments WHERE postID = $1 AND deletedAt IS NULL)
+		WHERE id = $1`,
+		postID, delta,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update post counters: %w", err)
+	}
+
+	if parentID != nil {
+		if _, err := q.Exec(ctx, "UPDATE comments SET replyCount = replyCount + $2 WHERE id = $1", *parentID, delta); err != nil {
+			return fmt.Errorf("failed to update reply count: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RepairCounters пересчитывает счетчики комментариев с нуля. Таблицы блокируются
+// от изменений на время пересчета, чтение не блокируется.
+func (s *PostgresRepository) RepairCounters(ctx context.Context) (int64, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := traced(tx)
+
+	if _, err := q.Exec(ctx, "LOCK TABLE posts, comments IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return 0, fmt.Errorf("failed to lock tables: %w", err)
+	}
+
+	var repaired int64
+	for _, query := range []string{`
+		WITH counts AS (
+			SELECT p.id, COUNT(c.id) AS n, MAX(c.createdAt) AS last
+			FROM posts p LEFT JOIN comments c ON c.postID = p.id AND c.deletedAt IS NULL
+			GROUP BY p.id
+		)
+		UPDATE posts SET commentCount = counts.n, lastCommentAt = counts.last
+		FROM counts
+		WHERE posts.id = counts.id AND (posts.commentCount <> counts.n OR posts.lastCommentAt IS DISTINCT FROM counts.last)`, `
+		WITH counts AS (
+			SELECT p.id, COUNT(r.id) AS n
+			FROM comments p LEFT JOIN comments r ON r.parentID = p.id AND r.deletedAt IS NULL
+			GROUP BY p.id
+		)
+		UPDATE comments SET replyCount = counts.n
+		FROM counts
+		WHERE comments.id = counts.id AND comments.replyCount <> counts.n`,
+	} {
+		tag, err := q.Exec(ctx, query)
+		if err != nil {
+			return 0, fmt.Errorf("failed to repair counters: %w", err)
+		}
+		repaired += tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	if repaired > 0 {
+		logging.FromContext(ctx).Info("counters repaired", slog.Int64("count", repaired))
+	}
+
+	return repaired, nil
+}
+
+// PurgeDeleted окончательное удаление постов и комментариев, удаленных раньше before.
+// Комментарии удаляются только без ответов; запрос повторяется, пока удаляются
+// целые ветки из удаленных комментариев.
+func (s *PostgresRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Комментарии удаленного поста удаляются каскадом
+	tag, err := traced(tx).Exec(ctx, "DELETE FROM posts WHERE deletedAt < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge posts: %w", err)
+	}
+	purged := tag.RowsAffected()
+
+	for {
+		tag, err := traced(tx).Exec(ctx, `
+			DELETE FROM comments c WHERE c.deletedAt < $1
+			AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parentID = c.id)`,
+			before,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge comments: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			break
+		}
+		purged += tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	if purged > 0 {
+		logging.FromContext(ctx).Info("deleted records purged", slog.Int64("count", purged))
+	}
+
+	return purged, nil
+}
+
+// EraseAuthor удаление или анонимизация всех данных автора одной транзакцией
+func (s *PostgresRepository) EraseAuthor(ctx context.Context, authorID string, mode domain.ErasureMode) (*domain.ErasureResult, error) {
+	if err := validateErasure(authorID, mode); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := traced(tx)
+	result := &domain.ErasureResult{AuthorID: authorID, Mode: mode}
+
+	if mode == domain.ErasureModeDeletePosts {
+		// Комментарии и история постов удаляются каскадом
+		tag, err := q.Exec(ctx, "DELETE FROM posts WHERE authorID = $1", authorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete posts: %w", err)
+		}
+		result.PostsDeleted = int32(tag.RowsAffected())
+	} else {
+		if _, err := q.Exec(ctx, "DELETE FROM post_revisions WHERE postID IN (SELECT id FROM posts WHERE authorID = $1)", authorID); err != nil {
+			return nil, fmt.Errorf("failed to delete post revisions: %w", err)
+		}
+		tag, err := q.Exec(ctx, "UPDATE posts SET authorID = $2, title = $2, content = $2 WHERE authorID = $1", authorID, domain.ErasedAuthor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to anonymize posts: %w", err)
+		}
+		result.PostsAnonymized = int32(tag.RowsAffected())
+	}
+
+	if _, err := q.Exec(ctx, "DELETE FROM comment_revisions WHERE commentID IN (SELECT id FROM comments WHERE authorID = $1)", authorID); err != nil {
+		return nil, fmt.Errorf("failed to delete comment revisions: %w", err)
+	}
+	tag, err := q.Exec(ctx, "UPDATE comments SET authorID = $2, content = $2 WHERE authorID = $1", authorID, domain.ErasedAuthor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to anonymize comments: %w", err)
+	}
+	result.CommentsAnonymized = int32(tag.RowsAffected())
+
+	// Автор мог редактировать и удалять чужие записи
+	for _, query := range []string{
+		"UPDATE post_revisions SET editorID = $2 WHERE editorID = $1",
+		"UPDATE comment_revisions SET editorID = $2 WHERE editorID = $1",
+		"UPDATE posts SET deletedBy = $2 WHERE deletedBy = $1",
+		"UPDATE comments SET deletedBy = $2 WHERE deletedBy = $1",
+	} {
+		if _, err := q.Exec(ctx, query, authorID, domain.ErasedAuthor); err != nil {
+			return nil, fmt.Errorf("failed to anonymize author references: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// ExportAuthor все посты и комментарии автора; читаются в одной транзакции,
+// чтобы выгрузка была согласованной
+func (s *PostgresRepository) ExportAuthor(ctx context.Context, authorID string) (*domain.AuthorArchive, error) {
+	var v validator
+	v.required("authorID", authorID)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	archive := &domain.AuthorArchive{
+		AuthorID:   authorID,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Posts:      []*domain.Post{},
+		Comments:   []*domain.Comment{},
+	}
+
+	rows, err := traced(tx).Query(ctx, "SELECT id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, commentCount, lastCommentAt, updatedAt FROM posts WHERE authorID = $1 ORDER BY createdAt", authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+	for rows.Next() {
+		var post domain.Post
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.CommentsDisabled, &post.Version, timestamp{&post.DeletedAt}, &post.DeletedBy, &post.CommentCount, &post.LastCommentAt, &post.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		archive.Posts = append(archive.Posts, &post)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+
+	rows, err = traced(tx).Query(ctx, "SELECT id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, replyCount, updatedAt FROM comments WHERE authorID = $1 ORDER BY createdAt", authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+	for rows.Next() {
+		var c domain.Comment
+		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.CreatedAt, &c.Version, timestamp{&c.DeletedAt}, &c.DeletedBy, &c.ReplyCount, &c.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		archive.Comments = append(archive.Comments, &c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return archive, nil
+}
+
+// getPost пост без комментариев, в том числе удаленный
+func (s *PostgresRepository) getPost(ctx context.Context, id string) (*domain.Post, error) {
+	return postByID(ctx, traced(s.conn), id)
+}
+
+// getComment комментарий, в том числе удаленный
+func (s *PostgresRepository) getComment(ctx context.Context, id string) (*domain.Comment, error) {
+	var c domain.Comment
+	err := traced(s.conn).QueryRow(ctx, "SELECT id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, replyCount, updatedAt FROM comments WHERE id=$1", id).Scan(
+		&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.CreatedAt, &c.Version, timestamp{&c.DeletedAt}, &c.DeletedBy, &c.ReplyCount, &c.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, domain.NotFound("comment", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve comment: %w", err)
+	}
+
+	return &c, nil
+}
+
+// timestamp сканирует TIMESTAMPTZ в строку RFC 3339, как она хранится в модели
+type timestamp struct {
+	dst **string
+}
+
+// Scan реализует sql.Scanner
+func (t timestamp) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t.dst = nil
+	case time.Time:
+		formatted := v.UTC().Format(time.RFC3339Nano)
+		*t.dst = &formatted
+	default:
+		return fmt.Errorf("cannot scan %T into timestamp", src)
+	}
+	return nil
+}
+
+// ExportRecords страница выгрузки; посты, комментарии и история читаются в одной транзакции
+func (s *PostgresRepository) ExportRecords(ctx context.Context, limit int, after *domain.RecordCursor) ([]*domain.Record, error) {
+	if after != nil {
+		if err := after.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := traced(tx)
+
+	query := "SELECT id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, commentCount, lastCommentAt, updatedAt FROM posts ORDER BY createdAt, id LIMIT $1"
+	args := []any{limit}
+	if after != nil {
+		query = `SELECT id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, commentCount, lastCommentAt, updatedAt FROM posts
+			WHERE (createdAt, id) > ($1, $2)
+			ORDER BY createdAt, id LIMIT $3`
+		args = []any{after.CreatedAt, after.ID, limit}
+	}
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+	var posts []*domain.Post
+	var ids []string
+	for rows.Next() {
+		var post domain.Post
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.CommentsDisabled, &post.Version, timestamp{&post.DeletedAt}, &post.DeletedBy, &post.CommentCount, &post.LastCommentAt, &post.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, &post)
+		ids = append(ids, post.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+
+	if len(posts) == 0 {
+		return []*domain.Record{}, nil
+	}
+
+	rows, err = q.Query(ctx, "SELECT id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, replyCount, updatedAt FROM comments WHERE postID = ANY($1) ORDER BY createdAt, id", ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+	comments := make(map[string][]*domain.Comment)
+	for rows.Next() {
+		var c domain.Comment
+		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.CreatedAt, &c.Version, timestamp{&c.DeletedAt}, &c.DeletedBy, &c.ReplyCount, &c.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments[c.PostID] = append(comments[c.PostID], &c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+
+	postRevisions, err := revisionsByID(ctx, q, `
+		SELECT postID, version, title, content, editorID, reason, editedAt FROM post_revisions
+		WHERE postID = ANY($1) ORDER BY postID, version`,
+		ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export post revisions: %w", err)
+	}
+
+	commentRevisions, err := revisionsByID(ctx, q, `
+		SELECT r.commentID, r.version, NULL, r.content, r.editorID, r.reason, r.editedAt
+		FROM comment_revisions r JOIN comments c ON c.id = r.commentID
+		WHERE c.postID = ANY($1) ORDER BY r.commentID, r.version`,
+		ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export comment revisions: %w", err)
+	}
+
+	return exportPage(posts, comments, postRevisions, commentRevisions), nil
+}
+
+// revisionsByID история изменений по id записи; query возвращает
+// id, version, title, content, editorID, reason, editedAt
+func revisionsByID(ctx context.Context, q querier, query string, args ...any) (map[string][]*domain.Revision, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make(map[string][]*domain.Revision)
+	for rows.Next() {
+		var id string
+		var r domain.Revision
+		if err := rows.Scan(&id, &r.Version, &r.Title, &r.Content, &r.EditorID, &r.Reason, &r.EditedAt); err != nil {
+			return nil, err
+		}
+		revisions[id] = append(revisions[id], &r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// ImportRecords сохраняет записи одной транзакцией; в конце пересчитываются счетчики
+// постов, получивших неудаленные комментарии
+func (s *PostgresRepository) ImportRecords(ctx context.Context, records []*domain.Record) (int64, error) {
+	imported := make([]*domain.Record, 0, len(records))
+	for _, r := range records {
+		record, err := importedRecord(r, rfc3339)
+		if err != nil {
+			return 0, err
+		}
+		imported = append(imported, record)
+	}
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := traced(tx)
+
+	var count int64
+	var touched []string
+	seen := make(map[string]bool)
+	for _, r := range imported {
+		inserted, err := importPostgresRecord(ctx, q, r)
+		if err != nil {
+			return 0, err
+		}
+		if !inserted {
+			continue
+		}
+		count++
+		if c := r.Comment; c != nil && !c.Deleted() && !seen[c.PostID] {
+			seen[c.PostID] = true
+			touched = append(touched, c.PostID)
+		}
+	}
+
+	if err := recountPostgresCounters(ctx, q, touched); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	if count > 0 {
+		logging.FromContext(ctx).Info("records imported", slog.Int64("count", count))
+	}
+
+	return count, nil
+}
+
+// importPostgresRecord вставляет запись вместе с историей; false, если запись с таким id уже есть
+func importPostgresRecord(ctx context.Context, q querier, r *domain.Record) (bool, error) {
+	if p := r.Post; p != nil {
+		tag, err := q.Exec(ctx, `
+			INSERT INTO posts (id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, updatedAt)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (id) DO NOTHING`,
+			p.ID, p.Title, p.Content, p.AuthorID, p.CreatedAt, p.CommentsDisabled, p.Version, p.DeletedAt, p.DeletedBy, p.UpdatedAt,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to insert post: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return false, nil
+		}
+
+		for _, rev := range r.Revisions {
+			_, err := q.Exec(ctx,
+				"INSERT INTO post_revisions (postID, version, title, content, editorID, reason, editedAt) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+				p.ID, rev.Version, rev.Title, rev.Content, rev.EditorID, rev.Reason, rev.EditedAt,
+			)
+			if err != nil {
+				return false, fmt.Errorf("failed to insert post revision: %w", err)
+			}
+		}
+		return true, nil
+	}
+
+	c := r.Comment
+	var exists, postExists, parentExists bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM comments WHERE id = $1),
+			EXISTS (SELECT 1 FROM posts WHERE id = $2),
+			$3::VARCHAR IS NULL OR EXISTS (SELECT 1 FROM comments WHERE id = $3 AND postID = $2)`,
+		c.ID, c.PostID, c.ParentID,
+	).Scan(&exists, &postExists, &parentExists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check comment: %w", err)
+	}
+	if exists {
+		return false, nil
+	}
+	if err := importedCommentErr(postExists, parentExists); err != nil {
+		return false, err
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO comments (id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, updatedAt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		c.ID, c.PostID, c.ParentID, c.AuthorID, c.Content, c.CreatedAt, c.Version, c.DeletedAt, c.DeletedBy, c.UpdatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	for _, rev := range r.Revisions {
+		_, err := q.Exec(ctx,
+			"INSERT INTO comment_revisions (commentID, version, content, editorID, reason, editedAt) VALUES ($1, $2, $3, $4, $5, $6)",
+			c.ID, rev.Version, rev.Content, rev.EditorID, rev.Reason, rev.EditedAt,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to insert comment revision: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// recountPostgresCounters пересчитывает счетчики постов postIDs и их комментариев.
+// Посты блокируются отдельным запросом, как в lockPostCounters: после ожидания блокировки
+// пересчет видит комментарии, добавленные параллельно.
+func recountPostgresCounters(ctx context.Context, q querier, postIDs []string) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	if _, err := q.Exec(ctx, "SELECT 1 FROM posts WHERE id = ANY($1) ORDER BY id FOR NO KEY UPDATE", postIDs); err != nil {
+		return fmt.Errorf("failed to lock posts: %w", err)
+	}
+
+	_, err := q.Exec(ctx, `
+		UPDATE posts SET
+			commentCount = (SELECT COUNT(*) FROM comments c WHERE c.postID = posts.id AND c.deletedAt IS NULL),
+			lastCommentAt = (SELECT MAX(createdAt) FROM comments c WHERE c.postID = posts.id AND c.deletedAt IS NULL)
+		WHERE id = ANY($1)`,
+		postIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update post counters: %w", err)
+	}
+
+	_, err = q.Exec(ctx, `
+		UPDATE comments SET replyCount = (SELECT COUNT(*) FROM comments r WHERE r.parentID = comments.id AND r.deletedAt IS NULL)
+		WHERE postID = ANY($1)`,
+		postIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update reply counts: %w", err)
+	}
+
+	return nil
+}
+
+// GetPostRevisions история изменений поста
+func (s *PostgresRepository) GetPostRevisions(ctx context.Context, postID string, limit int, after *int32) ([]*domain.Revision, error) {
+	rows, err := traced(s.conn).Query(ctx, `
+		SELECT version, title, content, editorID, reason, editedAt FROM post_revisions
+		WHERE postID = $1 AND ($2::INTEGER IS NULL OR version > $2)
+		ORDER BY version LIMIT $3`,
+		postID, after, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*domain.Revision{}
+	for rows.Next() {
+		var r domain.Revision
+		if err := rows.Scan(&r.Version, &r.Title, &r.Content, &r.EditorID, &r.Reason, &r.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan post revision: %w", err)
 		}
-		return nil, fmt.Errorf("failed to retrieve post: %v", err)
+		revisions = append(revisions, &r)
 	}
 
-	comments, err := s.GetCommentsByPostID(ctx, id)
+	return revisions, rows.Err()
+}
+
+// GetCommentRevisions история изменений комментария
+func (s *PostgresRepository) GetCommentRevisions(ctx context.Context, commentID string, limit int, after *int32) ([]*domain.Revision, error) {
+	rows, err := traced(s.conn).Query(ctx, `
+		SELECT version, content, editorID, reason, editedAt FROM comment_revisions
+		WHERE commentID = $1 AND ($2::INTEGER IS NULL OR version > $2)
+		ORDER BY version LIMIT $3`,
+		commentID, after, limit,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve comments: %v", err)
+		return nil, fmt.Errorf("failed to fetch comment revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*domain.Revision{}
+	for rows.Next() {
+		var r domain.Revision
+		if err := rows.Scan(&r.Version, &r.Content, &r.EditorID, &r.Reason, &r.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment revision: %w", err)
+		}
+		revisions = append(revisions, &r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// versionError объясняет, почему условный UPDATE не изменил строку:
+// сущности нет или ее версия уже другая
+func (s *PostgresRepository) versionError(ctx context.Context, entity, query, id string) error {
+	var current int32
+	err := traced(s.conn).QueryRow(ctx, query, id).Scan(&current)
+	if err == pgx.ErrNoRows {
+		return domain.NotFound(entity, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s version: %w", entity, err)
+	}
+
+	return domain.VersionConflict(entity, current)
+}
+
+func (s *PostgresRepository) GetPostByID(ctx context.Context, id string) (*domain.Post, error) {
+	var post *domain.Post
+	err := s.read(ctx, func(q querier) (err error) {
+		post, err = postByID(ctx, q, id)
+		return err
+	})
+	return post, err
+}
+
+// postByID пост без комментариев, в том числе удаленный
+func postByID(ctx context.Context, q querier, id string) (*domain.Post, error) {
+	var post domain.Post
+	err := q.QueryRow(ctx, "SELECT id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, commentCount, lastCommentAt, updatedAt FROM posts WHERE id=$1", id).Scan(
+		&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.CommentsDisabled, &post.Version, timestamp{&post.DeletedAt}, &post.DeletedBy, &post.CommentCount, &post.LastCommentAt, &post.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.NotFound("post", id)
+		}
+		return nil, fmt.Errorf("failed to retrieve post: %w", err)
 	}
-	post.Comments = comments
 
 	return &post, nil
 }
 
-func (s *PostgresRepository) GetCommentsByPostID(ctx context.Context, postID string) ([]*model.CommentWithReplies, error) {
-	var comments []*model.CommentWithReplies
-	rows, err := s.conn.Query(ctx, "SELECT id, postID, parentID, authorID, content, createdAt FROM comments WHERE postID=$1", postID)
+func (s *PostgresRepository) GetCommentsByPostID(ctx context.Context, postID string) ([]*domain.CommentWithReplies, error) {
+	var comments []*domain.CommentWithReplies
+	err := s.read(ctx, func(q querier) (err error) {
+		comments, err = commentsByPostID(ctx, q, postID)
+		return err
+	})
+	return comments, err
+}
+
+// commentsByPostID дерево всех комментариев поста
+func commentsByPostID(ctx context.Context, q querier, postID string) ([]*domain.CommentWithReplies, error) {
+	var comments []*domain.CommentWithReplies
+	rows, err := q.Query(ctx, "SELECT id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, replyCount, updatedAt FROM comments WHERE postID=$1 ORDER BY createdAt, id", postID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve comments: %v", err)
+		return nil, fmt.Errorf("failed to retrieve comments: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var comment model.CommentWithReplies
-		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.AuthorID, &comment.Content, &comment.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan comment: %v", err)
+		var comment domain.CommentWithReplies
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.AuthorID, &comment.Content, &comment.CreatedAt, &comment.Version, timestamp{&comment.DeletedAt}, &comment.DeletedBy, &comment.ReplyCount, &comment.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
 		}
 		comments = append(comments, &comment)
 	}
@@ -172,9 +1056,9 @@ func (s *PostgresRepository) GetCommentsByPostID(ctx context.Context, postID str
 	return buildCommentHierarchy(comments), nil
 }
 
-func buildCommentHierarchy(comments []*model.CommentWithReplies) []*model.CommentWithReplies {
-	m := make(map[string]*model.CommentWithReplies)
-	var roots []*model.CommentWithReplies
+func buildCommentHierarchy(comments []*domain.CommentWithReplies) []*domain.CommentWithReplies {
+	m := make(map[string]*domain.CommentWithReplies)
+	var roots []*domain.CommentWithReplies
 
 	for _, c := range comments {
 		m[c.ID] = c
@@ -193,23 +1077,29 @@ func buildCommentHierarchy(comments []*model.CommentWithReplies) []*model.Commen
 	return roots
 }
 
-// GetPosts Получение всех постов с комментариями
-func (s *PostgresRepository) GetPosts(ctx context.Context) ([]*model.Post, error) {
-	// Шаг 1: Получаем все посты
-	rows, err := s.conn.Query(ctx, "SELECT id, title, content, authorID, createdAt, commentsDisabled FROM posts")
+// GetPosts Получение всех постов в порядке создания
+func (s *PostgresRepository) GetPosts(ctx context.Context) ([]*domain.Post, error) {
+	var posts []*domain.Post
+	err := s.read(ctx, func(q querier) (err error) {
+		posts, err = getPosts(ctx, q)
+		return err
+	})
+	return posts, err
+}
+
+func getPosts(ctx context.Context, q querier) ([]*domain.Post, error) {
+	rows, err := q.Query(ctx, "SELECT id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, commentCount, lastCommentAt, updatedAt FROM posts ORDER BY createdAt, id")
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch posts: %v", err)
+		return nil, fmt.Errorf("failed to fetch posts: %w", err)
 	}
 	defer rows.Close()
 
-	var posts []*model.Post
-	postIDs := []string{} // Сохраняем ID постов для дальнейшего запроса комментариев
+	var posts []*domain.Post
 	for rows.Next() {
-		var post model.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.CommentsDisabled); err != nil {
+		var post domain.Post
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.CommentsDisabled, &post.Version, timestamp{&post.DeletedAt}, &post.DeletedBy, &post.CommentCount, &post.LastCommentAt, &post.UpdatedAt); err != nil {
 			return nil, err
 		}
-		postIDs = append(postIDs, post.ID)
 		posts = append(posts, &post)
 	}
 
@@ -217,65 +1107,80 @@ func (s *PostgresRepository) GetPosts(ctx context.Context) ([]*model.Post, error
 		return nil, err
 	}
 
-	// Шаг 2: Получаем все комментарии для этих постов
-	query := `SELECT id, postID, parentID, authorID, content, createdAt FROM comments WHERE postID = ANY($1)`
-	rows, err = s.conn.Query(ctx, query, postIDs)
+	return posts, nil
+}
+
+// GetLatestPosts последние неудаленные посты, от новых к старым; с authorID — только посты автора
+func (s *PostgresRepository) GetLatestPosts(ctx context.Context, authorID string, limit int) ([]*domain.Post, error) {
+	var posts []*domain.Post
+	err := s.read(ctx, func(q querier) (err error) {
+		posts, err = getLatestPosts(ctx, q, authorID, limit)
+		return err
+	})
+	return posts, err
+}
+
+func getLatestPosts(ctx context.Context, q querier, authorID string, limit int) ([]*domain.Post, error) {
+	query := `SELECT id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, commentCount, lastCommentAt, updatedAt FROM posts
+		WHERE deletedAt IS NULL ORDER BY createdAt DESC, id DESC LIMIT $1`
+	args := []any{limit}
+	if authorID != "" {
+		query = `SELECT id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, commentCount, lastCommentAt, updatedAt FROM posts
+			WHERE authorID = $1 AND deletedAt IS NULL ORDER BY createdAt DESC, id DESC LIMIT $2`
+		args = []any{authorID, limit}
+	}
+
+	rows, err := q.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch comments: %v", err)
+		return nil, fmt.Errorf("failed to fetch latest posts: %w", err)
 	}
 	defer rows.Close()
 
-	var comments []*model.CommentWithReplies
-	commentMap := make(map[string]*model.CommentWithReplies)
+	var posts []*domain.Post
 	for rows.Next() {
-		var c model.CommentWithReplies
-		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.CreatedAt); err != nil {
+		var post domain.Post
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.CommentsDisabled, &post.Version, timestamp{&post.DeletedAt}, &post.DeletedBy, &post.CommentCount, &post.LastCommentAt, &post.UpdatedAt); err != nil {
 			return nil, err
 		}
-		comments = append(comments, &c)
-		commentMap[c.ID] = &c
+		posts = append(posts, &post)
 	}
 
-	// Шаг 3: Строим иерархию комментариев
-	commentTree := buildCommentTree(comments)
-
-	// Привязываем комментарии к постам в ходе формирования ответа
-	for _, post := range posts {
-		var postComments []*model.CommentWithReplies
-		for _, c := range commentTree {
-			if c.PostID == post.ID {
-				postComments = append(postComments, c)
-			}
-		}
-		// Вставляем комментарии в ответ
-		post.Comments = postComments
-	}
+	return posts, rows.Err()
+}
 
-	return posts, nil
+func (s *PostgresRepository) GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*domain.CommentWithReplies, error) {
+	var comments []*domain.CommentWithReplies
+	err := s.read(ctx, func(q querier) (err error) {
+		comments, err = getCommentsForPost(ctx, q, postID, limit, cursor)
+		return err
+	})
+	return comments, err
 }
 
-func (s *PostgresRepository) GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*model.CommentWithReplies, error) {
+func getCommentsForPost(ctx context.Context, q querier, postID string, limit int, cursor *string) ([]*domain.CommentWithReplies, error) {
 	var query string
 	var args []interface{}
 
 	if cursor != nil && *cursor != "" {
-		query = `SELECT id, postID, parentID, authorID, content, createdAt FROM comments WHERE postID=$1 AND id > $2 ORDER BY id LIMIT $3`
+		query = `SELECT id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, replyCount, updatedAt FROM comments
+			WHERE postID=$1 AND (createdAt, id) > (SELECT createdAt, id FROM comments WHERE id = $2)
+			ORDER BY createdAt, id LIMIT $3`
 		args = append(args, postID, *cursor, limit)
 	} else {
-		query = `SELECT id, postID, parentID, authorID, content, createdAt FROM comments WHERE postID=$1 ORDER BY id LIMIT $2`
+		query = `SELECT id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, replyCount, updatedAt FROM comments WHERE postID=$1 ORDER BY createdAt, id LIMIT $2`
 		args = append(args, postID, limit)
 	}
 
-	rows, err := s.conn.Query(ctx, query, args...)
+	rows, err := q.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var comments []*model.CommentWithReplies
+	var comments []*domain.CommentWithReplies
 	for rows.Next() {
-		var c model.CommentWithReplies
-		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.CreatedAt); err != nil {
+		var c domain.CommentWithReplies
+		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.CreatedAt, &c.Version, timestamp{&c.DeletedAt}, &c.DeletedBy, &c.ReplyCount, &c.UpdatedAt); err != nil {
 			return nil, err
 		}
 		comments = append(comments, &c)
@@ -284,6 +1189,38 @@ func (s *PostgresRepository) GetCommentsForPost(ctx context.Context, postID stri
 	return buildCommentTree(comments), nil
 }
 
+// GetLatestComments последние неудаленные комментарии поста всех уровней, от новых к старым
+func (s *PostgresRepository) GetLatestComments(ctx context.Context, postID string, limit int) ([]*domain.Comment, error) {
+	var comments []*domain.Comment
+	err := s.read(ctx, func(q querier) (err error) {
+		comments, err = getLatestComments(ctx, q, postID, limit)
+		return err
+	})
+	return comments, err
+}
+
+func getLatestComments(ctx context.Context, q querier, postID string, limit int) ([]*domain.Comment, error) {
+	rows, err := q.Query(ctx, `SELECT id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, replyCount, updatedAt FROM comments
+		WHERE postID = $1 AND deletedAt IS NULL ORDER BY createdAt DESC, id DESC LIMIT $2`,
+		postID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []*domain.Comment
+	for rows.Next() {
+		var c domain.Comment
+		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.CreatedAt, &c.Version, timestamp{&c.DeletedAt}, &c.DeletedBy, &c.ReplyCount, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, &c)
+	}
+
+	return comments, rows.Err()
+}
+
 func generateID() string {
 	return uuid.New().String() // Используем UUID для генерации уникальных ID
 }
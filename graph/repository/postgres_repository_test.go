@@ -0,0 +1,133 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/graph/repository"
+	"github.com/YakovlevIgA/forozon/graph/storagetest"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	_ "github.com/lib/pq"
+)
+
+// TestPostgresRepository запускается, если задан TEST_POSTGRES_URL.
+// База очищается перед каждой проверкой, поэтому не указывайте рабочую базу.
+func TestPostgresRepository(t *testing.T) {
+	pool := postgresTestPool(t)
+
+	storagetest.Run(t, func(t *testing.T) domain.Storage {
+		truncate(t, pool)
+
+		repo, err := repository.NewPostgresRepository(pool)
+		if err != nil {
+			t.Fatalf("NewPostgresRepository: %v", err)
+		}
+		return repo
+	})
+}
+
+// TestPostgresRepositoryReplicas чтение через реплику (тот же сервер) должно проходить
+// общие проверки, а отказавшая реплика — подменяться основным сервером
+func TestPostgresRepositoryReplicas(t *testing.T) {
+	pool := postgresTestPool(t)
+
+	t.Run("conformance", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) domain.Storage {
+			truncate(t, pool)
+
+			repo, err := repository.NewPostgresRepository(pool, pool)
+			if err != nil {
+				t.Fatalf("NewPostgresRepository: %v", err)
+			}
+			return repo
+		})
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		truncate(t, pool)
+		ctx := context.Background()
+
+		// Закрытый пул отвечает ошибкой на любой запрос, как недоступная реплика
+		broken, err := pgxpool.Connect(ctx, os.Getenv("TEST_POSTGRES_URL"))
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		broken.Close()
+
+		repo, err := repository.NewPostgresRepository(pool, broken)
+		if err != nil {
+			t.Fatalf("NewPostgresRepository: %v", err)
+		}
+
+		post, err := repo.CreatePost(ctx, "title", "content", "author", false)
+		if err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			got, err := repo.GetPostByID(ctx, post.ID)
+			if err != nil {
+				t.Fatalf("GetPostByID #%d: %v", i, err)
+			}
+			if got.ID != post.ID {
+				t.Fatalf("GetPostByID #%d = %q, want %q", i, got.ID, post.ID)
+			}
+		}
+
+		if _, err := repo.GetPostByID(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("GetPostByID(missing) error = %v, want NOT_FOUND", err)
+		}
+	})
+}
+
+// postgresTestPool пул к TEST_POSTGRES_URL с примененными миграциями; пропускает тест без него
+func postgresTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		t.Fatalf("migrate driver: %v", err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://../../migrations", "postgres", driver)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	pool, err := pgxpool.Connect(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+// truncate очищает таблицы перед проверкой
+func truncate(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+
+	if _, err := pool.Exec(context.Background(), "TRUNCATE posts, comments, post_revisions, comment_revisions"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// replicaSet реплики для чтения с выбором по кругу среди доступных
+type replicaSet struct {
+	pools   []*pgxpool.Pool
+	healthy []atomic.Bool
+	next    atomic.Uint32
+}
+
+func newReplicaSet(pools []*pgxpool.Pool) *replicaSet {
+	r := &replicaSet{
+		pools:   pools,
+		healthy: make([]atomic.Bool, len(pools)),
+	}
+	for i := range r.healthy {
+		r.healthy[i].Store(true)
+	}
+	return r
+}
+
+// pick следующая доступная реплика; -1, если доступных нет
+func (r *replicaSet) pick() int {
+	n := len(r.pools)
+	if n == 0 {
+		return -1
+	}
+
+	start := int(r.next.Add(1) % uint32(n))
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+		if r.healthy[idx].Load() {
+			return idx
+		}
+	}
+	return -1
+}
+
+// setHealthy отмечает доступность реплики и пишет в лог смену состояния
+func (r *replicaSet) setHealthy(idx int, healthy bool, err error) {
+	if r.healthy[idx].Swap(healthy) == healthy {
+		return
+	}
+
+	if healthy {
+		slog.Info("postgres replica is back", slog.Int("replica", idx))
+	} else {
+		slog.Warn("postgres replica is unavailable, reading from primary", slog.Int("replica", idx), slog.Any("error", err))
+	}
+}
+
+// check проверяет все реплики
+func (r *replicaSet) check(ctx context.Context, timeout time.Duration) {
+	for i, pool := range r.pools {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := pool.Ping(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		r.setHealthy(i, err == nil, err)
+	}
+}
+
+// replicaFailed считается ли ошибка отказом реплики: доменные ошибки и отмена
+// запроса клиентом ничего не говорят о ее состоянии
+func replicaFailed(ctx context.Context, err error) bool {
+	var domainErr *domain.Error
+	return err != nil && ctx.Err() == nil && !errors.As(err, &domainErr)
+}
+
+// read выполняет чтение на реплике; при отказе реплика отмечается недоступной,
+// а чтение повторяется на основном сервере. Если клиент недавно изменял данные
+// или доступных реплик нет, чтение сразу идет на основной сервер.
+func (s *PostgresRepository) read(ctx context.Context, fn func(q querier) error) error {
+	if s.replicas == nil || domain.ReadYourWrites(ctx) {
+		return fn(traced(s.conn))
+	}
+
+	idx := s.replicas.pick()
+	if idx < 0 {
+		return fn(traced(s.conn))
+	}
+
+	err := fn(traced(s.replicas.pools[idx]))
+	if !replicaFailed(ctx, err) {
+		return err
+	}
+
+	s.replicas.setHealthy(idx, false, err)
+	return fn(traced(s.conn))
+}
+
+// WatchReplicas периодически проверяет реплики и возвращает восстановившиеся в работу;
+// завершается вместе с контекстом
+func (s *PostgresRepository) WatchReplicas(ctx context.Context, interval time.Duration) {
+	if s.replicas == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.replicas.check(ctx, interval)
+		}
+	}
+}
@@ -0,0 +1,1206 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/logging"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteRepository репозиторий на основе sqlite; семантика совпадает с PostgresRepository
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// OpenSQLite открывает файл sqlite с настройками, которые ожидает SQLiteRepository.
+// WAL позволяет читать во время записи; immediate-транзакции сразу берут блокировку
+// на запись, а busy_timeout ждет ее вместо ошибки SQLITE_BUSY.
+func OpenSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?_txlock=immediate" +
+		"&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// NewSQLiteRepository создает новый экземпляр SQLiteRepository.
+// Соединение открывается через OpenSQLite, схема — миграциями из migrations/sqlite.
+func NewSQLiteRepository(db *sql.DB) (*SQLiteRepository, error) {
+	return &SQLiteRepository{db: db}, nil
+}
+
+// Колонки постов и комментариев в порядке scanSQLitePost/scanSQLiteComment
+const (
+	sqlitePostColumns    = "id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, commentCount, lastCommentAt, updatedAt"
+	sqliteCommentColumns = "id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, replyCount, updatedAt"
+)
+
+// sqliteTimeLayout время фиксированной ширины: строки сравниваются так же, как время
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteTime время в формате хранения sqlite
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// CreatePost создание поста
+func (s *SQLiteRepository) CreatePost(ctx context.Context, title, content, authorID string, commentsDisabled bool) (*domain.Post, error) {
+	// Валидация
+
+	var v validator
+	v.required("title", title)
+	v.required("content", content)
+	v.required("authorID", authorID)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// Исполнение
+
+	post := &domain.Post{
+		ID:               generateID(),
+		Title:            title,
+		Content:          content,
+		AuthorID:         authorID,
+		CreatedAt:        time.Now().UTC().String(),
+		CommentsDisabled: commentsDisabled,
+		Version:          1,
+	}
+
+	_, err := tracedSQL(s.db).Exec(ctx,
+		"INSERT INTO posts (id, title, content, authorID, commentsDisabled, createdAt) VALUES (?, ?, ?, ?, ?, ?)",
+		post.ID, post.Title, post.Content, post.AuthorID, post.CommentsDisabled, post.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert post: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("post created",
+		slog.String("post_id", post.ID),
+		slog.String("author_id", post.AuthorID),
+		logging.Content("title", post.Title),
+	)
+
+	return post, nil
+}
+
+// AddComment добавление комментария; проверки и вставка выполняются в одной транзакции
+func (s *SQLiteRepository) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*domain.Comment, error) {
+	// Валидация
+
+	var v validator
+	v.required("authorID", authorID)
+	v.required("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+
+	var commentsDisabled bool
+	err = q.QueryRow(ctx, "SELECT commentsDisabled FROM posts WHERE id=? AND deletedAt IS NULL", postID).Scan(&commentsDisabled)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound("post", postID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post for comment: %w", err)
+	}
+
+	if commentsDisabled {
+		return nil, domain.CommentsDisabled(postID)
+	}
+
+	if parentID != nil {
+		var parentPostID string
+		err := q.QueryRow(ctx, "SELECT postID FROM comments WHERE id=? AND deletedAt IS NULL", *parentID).Scan(&parentPostID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get parent comment: %w", err)
+		}
+		if err == sql.ErrNoRows || parentPostID != postID {
+			v.add("parentID", "parent comment not found in this post")
+			return nil, v.err()
+		}
+	}
+
+	// Исполнение
+
+	comment := &domain.Comment{
+		ID:        generateID(),
+		PostID:    postID,
+		ParentID:  parentID,
+		AuthorID:  authorID,
+		Content:   content,
+		CreatedAt: time.Now().UTC().String(),
+		Version:   1,
+	}
+
+	_, err = q.Exec(ctx,
+		"INSERT INTO comments (id, postID, parentID, authorID, content, createdAt) VALUES (?, ?, ?, ?, ?, ?)",
+		comment.ID, comment.PostID, comment.ParentID, comment.AuthorID, comment.Content, comment.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	if err := adjustSQLiteCounters(ctx, q, comment.PostID, comment.ParentID, 1); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("comment added",
+		slog.String("comment_id", comment.ID),
+		slog.String("post_id", comment.PostID),
+		slog.String("author_id", comment.AuthorID),
+		logging.Content("content", comment.Content),
+	)
+
+	return comment, nil
+}
+
+// UpdatePost изменение поста; проверка версии, запись в post_revisions и UPDATE
+// выполняются в одной транзакции
+func (s *SQLiteRepository) UpdatePost(ctx context.Context, id string, expectedVersion int32, title, content *string, editorID string, reason *string) (*domain.Post, error) {
+	// Валидация
+
+	var v validator
+	v.required("editorID", editorID)
+	v.notEmpty("title", title)
+	v.notEmpty("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// Исполнение
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+
+	var current int32
+	err = q.QueryRow(ctx, "SELECT version FROM posts WHERE id=? AND deletedAt IS NULL", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound("post", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post version: %w", err)
+	}
+	if current != expectedVersion {
+		return nil, domain.VersionConflict("post", current)
+	}
+
+	editedAt := time.Now().UTC().String()
+	_, err = q.Exec(ctx, `
+		INSERT INTO post_revisions (postID, version, title, content, editorID, reason, editedAt)
+		SELECT id, version, title, content, ?, ?, ? FROM posts WHERE id = ?`,
+		editorID, reason, editedAt, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert post revision: %w", err)
+	}
+
+	post, err := scanSQLitePost(q.QueryRow(ctx, `
+		UPDATE posts SET title = COALESCE(?, title), content = COALESCE(?, content), version = version + 1, updatedAt = ?
+		WHERE id = ? RETURNING `+sqlitePostColumns,
+		title, content, editedAt, id,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("post updated",
+		slog.String("post_id", post.ID),
+		slog.String("editor_id", editorID),
+		slog.Int("version", int(post.Version)),
+	)
+
+	return post, nil
+}
+
+// UpdateComment изменение комментария; проверка версии, запись в comment_revisions и UPDATE
+// выполняются в одной транзакции
+func (s *SQLiteRepository) UpdateComment(ctx context.Context, id string, expectedVersion int32, content string, editorID string, reason *string) (*domain.Comment, error) {
+	// Валидация
+
+	var v validator
+	v.required("editorID", editorID)
+	v.required("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// Исполнение
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+
+	var current int32
+	err = q.QueryRow(ctx, "SELECT version FROM comments WHERE id=? AND deletedAt IS NULL", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound("comment", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment version: %w", err)
+	}
+	if current != expectedVersion {
+		return nil, domain.VersionConflict("comment", current)
+	}
+
+	editedAt := time.Now().UTC().String()
+	_, err = q.Exec(ctx, `
+		INSERT INTO comment_revisions (commentID, version, content, editorID, reason, editedAt)
+		SELECT id, version, content, ?, ?, ? FROM comments WHERE id = ?`,
+		editorID, reason, editedAt, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert comment revision: %w", err)
+	}
+
+	comment, err := scanSQLiteComment(q.QueryRow(ctx,
+		"UPDATE comments SET content = ?, version = version + 1, updatedAt = ? WHERE id = ? RETURNING "+sqliteCommentColumns,
+		content, editedAt, id,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("comment updated",
+		slog.String("comment_id", comment.ID),
+		slog.String("editor_id", editorID),
+		slog.Int("version", int(comment.Version)),
+		logging.Content("content", comment.Content),
+	)
+
+	return comment, nil
+}
+
+// DeletePost мягкое удаление поста; повторное удаление ничего не меняет
+func (s *SQLiteRepository) DeletePost(ctx context.Context, id, deletedBy string) (*domain.Post, error) {
+	var v validator
+	v.required("deletedBy", deletedBy)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	res, err := tracedSQL(s.db).Exec(ctx,
+		"UPDATE posts SET deletedAt = ?, deletedBy = ? WHERE id = ? AND deletedAt IS NULL",
+		sqliteTime(time.Now()), deletedBy, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete post: %w", err)
+	}
+
+	if n, _ := res.RowsAffected(); n > 0 {
+		logging.FromContext(ctx).Info("post deleted", slog.String("post_id", id), slog.String("deleted_by", deletedBy))
+	}
+
+	return s.getPost(ctx, id)
+}
+
+// DeleteComment мягкое удаление комментария; повторное удаление ничего не меняет
+func (s *SQLiteRepository) DeleteComment(ctx context.Context, id, deletedBy string) (*domain.Comment, error) {
+	var v validator
+	v.required("deletedBy", deletedBy)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	changed, err := s.setCommentDeleted(ctx, id,
+		"UPDATE comments SET deletedAt = ?, deletedBy = ? WHERE id = ? AND deletedAt IS NULL RETURNING postID, parentID",
+		-1, sqliteTime(time.Now()), deletedBy, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	if changed {
+		logging.FromContext(ctx).Info("comment deleted", slog.String("comment_id", id), slog.String("deleted_by", deletedBy))
+	}
+
+	return s.getComment(ctx, id)
+}
+
+// RestorePost отмена мягкого удаления поста
+func (s *SQLiteRepository) RestorePost(ctx context.Context, id string) (*domain.Post, error) {
+	res, err := tracedSQL(s.db).Exec(ctx, "UPDATE posts SET deletedAt = NULL, deletedBy = NULL WHERE id = ? AND deletedAt IS NOT NULL", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore post: %w", err)
+	}
+
+	if n, _ := res.RowsAffected(); n > 0 {
+		logging.FromContext(ctx).Info("post restored", slog.String("post_id", id))
+	}
+
+	return s.getPost(ctx, id)
+}
+
+// RestoreComment отмена мягкого удаления комментария
+func (s *SQLiteRepository) RestoreComment(ctx context.Context, id string) (*domain.Comment, error) {
+	changed, err := s.setCommentDeleted(ctx, id,
+		"UPDATE comments SET deletedAt = NULL, deletedBy = NULL WHERE id = ? AND deletedAt IS NOT NULL RETURNING postID, parentID",
+		1, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore comment: %w", err)
+	}
+
+	if changed {
+		logging.FromContext(ctx).Info("comment restored", slog.String("comment_id", id))
+	}
+
+	return s.getComment(ctx, id)
+}
+
+// setCommentDeleted выполняет удаление или восстановление комментария (query возвращает
+// postID и parentID измененной строки) и в той же транзакции меняет счетчики на delta;
+// false, если комментарий уже в нужном состоянии или его нет
+func (s *SQLiteRepository) setCommentDeleted(ctx context.Context, id, query string, delta int, args ...any) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+
+	var postID string
+	var parentID *string
+	err = q.QueryRow(ctx, query, args...).Scan(&postID, &parentID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := adjustSQLiteCounters(ctx, q, postID, parentID, delta); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// adjustSQLiteCounters меняет на delta число комментариев поста и ответов родителя
+// и пересчитывает время последнего комментария поста. Вызывается в транзакции
+// после изменения комментария.
+func adjustSQLiteCounters(ctx context.Context, q tracedSQLQuerier, postID string, parentID *string, delta int) error {
+	_, err := q.Exec(ctx, `
+		UPDATE posts SET commentCount = commentCount + ?,
+			lastCommentAt = (SELECT MAX(createdAt) FROM comments WHERE postID = ? AND deletedAt IS NULL)
+		WHERE id = ?`,
+		delta, postID, postID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update post counters: %w", err)
+	}
+
+	if parentID != nil {
+		if _, err := q.Exec(ctx, "UPDATE comments SET replyCount = replyCount + ? WHERE id = ?", delta, *parentID); err != nil {
+			return fmt.Errorf("failed to update reply count: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RepairCounters пересчитывает счетчики комментариев с нуля в одной транзакции
+func (s *SQLiteRepository) RepairCounters(ctx context.Context) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+
+	var repaired int64
+	for _, query := range []string{`
+		WITH counts AS (
+			SELECT p.id, COUNT(c.id) AS n, MAX(c.createdAt) AS last
+			FROM posts p LEFT JOIN comments c ON c.postID = p.id AND c.deletedAt IS NULL
+			GROUP BY p.id
+		)
+		UPDATE posts SET commentCount = counts.n, lastCommentAt = counts.last
+		FROM counts
+		WHERE posts.id = counts.id AND (posts.commentCount <> counts.n OR posts.lastCommentAt IS NOT counts.last)`, `
+		WITH counts AS (
+			SELECT p.id, COUNT(r.id) AS n
+			FROM comments p LEFT JOIN comments r ON r.parentID = p.id AND r.deletedAt IS NULL
+			GROUP BY p.id
+		)
+		UPDATE comments SET replyCount = counts.n
+		FROM counts
+		WHERE comments.id = counts.id AND comments.replyCount <> counts.n`,
+	} {
+		res, err := q.Exec(ctx, query)
+		if err != nil {
+			return 0, fmt.Errorf("failed to repair counters: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		repaired += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	if repaired > 0 {
+		logging.FromContext(ctx).Info("counters repaired", slog.Int64("count", repaired))
+	}
+
+	return repaired, nil
+}
+
+// PurgeDeleted окончательное удаление постов и комментариев, удаленных раньше before.
+// Комментарии удаляются только без ответов; запрос повторяется, пока удаляются
+// целые ветки из удаленных комментариев.
+func (s *SQLiteRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+	cutoff := sqliteTime(before)
+
+	// Комментарии удаленного поста удаляются каскадом
+	res, err := q.Exec(ctx, "DELETE FROM posts WHERE deletedAt < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge posts: %w", err)
+	}
+	purged, _ := res.RowsAffected()
+
+	for {
+		res, err := q.Exec(ctx, `
+			DELETE FROM comments WHERE deletedAt < ?
+			AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parentID = comments.id)`,
+			cutoff,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge comments: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		if n == 0 {
+			break
+		}
+		purged += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	if purged > 0 {
+		logging.FromContext(ctx).Info("deleted records purged", slog.Int64("count", purged))
+	}
+
+	return purged, nil
+}
+
+// EraseAuthor удаление или анонимизация всех данных автора одной транзакцией
+func (s *SQLiteRepository) EraseAuthor(ctx context.Context, authorID string, mode domain.ErasureMode) (*domain.ErasureResult, error) {
+	if err := validateErasure(authorID, mode); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+	result := &domain.ErasureResult{AuthorID: authorID, Mode: mode}
+
+	if mode == domain.ErasureModeDeletePosts {
+		// Комментарии и история постов удаляются каскадом
+		res, err := q.Exec(ctx, "DELETE FROM posts WHERE authorID = ?", authorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete posts: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		result.PostsDeleted = int32(n)
+	} else {
+		if _, err := q.Exec(ctx, "DELETE FROM post_revisions WHERE postID IN (SELECT id FROM posts WHERE authorID = ?)", authorID); err != nil {
+			return nil, fmt.Errorf("failed to delete post revisions: %w", err)
+		}
+		res, err := q.Exec(ctx, "UPDATE posts SET authorID = ?2, title = ?2, content = ?2 WHERE authorID = ?1", authorID, domain.ErasedAuthor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to anonymize posts: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		result.PostsAnonymized = int32(n)
+	}
+
+	if _, err := q.Exec(ctx, "DELETE FROM comment_revisions WHERE commentID IN (SELECT id FROM comments WHERE authorID = ?)", authorID); err != nil {
+		return nil, fmt.Errorf("failed to delete comment revisions: %w", err)
+	}
+	res, err := q.Exec(ctx, "UPDATE comments SET authorID = ?2, content = ?2 WHERE authorID = ?1", authorID, domain.ErasedAuthor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to anonymize comments: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	result.CommentsAnonymized = int32(n)
+
+	// Автор мог редактировать и удалять чужие записи
+	for _, query := range []string{
+		"UPDATE post_revisions SET editorID = ?2 WHERE editorID = ?1",
+		"UPDATE comment_revisions SET editorID = ?2 WHERE editorID = ?1",
+		"UPDATE posts SET deletedBy = ?2 WHERE deletedBy = ?1",
+		"UPDATE comments SET deletedBy = ?2 WHERE deletedBy = ?1",
+	} {
+		if _, err := q.Exec(ctx, query, authorID, domain.ErasedAuthor); err != nil {
+			return nil, fmt.Errorf("failed to anonymize author references: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// ExportAuthor все посты и комментарии автора; читаются в одной транзакции,
+// чтобы выгрузка была согласованной
+func (s *SQLiteRepository) ExportAuthor(ctx context.Context, authorID string) (*domain.AuthorArchive, error) {
+	var v validator
+	v.required("authorID", authorID)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+	archive := &domain.AuthorArchive{
+		AuthorID:   authorID,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Posts:      []*domain.Post{},
+		Comments:   []*domain.Comment{},
+	}
+
+	rows, err := q.Query(ctx, "SELECT "+sqlitePostColumns+" FROM posts WHERE authorID = ? ORDER BY createdAt", authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+	for rows.Next() {
+		post, err := scanSQLitePost(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		archive.Posts = append(archive.Posts, post)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+
+	rows, err = q.Query(ctx, "SELECT "+sqliteCommentColumns+" FROM comments WHERE authorID = ? ORDER BY createdAt", authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+	for rows.Next() {
+		comment, err := scanSQLiteComment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		archive.Comments = append(archive.Comments, comment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return archive, nil
+}
+
+// ExportRecords страница выгрузки; посты, комментарии и история читаются в одной транзакции
+func (s *SQLiteRepository) ExportRecords(ctx context.Context, limit int, after *domain.RecordCursor) ([]*domain.Record, error) {
+	if after != nil {
+		if err := after.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+
+	query := "SELECT " + sqlitePostColumns + " FROM posts ORDER BY createdAt, id LIMIT ?"
+	args := []any{limit}
+	if after != nil {
+		query = "SELECT " + sqlitePostColumns + ` FROM posts
+			WHERE (createdAt, id) > (?, ?)
+			ORDER BY createdAt, id LIMIT ?`
+		args = []any{after.CreatedAt, after.ID, limit}
+	}
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+	var posts []*domain.Post
+	var ids []string
+	for rows.Next() {
+		post, err := scanSQLitePost(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+		ids = append(ids, post.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+
+	if len(posts) == 0 {
+		return []*domain.Record{}, nil
+	}
+
+	// Список id передается одним параметром и разворачивается json_each
+	pageIDs, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(ctx, "SELECT "+sqliteCommentColumns+" FROM comments WHERE postID IN (SELECT value FROM json_each(?)) ORDER BY createdAt, id", string(pageIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+	comments := make(map[string][]*domain.Comment)
+	for rows.Next() {
+		comment, err := scanSQLiteComment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments[comment.PostID] = append(comments[comment.PostID], comment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+
+	postRevisions, err := sqliteRevisionsByID(ctx, q, `
+		SELECT postID, version, title, content, editorID, reason, editedAt FROM post_revisions
+		WHERE postID IN (SELECT value FROM json_each(?)) ORDER BY postID, version`,
+		string(pageIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export post revisions: %w", err)
+	}
+
+	commentRevisions, err := sqliteRevisionsByID(ctx, q, `
+		SELECT r.commentID, r.version, NULL, r.content, r.editorID, r.reason, r.editedAt
+		FROM comment_revisions r JOIN comments c ON c.id = r.commentID
+		WHERE c.postID IN (SELECT value FROM json_each(?)) ORDER BY r.commentID, r.version`,
+		string(pageIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export comment revisions: %w", err)
+	}
+
+	return exportPage(posts, comments, postRevisions, commentRevisions), nil
+}
+
+// sqliteRevisionsByID история изменений по id записи; query возвращает
+// id, version, title, content, editorID, reason, editedAt
+func sqliteRevisionsByID(ctx context.Context, q tracedSQLQuerier, query string, args ...any) (map[string][]*domain.Revision, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make(map[string][]*domain.Revision)
+	for rows.Next() {
+		var id string
+		var r domain.Revision
+		if err := rows.Scan(&id, &r.Version, &r.Title, &r.Content, &r.EditorID, &r.Reason, &r.EditedAt); err != nil {
+			return nil, err
+		}
+		revisions[id] = append(revisions[id], &r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// ImportRecords сохраняет записи одной транзакцией; в конце пересчитываются счетчики
+// постов, получивших неудаленные комментарии
+func (s *SQLiteRepository) ImportRecords(ctx context.Context, records []*domain.Record) (int64, error) {
+	imported := make([]*domain.Record, 0, len(records))
+	for _, r := range records {
+		record, err := importedRecord(r, sqliteTime)
+		if err != nil {
+			return 0, err
+		}
+		imported = append(imported, record)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := tracedSQL(tx)
+
+	var count int64
+	var touched []string
+	seen := make(map[string]bool)
+	for _, r := range imported {
+		inserted, err := importSQLiteRecord(ctx, q, r)
+		if err != nil {
+			return 0, err
+		}
+		if !inserted {
+			continue
+		}
+		count++
+		if c := r.Comment; c != nil && !c.Deleted() && !seen[c.PostID] {
+			seen[c.PostID] = true
+			touched = append(touched, c.PostID)
+		}
+	}
+
+	if err := recountSQLiteCounters(ctx, q, touched); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	if count > 0 {
+		logging.FromContext(ctx).Info("records imported", slog.Int64("count", count))
+	}
+
+	return count, nil
+}
+
+// importSQLiteRecord вставляет запись вместе с историей; false, если запись с таким id уже есть
+func importSQLiteRecord(ctx context.Context, q tracedSQLQuerier, r *domain.Record) (bool, error) {
+	if p := r.Post; p != nil {
+		res, err := q.Exec(ctx, `
+			INSERT INTO posts (id, title, content, authorID, createdAt, commentsDisabled, version, deletedAt, deletedBy, updatedAt)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+			p.ID, p.Title, p.Content, p.AuthorID, p.CreatedAt, p.CommentsDisabled, p.Version, p.DeletedAt, p.DeletedBy, p.UpdatedAt,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to insert post: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return false, nil
+		}
+
+		for _, rev := range r.Revisions {
+			_, err := q.Exec(ctx,
+				"INSERT INTO post_revisions (postID, version, title, content, editorID, reason, editedAt) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				p.ID, rev.Version, rev.Title, rev.Content, rev.EditorID, rev.Reason, rev.EditedAt,
+			)
+			if err != nil {
+				return false, fmt.Errorf("failed to insert post revision: %w", err)
+			}
+		}
+		return true, nil
+	}
+
+	c := r.Comment
+	var exists, postExists, parentExists bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM comments WHERE id = ?),
+			EXISTS (SELECT 1 FROM posts WHERE id = ?),
+			? IS NULL OR EXISTS (SELECT 1 FROM comments WHERE id = ? AND postID = ?)`,
+		c.ID, c.PostID, c.ParentID, c.ParentID, c.PostID,
+	).Scan(&exists, &postExists, &parentExists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check comment: %w", err)
+	}
+	if exists {
+		return false, nil
+	}
+	if err := importedCommentErr(postExists, parentExists); err != nil {
+		return false, err
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO comments (id, postID, parentID, authorID, content, createdAt, version, deletedAt, deletedBy, updatedAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.ID, c.PostID, c.ParentID, c.AuthorID, c.Content, c.CreatedAt, c.Version, c.DeletedAt, c.DeletedBy, c.UpdatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	for _, rev := range r.Revisions {
+		_, err := q.Exec(ctx,
+			"INSERT INTO comment_revisions (commentID, version, content, editorID, reason, editedAt) VALUES (?, ?, ?, ?, ?, ?)",
+			c.ID, rev.Version, rev.Content, rev.EditorID, rev.Reason, rev.EditedAt,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to insert comment revision: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// recountSQLiteCounters пересчитывает счетчики постов postIDs и их комментариев
+func recountSQLiteCounters(ctx context.Context, q tracedSQLQuerier, postIDs []string) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	ids, err := json.Marshal(postIDs)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(ctx, `
+		UPDATE posts SET
+			commentCount = (SELECT COUNT(*) FROM comments c WHERE c.postID = posts.id AND c.deletedAt IS NULL),
+			lastCommentAt = (SELECT MAX(createdAt) FROM comments c WHERE c.postID = posts.id AND c.deletedAt IS NULL)
+		WHERE id IN (SELECT value FROM json_each(?))`,
+		string(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update post counters: %w", err)
+	}
+
+	_, err = q.Exec(ctx, `
+		UPDATE comments SET replyCount = (SELECT COUNT(*) FROM comments r WHERE r.parentID = comments.id AND r.deletedAt IS NULL)
+		WHERE postID IN (SELECT value FROM json_each(?))`,
+		string(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update reply counts: %w", err)
+	}
+
+	return nil
+}
+
+// GetPostRevisions история изменений поста
+func (s *SQLiteRepository) GetPostRevisions(ctx context.Context, postID string, limit int, after *int32) ([]*domain.Revision, error) {
+	rows, err := tracedSQL(s.db).Query(ctx, `
+		SELECT version, title, content, editorID, reason, editedAt FROM post_revisions
+		WHERE postID = ? AND (? IS NULL OR version > ?)
+		ORDER BY version LIMIT ?`,
+		postID, after, after, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*domain.Revision{}
+	for rows.Next() {
+		var r domain.Revision
+		if err := rows.Scan(&r.Version, &r.Title, &r.Content, &r.EditorID, &r.Reason, &r.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan post revision: %w", err)
+		}
+		revisions = append(revisions, &r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetCommentRevisions история изменений комментария
+func (s *SQLiteRepository) GetCommentRevisions(ctx context.Context, commentID string, limit int, after *int32) ([]*domain.Revision, error) {
+	rows, err := tracedSQL(s.db).Query(ctx, `
+		SELECT version, content, editorID, reason, editedAt FROM comment_revisions
+		WHERE commentID = ? AND (? IS NULL OR version > ?)
+		ORDER BY version LIMIT ?`,
+		commentID, after, after, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comment revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*domain.Revision{}
+	for rows.Next() {
+		var r domain.Revision
+		if err := rows.Scan(&r.Version, &r.Content, &r.EditorID, &r.Reason, &r.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment revision: %w", err)
+		}
+		revisions = append(revisions, &r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetPostByID пост без комментариев
+func (s *SQLiteRepository) GetPostByID(ctx context.Context, id string) (*domain.Post, error) {
+	return s.getPost(ctx, id)
+}
+
+// GetCommentsByPostID дерево комментариев поста; ветки обходятся рекурсивным запросом
+// от корневых комментариев, поэтому ответы без родителя в дерево не попадают
+func (s *SQLiteRepository) GetCommentsByPostID(ctx context.Context, postID string) ([]*domain.CommentWithReplies, error) {
+	rows, err := tracedSQL(s.db).Query(ctx, `
+		WITH RECURSIVE thread AS (
+			SELECT `+sqliteCommentColumns+` FROM comments WHERE postID = ? AND parentID IS NULL
+			UNION ALL
+			SELECT c.id, c.postID, c.parentID, c.authorID, c.content, c.createdAt, c.version, c.deletedAt, c.deletedBy, c.replyCount, c.updatedAt
+			FROM comments c JOIN thread t ON c.parentID = t.id
+		)
+		SELECT `+sqliteCommentColumns+` FROM thread ORDER BY createdAt, id`,
+		postID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments, err := scanSQLiteCommentsWithReplies(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildCommentHierarchy(comments), nil
+}
+
+// GetPosts все посты в порядке создания
+func (s *SQLiteRepository) GetPosts(ctx context.Context) ([]*domain.Post, error) {
+	rows, err := tracedSQL(s.db).Query(ctx, "SELECT "+sqlitePostColumns+" FROM posts ORDER BY createdAt, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []*domain.Post
+	for rows.Next() {
+		post, err := scanSQLitePost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// GetLatestPosts последние неудаленные посты, от новых к старым; с authorID — только посты автора
+func (s *SQLiteRepository) GetLatestPosts(ctx context.Context, authorID string, limit int) ([]*domain.Post, error) {
+	query := "SELECT " + sqlitePostColumns + " FROM posts WHERE deletedAt IS NULL ORDER BY createdAt DESC, id DESC LIMIT ?"
+	args := []any{limit}
+	if authorID != "" {
+		query = "SELECT " + sqlitePostColumns + " FROM posts WHERE authorID = ? AND deletedAt IS NULL ORDER BY createdAt DESC, id DESC LIMIT ?"
+		args = []any{authorID, limit}
+	}
+
+	rows, err := tracedSQL(s.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []*domain.Post
+	for rows.Next() {
+		post, err := scanSQLitePost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// GetCommentsForPost страница комментариев поста в порядке создания; cursor — id последнего
+// комментария предыдущей страницы
+func (s *SQLiteRepository) GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*domain.CommentWithReplies, error) {
+	query := "SELECT " + sqliteCommentColumns + " FROM comments WHERE postID = ? ORDER BY createdAt, id LIMIT ?"
+	args := []any{postID, limit}
+	if cursor != nil && *cursor != "" {
+		query = "SELECT " + sqliteCommentColumns + ` FROM comments
+			WHERE postID = ? AND (createdAt, id) > (SELECT createdAt, id FROM comments WHERE id = ?)
+			ORDER BY createdAt, id LIMIT ?`
+		args = []any{postID, *cursor, limit}
+	}
+
+	rows, err := tracedSQL(s.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments, err := scanSQLiteCommentsWithReplies(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildCommentTree(comments), nil
+}
+
+// GetLatestComments последние неудаленные комментарии поста всех уровней, от новых к старым
+func (s *SQLiteRepository) GetLatestComments(ctx context.Context, postID string, limit int) ([]*domain.Comment, error) {
+	rows, err := tracedSQL(s.db).Query(ctx,
+		"SELECT "+sqliteCommentColumns+" FROM comments WHERE postID = ? AND deletedAt IS NULL ORDER BY createdAt DESC, id DESC LIMIT ?",
+		postID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []*domain.Comment
+	for rows.Next() {
+		comment, err := scanSQLiteComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// getPost пост без комментариев, в том числе удаленный
+func (s *SQLiteRepository) getPost(ctx context.Context, id string) (*domain.Post, error) {
+	post, err := scanSQLitePost(tracedSQL(s.db).QueryRow(ctx, "SELECT "+sqlitePostColumns+" FROM posts WHERE id=?", id))
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound("post", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve post: %w", err)
+	}
+
+	return post, nil
+}
+
+// getComment комментарий, в том числе удаленный
+func (s *SQLiteRepository) getComment(ctx context.Context, id string) (*domain.Comment, error) {
+	comment, err := scanSQLiteComment(tracedSQL(s.db).QueryRow(ctx, "SELECT "+sqliteCommentColumns+" FROM comments WHERE id=?", id))
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound("comment", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// sqliteScanner строка результата: tracedSQLRow или tracedSQLRows
+type sqliteScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSQLitePost(row sqliteScanner) (*domain.Post, error) {
+	var p domain.Post
+	err := row.Scan(&p.ID, &p.Title, &p.Content, &p.AuthorID, &p.CreatedAt, &p.CommentsDisabled, &p.Version, textTimestamp{&p.DeletedAt}, &p.DeletedBy, &p.CommentCount, &p.LastCommentAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func scanSQLiteComment(row sqliteScanner) (*domain.Comment, error) {
+	var c domain.Comment
+	err := row.Scan(&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.CreatedAt, &c.Version, textTimestamp{&c.DeletedAt}, &c.DeletedBy, &c.ReplyCount, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func scanSQLiteCommentsWithReplies(rows *tracedSQLRows) ([]*domain.CommentWithReplies, error) {
+	var comments []*domain.CommentWithReplies
+	for rows.Next() {
+		var c domain.CommentWithReplies
+		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.CreatedAt, &c.Version, textTimestamp{&c.DeletedAt}, &c.DeletedBy, &c.ReplyCount, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, &c)
+	}
+
+	return comments, rows.Err()
+}
+
+// textTimestamp сканирует время в формате sqliteTimeLayout в строку RFC 3339, как она хранится в модели
+type textTimestamp struct {
+	dst **string
+}
+
+// Scan реализует sql.Scanner
+func (t textTimestamp) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t.dst = nil
+	case string:
+		parsed, err := time.Parse(sqliteTimeLayout, v)
+		if err != nil {
+			return err
+		}
+		formatted := parsed.Format(time.RFC3339Nano)
+		*t.dst = &formatted
+	default:
+		return fmt.Errorf("cannot scan %T into timestamp", src)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/graph/repository"
+	"github.com/YakovlevIgA/forozon/graph/storagetest"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) domain.Storage {
+		return newSQLiteRepository(t, newSQLiteDB(t))
+	})
+}
+
+func TestSQLiteRepairCounters(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteDB(t)
+	repo := newSQLiteRepository(t, db)
+
+	post, err := repo.CreatePost(ctx, "title", "content", "author", false)
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	root, err := repo.AddComment(ctx, post.ID, nil, "author", "root")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	reply, err := repo.AddComment(ctx, post.ID, &root.ID, "author", "reply")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	// Счетчики, испорченные в обход репозитория, пересчитываются с нуля
+	if _, err := db.ExecContext(ctx, "UPDATE posts SET commentCount = 7, lastCommentAt = NULL"); err != nil {
+		t.Fatalf("corrupt posts: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE comments SET replyCount = 3"); err != nil {
+		t.Fatalf("corrupt comments: %v", err)
+	}
+
+	repaired, err := repo.RepairCounters(ctx)
+	if err != nil {
+		t.Fatalf("RepairCounters: %v", err)
+	}
+	if repaired != 3 {
+		t.Errorf("RepairCounters repaired %d records, want 3", repaired)
+	}
+
+	got, err := repo.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if got.CommentCount != 2 || got.LastCommentAt == nil || *got.LastCommentAt != reply.CreatedAt {
+		t.Errorf("post counters after repair: commentCount = %d, lastCommentAt = %v", got.CommentCount, got.LastCommentAt)
+	}
+	comments, err := repo.GetCommentsByPostID(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("GetCommentsByPostID: %v", err)
+	}
+	if comments[0].ReplyCount != 1 || comments[0].Replies[0].ReplyCount != 0 {
+		t.Errorf("reply counts after repair: %d, %d", comments[0].ReplyCount, comments[0].Replies[0].ReplyCount)
+	}
+
+	repaired, err = repo.RepairCounters(ctx)
+	if err != nil {
+		t.Fatalf("RepairCounters: %v", err)
+	}
+	if repaired != 0 {
+		t.Errorf("second RepairCounters repaired %d records, want 0", repaired)
+	}
+}
+
+// newSQLiteDB база sqlite во временном каталоге с примененными миграциями
+func newSQLiteDB(t *testing.T) *sql.DB {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := repository.OpenSQLite(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		t.Fatalf("migrate driver: %v", err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://../../migrations/sqlite", "sqlite", driver)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	return db
+}
+
+func newSQLiteRepository(t *testing.T, db *sql.DB) *repository.SQLiteRepository {
+	t.Helper()
+
+	repo, err := repository.NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %v", err)
+	}
+	return repo
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/YakovlevIgA/forozon/graph/repository")
+
+// querier общий интерфейс пула соединений и транзакции
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// traced оборачивает пул или транзакцию, открывая спан на каждый SQL-запрос
+func traced(q querier) querier {
+	return tracedQuerier{q}
+}
+
+type tracedQuerier struct {
+	q querier
+}
+
+func (t tracedQuerier) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startSQLSpan(ctx, semconv.DBSystemPostgreSQL, sql)
+	tag, err := t.q.Exec(ctx, sql, args...)
+	endSQLSpan(span, err)
+	return tag, err
+}
+
+func (t tracedQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startSQLSpan(ctx, semconv.DBSystemPostgreSQL, sql)
+	rows, err := t.q.Query(ctx, sql, args...)
+	if err != nil {
+		endSQLSpan(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (t tracedQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, span := startSQLSpan(ctx, semconv.DBSystemPostgreSQL, sql)
+	return tracedRow{row: t.q.QueryRow(ctx, sql, args...), span: span}
+}
+
+// tracedRows закрывает спан вместе с курсором
+type tracedRows struct {
+	pgx.Rows
+	span trace.Span
+}
+
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	endSQLSpan(r.span, r.Rows.Err())
+}
+
+// tracedRow закрывает спан после Scan
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+func (r tracedRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	if err == pgx.ErrNoRows {
+		endSQLSpan(r.span, nil)
+	} else {
+		endSQLSpan(r.span, err)
+	}
+	return err
+}
+
+// sqlQuerier общий интерфейс *sql.DB и *sql.Tx
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// tracedSQL оборачивает database/sql соединение или транзакцию (sqlite), открывая спан на каждый запрос
+func tracedSQL(q sqlQuerier) tracedSQLQuerier {
+	return tracedSQLQuerier{q}
+}
+
+type tracedSQLQuerier struct {
+	q sqlQuerier
+}
+
+func (t tracedSQLQuerier) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startSQLSpan(ctx, semconv.DBSystemSqlite, query)
+	res, err := t.q.ExecContext(ctx, query, args...)
+	endSQLSpan(span, err)
+	return res, err
+}
+
+func (t tracedSQLQuerier) Query(ctx context.Context, query string, args ...any) (*tracedSQLRows, error) {
+	ctx, span := startSQLSpan(ctx, semconv.DBSystemSqlite, query)
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		endSQLSpan(span, err)
+		return nil, err
+	}
+	return &tracedSQLRows{Rows: rows, span: span}, nil
+}
+
+func (t tracedSQLQuerier) QueryRow(ctx context.Context, query string, args ...any) tracedSQLRow {
+	ctx, span := startSQLSpan(ctx, semconv.DBSystemSqlite, query)
+	return tracedSQLRow{row: t.q.QueryRowContext(ctx, query, args...), span: span}
+}
+
+// tracedSQLRows закрывает спан вместе с курсором
+type tracedSQLRows struct {
+	*sql.Rows
+	span trace.Span
+}
+
+func (r *tracedSQLRows) Close() error {
+	err := r.Rows.Close()
+	endSQLSpan(r.span, r.Rows.Err())
+	return err
+}
+
+// tracedSQLRow закрывает спан после Scan
+type tracedSQLRow struct {
+	row  *sql.Row
+	span trace.Span
+}
+
+func (r tracedSQLRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if err == sql.ErrNoRows {
+		endSQLSpan(r.span, nil)
+	} else {
+		endSQLSpan(r.span, err)
+	}
+	return err
+}
+
+// startSQLSpan открывает спан запроса; имя спана — SQL-команда и таблица
+func startSQLSpan(ctx context.Context, system attribute.KeyValue, sql string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, sqlSpanName(sql),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			system,
+			semconv.DBQueryText(sql),
+		),
+	)
+}
+
+// endSQLSpan закрывает спан, отмечая ошибку
+func endSQLSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// sqlSpanName "SELECT comments" из текста запроса
+func sqlSpanName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "sql"
+	}
+
+	name := strings.ToUpper(fields[0])
+	for i, f := range fields[:len(fields)-1] {
+		switch strings.ToUpper(f) {
+		case "FROM", "INTO", "UPDATE":
+			return name + " " + strings.Trim(fields[i+1], "(),;")
+		}
+	}
+
+	return name
+}
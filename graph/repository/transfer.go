@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+// exportPage записи страницы выгрузки: за каждым постом его комментарии (в порядке создания,
+// родитель раньше ответов) с историей изменений
+func exportPage(posts []*domain.Post, comments map[string][]*domain.Comment, postRevisions, commentRevisions map[string][]*domain.Revision) []*domain.Record {
+	records := []*domain.Record{}
+	for _, post := range posts {
+		records = append(records, &domain.Record{Type: domain.RecordPost, Post: post, Revisions: postRevisions[post.ID]})
+		for _, c := range parentsFirst(comments[post.ID]) {
+			records = append(records, &domain.Record{Type: domain.RecordComment, Comment: c, Revisions: commentRevisions[c.ID]})
+		}
+	}
+	return records
+}
+
+// parentsFirst переставляет ответы, созданные раньше родителя (так бывает после загрузки
+// чужих данных), сразу за родителем; остальной порядок сохраняется
+func parentsFirst(comments []*domain.Comment) []*domain.Comment {
+	present := make(map[string]bool, len(comments))
+	for _, c := range comments {
+		present[c.ID] = true
+	}
+
+	ordered := make([]*domain.Comment, 0, len(comments))
+	emitted := make(map[string]bool, len(comments))
+	waiting := make(map[string][]*domain.Comment)
+
+	var emit func(c *domain.Comment)
+	emit = func(c *domain.Comment) {
+		ordered = append(ordered, c)
+		emitted[c.ID] = true
+		replies := waiting[c.ID]
+		delete(waiting, c.ID)
+		for _, r := range replies {
+			emit(r)
+		}
+	}
+
+	for _, c := range comments {
+		if c.ParentID == nil || emitted[*c.ParentID] || !present[*c.ParentID] {
+			emit(c)
+			continue
+		}
+		waiting[*c.ParentID] = append(waiting[*c.ParentID], c)
+	}
+
+	return ordered
+}
+
+// timeString время в формате, в котором хранилища хранят время создания и правки
+func timeString(t time.Time) string {
+	return t.UTC().String()
+}
+
+// rfc3339 время удаления в формате модели (in-memory и postgres)
+func rfc3339(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// importedRecord копия записи для сохранения: время создания и правки приведено к timeString,
+// время удаления — к формату хранилища deletedAt; счетчики обнулены, их пересчитывает хранилище
+func importedRecord(r *domain.Record, deletedAt func(time.Time) string) (*domain.Record, error) {
+	var v validator
+	imported := &domain.Record{Type: r.Type}
+
+	switch {
+	case r.Type == domain.RecordPost && r.Post != nil:
+		post := *r.Post
+		post.CommentCount, post.LastCommentAt = 0, nil
+		v.required("post.id", post.ID)
+		post.CreatedAt = importTime(&v, "post.createdAt", post.CreatedAt, timeString)
+		post.DeletedAt = importOptionalTime(&v, "post.deletedAt", post.DeletedAt, deletedAt)
+		post.UpdatedAt = importOptionalTime(&v, "post.updatedAt", post.UpdatedAt, timeString)
+		imported.Post = &post
+	case r.Type == domain.RecordComment && r.Comment != nil:
+		comment := *r.Comment
+		comment.ReplyCount = 0
+		v.required("comment.id", comment.ID)
+		v.required("comment.postID", comment.PostID)
+		comment.CreatedAt = importTime(&v, "comment.createdAt", comment.CreatedAt, timeString)
+		comment.DeletedAt = importOptionalTime(&v, "comment.deletedAt", comment.DeletedAt, deletedAt)
+		comment.UpdatedAt = importOptionalTime(&v, "comment.updatedAt", comment.UpdatedAt, timeString)
+		imported.Comment = &comment
+	default:
+		v.add("type", "record must be a post or a comment")
+		return nil, v.err()
+	}
+
+	for _, revision := range r.Revisions {
+		rev := *revision
+		rev.EditedAt = importTime(&v, "revisions.editedAt", rev.EditedAt, timeString)
+		imported.Revisions = append(imported.Revisions, &rev)
+	}
+
+	// В выгрузках без updatedAt время правки берется из последней версии истории
+	if n := len(imported.Revisions); n > 0 {
+		editedAt := imported.Revisions[n-1].EditedAt
+		if imported.Post != nil && imported.Post.UpdatedAt == nil {
+			imported.Post.UpdatedAt = &editedAt
+		}
+		if imported.Comment != nil && imported.Comment.UpdatedAt == nil {
+			imported.Comment.UpdatedAt = &editedAt
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
+
+// importTime приводит время из выгрузки к формату format
+func importTime(v *validator, field, value string, format func(time.Time) string) string {
+	t, err := domain.ParseTimestamp(value)
+	if err != nil {
+		v.add(field, err.Error())
+		return value
+	}
+	return format(t)
+}
+
+// importOptionalTime приводит необязательное время к формату format
+func importOptionalTime(v *validator, field string, value *string, format func(time.Time) string) *string {
+	if value == nil {
+		return nil
+	}
+	formatted := importTime(v, field, *value, format)
+	return &formatted
+}
+
+// importedCommentErr ошибка валидации загружаемого комментария, если нет его поста
+// или родителя в этом посте
+func importedCommentErr(postExists, parentExists bool) error {
+	var v validator
+	if !postExists {
+		v.add("comment.postID", "post not found")
+	}
+	if !parentExists {
+		v.add("comment.parentID", "parent comment not found in this post")
+	}
+	return v.err()
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+// validator собирает ошибки валидации по всем полям
+type validator struct {
+	fields []domain.FieldError
+}
+
+// required проверяет, что поле заполнено
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.fields = append(v.fields, domain.FieldError{Field: field, Message: field + " is required"})
+	}
+}
+
+// notEmpty проверяет, что поле, если передано, не пустое
+func (v *validator) notEmpty(field string, value *string) {
+	if value != nil {
+		v.required(field, *value)
+	}
+}
+
+// add добавляет ошибку поля
+func (v *validator) add(field, message string) {
+	v.fields = append(v.fields, domain.FieldError{Field: field, Message: message})
+}
+
+// err ошибка VALIDATION_FAILED или nil
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return domain.ValidationFailed(v.fields...)
+}
+
+// validateErasure проверяет параметры удаления данных автора
+func validateErasure(authorID string, mode domain.ErasureMode) error {
+	var v validator
+	v.required("authorID", authorID)
+	if authorID == domain.ErasedAuthor {
+		v.add("authorID", "author is already erased")
+	}
+	if mode != domain.ErasureModeAnonymize && mode != domain.ErasureModeDeletePosts {
+		v.add("mode", "unknown erasure mode")
+	}
+	return v.err()
+}
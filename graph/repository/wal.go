@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy когда сбрасывать журнал на диск
+type FsyncPolicy string
+
+const (
+	// FsyncAlways fsync после каждой записи: изменение не теряется после ответа клиенту
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncInterval fsync раз в интервал: при сбое ОС теряются изменения за последний интервал
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncNever сброс на диск остается на усмотрение ОС
+	FsyncNever FsyncPolicy = "never"
+)
+
+// walHeaderSize заголовок записи: длина и crc32 содержимого
+const walHeaderSize = 8
+
+// maxWALRecord ограничение на размер записи; большее значение в заголовке
+// считается повреждением, а не поводом выделить гигабайты памяти
+const maxWALRecord = 64 << 20
+
+// wal журнал упреждающей записи: последовательность записей
+// [длина uint32][crc32 uint32][данные]
+type wal struct {
+	mu     sync.Mutex
+	file   *os.File
+	offset int64
+	policy FsyncPolicy
+	dirty  bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// openWAL открывает журнал, вызывает replay для каждой целой записи и обрезает
+// оборванный хвост, оставшийся после сбоя посреди записи
+func openWAL(path string, policy FsyncPolicy, interval time.Duration, replay func([]byte) error) (w *wal, torn int64, err error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+		}
+	}()
+
+	offset, err := readWAL(file, replay)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if torn = info.Size() - offset; torn > 0 {
+		if err := file.Truncate(offset); err != nil {
+			return nil, 0, fmt.Errorf("failed to truncate torn tail: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	w = &wal{file: file, offset: offset, policy: policy}
+
+	if policy == FsyncInterval {
+		w.stop = make(chan struct{})
+		w.done = make(chan struct{})
+		go w.syncLoop(interval)
+	}
+
+	return w, torn, nil
+}
+
+// readWAL читает записи до конца файла или первой поврежденной записи,
+// возвращает смещение конца последней целой записи
+func readWAL(file *os.File, replay func([]byte) error) (int64, error) {
+	r := bufio.NewReader(file)
+	header := make([]byte, walHeaderSize)
+	var offset int64
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			// Конец файла или оборванный заголовок
+			return offset, nil
+		}
+
+		size := binary.LittleEndian.Uint32(header[0:4])
+		sum := binary.LittleEndian.Uint32(header[4:8])
+		if size > maxWALRecord {
+			return offset, nil
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return offset, nil
+		}
+		if crc32.ChecksumIEEE(data) != sum {
+			return offset, nil
+		}
+
+		if err := replay(data); err != nil {
+			return 0, fmt.Errorf("failed to replay record at offset %d: %w", offset, err)
+		}
+
+		offset += walHeaderSize + int64(size)
+	}
+}
+
+// append дописывает запись; при ошибке журнал возвращается к предыдущей границе записи
+func (w *wal) append(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	record := make([]byte, walHeaderSize+len(data))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(data))
+	copy(record[walHeaderSize:], data)
+
+	if _, err := w.file.Write(record); err != nil {
+		w.rollback()
+		return err
+	}
+
+	if w.policy == FsyncAlways {
+		if err := w.file.Sync(); err != nil {
+			w.rollback()
+			return err
+		}
+	} else {
+		w.dirty = true
+	}
+
+	w.offset += int64(len(record))
+	return nil
+}
+
+// rollback отрезает частично записанную запись
+func (w *wal) rollback() {
+	_ = w.file.Truncate(w.offset)
+	_, _ = w.file.Seek(w.offset, io.SeekStart)
+}
+
+// reset очищает журнал после того, как его содержимое попало в снимок
+func (w *wal) reset() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.offset = 0
+	w.dirty = false
+
+	return w.file.Sync()
+}
+
+// sync сбрасывает несохраненные записи на диск
+func (w *wal) sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.file.Sync()
+}
+
+// syncLoop периодический fsync для FsyncInterval
+func (w *wal) syncLoop(interval time.Duration) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			_ = w.sync()
+		}
+	}
+}
+
+// close останавливает фоновый fsync, сбрасывает журнал и закрывает файл
+func (w *wal) close() error {
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+	}
+
+	return errors.Join(w.sync(), w.file.Close())
+}
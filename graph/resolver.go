@@ -3,35 +3,47 @@ package graph
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 
+	"github.com/YakovlevIgA/forozon/auth"
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/graph/idempotency"
 	"github.com/YakovlevIgA/forozon/graph/model"
+	"github.com/YakovlevIgA/forozon/logging"
+	"github.com/YakovlevIgA/forozon/transfer"
 )
 
-// Storage интерфейс хранилища
-type Storage interface {
-	GetPosts(ctx context.Context) ([]*model.Post, error)
-	GetPostByID(ctx context.Context, id string) (*model.Post, error)
-	CreatePost(ctx context.Context, title, content, authorID string, commentsDisabled bool) (*model.Post, error)
-	AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*model.Comment, error)
-	GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*model.CommentWithReplies, error)
-}
-
 // Resolver сервис для работы с постами и комментариями
 type Resolver struct {
-	Storage Storage
+	Storage domain.Storage
+	// ErasureMode способ удаления данных автора, если в authorErase он не указан
+	ErasureMode domain.ErasureMode
+	// Idempotency защита мутаций от повторного выполнения; nil отключает ее
+	Idempotency *idempotency.Guard
+
+	broker *commentBroker
 }
 
 // NewResolver создает новый экземпляр Resolver
-func NewResolver(storage Storage) *Resolver {
-	return &Resolver{storage}
+func NewResolver(storage domain.Storage) *Resolver {
+	return &Resolver{
+		Storage:     storage,
+		ErasureMode: domain.ErasureModeAnonymize,
+		broker:      newCommentBroker(),
+	}
 }
 
 // CreatePost создание поста
 func (r *mutationResolver) CreatePost(ctx context.Context, title string, content string, authorID string, commentsDisabled bool) (*model.Post, error) {
-	post, err := r.Storage.CreatePost(ctx, title, content, authorID, commentsDisabled)
+	post, err := r.createPost(ctx, model.CreatePostInput{
+		Title:            title,
+		Content:          content,
+		AuthorID:         authorID,
+		CommentsDisabled: commentsDisabled,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create post: %v", err)
+		return nil, fmt.Errorf("failed to create post: %w", err)
 	}
 
 	return post, nil
@@ -39,60 +51,396 @@ func (r *mutationResolver) CreatePost(ctx context.Context, title string, content
 
 // AddComment создание комментария для поста
 func (r *mutationResolver) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*model.Comment, error) {
-	comment, err := r.Storage.AddComment(ctx, postID, parentID, authorID, content)
+	comment, err := r.addComment(ctx, model.AddCommentInput{
+		PostID:   postID,
+		ParentID: parentID,
+		AuthorID: authorID,
+		Content:  content,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get comment: %v", err)
+		return nil, fmt.Errorf("failed to add comment: %w", err)
 	}
 
 	return comment, nil
 }
 
+// PostCreate создание поста; ошибки, которые может исправить клиент, возвращаются в userErrors
+func (r *mutationResolver) PostCreate(ctx context.Context, input model.CreatePostInput) (*model.CreatePostPayload, error) {
+	post, err := r.createPost(ctx, input)
+	if err != nil {
+		errs, ok := userErrors(err, "")
+		if !ok {
+			return nil, fmt.Errorf("failed to create post: %w", err)
+		}
+
+		return &model.CreatePostPayload{ClientMutationID: input.ClientMutationID, UserErrors: errs}, nil
+	}
+
+	return &model.CreatePostPayload{
+		ClientMutationID: input.ClientMutationID,
+		Post:             post,
+		UserErrors:       []*model.UserError{},
+	}, nil
+}
+
+// CommentAdd создание комментария; ошибки, которые может исправить клиент, возвращаются в userErrors
+func (r *mutationResolver) CommentAdd(ctx context.Context, input model.AddCommentInput) (*model.AddCommentPayload, error) {
+	comment, err := r.addComment(ctx, input)
+	if err != nil {
+		errs, ok := userErrors(err, "postID")
+		if !ok {
+			return nil, fmt.Errorf("failed to add comment: %w", err)
+		}
+
+		return &model.AddCommentPayload{ClientMutationID: input.ClientMutationID, UserErrors: errs}, nil
+	}
+
+	return &model.AddCommentPayload{
+		ClientMutationID: input.ClientMutationID,
+		Comment:          comment,
+		UserErrors:       []*model.UserError{},
+	}, nil
+}
+
+// PostUpdate изменение поста с проверкой версии; при CONFLICT в payload приходит текущая версия
+func (r *mutationResolver) PostUpdate(ctx context.Context, input model.UpdatePostInput) (*model.UpdatePostPayload, error) {
+	post, err := r.Storage.UpdatePost(ctx, input.ID, input.ExpectedVersion, input.Title, input.Content, input.EditorID, input.Reason)
+	if err != nil {
+		errs, ok := userErrors(err, "id")
+		if !ok {
+			return nil, fmt.Errorf("failed to update post: %w", err)
+		}
+
+		return &model.UpdatePostPayload{
+			ClientMutationID: input.ClientMutationID,
+			CurrentVersion:   currentVersion(err),
+			UserErrors:       errs,
+		}, nil
+	}
+
+	return &model.UpdatePostPayload{
+		ClientMutationID: input.ClientMutationID,
+		Post:             modelPost(post),
+		UserErrors:       []*model.UserError{},
+	}, nil
+}
+
+// CommentUpdate изменение комментария с проверкой версии
+func (r *mutationResolver) CommentUpdate(ctx context.Context, input model.UpdateCommentInput) (*model.UpdateCommentPayload, error) {
+	comment, err := r.Storage.UpdateComment(ctx, input.ID, input.ExpectedVersion, input.Content, input.EditorID, input.Reason)
+	if err != nil {
+		errs, ok := userErrors(err, "id")
+		if !ok {
+			return nil, fmt.Errorf("failed to update comment: %w", err)
+		}
+
+		return &model.UpdateCommentPayload{
+			ClientMutationID: input.ClientMutationID,
+			CurrentVersion:   currentVersion(err),
+			UserErrors:       errs,
+		}, nil
+	}
+
+	return &model.UpdateCommentPayload{
+		ClientMutationID: input.ClientMutationID,
+		Comment:          modelComment(comment),
+		UserErrors:       []*model.UserError{},
+	}, nil
+}
+
+// PostDelete мягкое удаление поста модератором; deletedBy — имя его токена
+func (r *mutationResolver) PostDelete(ctx context.Context, input model.DeleteInput) (*model.DeletePostPayload, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, domain.Forbidden("deleting posts requires an admin token")
+	}
+
+	post, err := r.Storage.DeletePost(ctx, input.ID, auth.AdminName(ctx))
+	if err != nil {
+		errs, ok := userErrors(err, "id")
+		if !ok {
+			return nil, fmt.Errorf("failed to delete post: %w", err)
+		}
+
+		return &model.DeletePostPayload{ClientMutationID: input.ClientMutationID, UserErrors: errs}, nil
+	}
+
+	return &model.DeletePostPayload{
+		ClientMutationID: input.ClientMutationID,
+		Post:             tombstonePost(ctx, modelPost(post)),
+		UserErrors:       []*model.UserError{},
+	}, nil
+}
+
+// CommentDelete мягкое удаление комментария модератором; deletedBy — имя его токена
+func (r *mutationResolver) CommentDelete(ctx context.Context, input model.DeleteInput) (*model.DeleteCommentPayload, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, domain.Forbidden("deleting comments requires an admin token")
+	}
+
+	comment, err := r.Storage.DeleteComment(ctx, input.ID, auth.AdminName(ctx))
+	if err != nil {
+		errs, ok := userErrors(err, "id")
+		if !ok {
+			return nil, fmt.Errorf("failed to delete comment: %w", err)
+		}
+
+		return &model.DeleteCommentPayload{ClientMutationID: input.ClientMutationID, UserErrors: errs}, nil
+	}
+
+	return &model.DeleteCommentPayload{
+		ClientMutationID: input.ClientMutationID,
+		Comment:          tombstoneComment(ctx, modelComment(comment)),
+		UserErrors:       []*model.UserError{},
+	}, nil
+}
+
+// PostRestore восстановление удаленного поста модератором
+func (r *mutationResolver) PostRestore(ctx context.Context, input model.RestoreInput) (*model.RestorePostPayload, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, domain.Forbidden("restoring posts requires an admin token")
+	}
+
+	post, err := r.Storage.RestorePost(ctx, input.ID)
+	if err != nil {
+		errs, ok := userErrors(err, "id")
+		if !ok {
+			return nil, fmt.Errorf("failed to restore post: %w", err)
+		}
+
+		return &model.RestorePostPayload{ClientMutationID: input.ClientMutationID, UserErrors: errs}, nil
+	}
+
+	return &model.RestorePostPayload{
+		ClientMutationID: input.ClientMutationID,
+		Post:             modelPost(post),
+		UserErrors:       []*model.UserError{},
+	}, nil
+}
+
+// CommentRestore восстановление удаленного комментария модератором
+func (r *mutationResolver) CommentRestore(ctx context.Context, input model.RestoreInput) (*model.RestoreCommentPayload, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, domain.Forbidden("restoring comments requires an admin token")
+	}
+
+	comment, err := r.Storage.RestoreComment(ctx, input.ID)
+	if err != nil {
+		errs, ok := userErrors(err, "id")
+		if !ok {
+			return nil, fmt.Errorf("failed to restore comment: %w", err)
+		}
+
+		return &model.RestoreCommentPayload{ClientMutationID: input.ClientMutationID, UserErrors: errs}, nil
+	}
+
+	return &model.RestoreCommentPayload{
+		ClientMutationID: input.ClientMutationID,
+		Comment:          modelComment(comment),
+		UserErrors:       []*model.UserError{},
+	}, nil
+}
+
+// AuthorErase удаление данных автора по запросу GDPR
+func (r *mutationResolver) AuthorErase(ctx context.Context, input model.AuthorEraseInput) (*model.AuthorErasePayload, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, domain.Forbidden("erasing author data requires an admin token")
+	}
+
+	mode := r.ErasureMode
+	if input.Mode != nil {
+		mode = domain.ErasureMode(*input.Mode)
+	}
+
+	result, err := r.Storage.EraseAuthor(ctx, input.AuthorID, mode)
+	if err != nil {
+		errs, ok := userErrors(err, "authorID")
+		if !ok {
+			return nil, fmt.Errorf("failed to erase author: %w", err)
+		}
+
+		return &model.AuthorErasePayload{ClientMutationID: input.ClientMutationID, UserErrors: errs}, nil
+	}
+
+	// Сохраненные ответы createPost/addComment содержат текст и authorID автора.
+	// Если удалить их не удалось, ошибка вернется клиенту: повтор authorErase безопасен
+	if err := r.Idempotency.Forget(ctx, input.AuthorID); err != nil {
+		return nil, fmt.Errorf("failed to forget idempotent results: %w", err)
+	}
+
+	logging.FromContext(ctx).Info("author erased",
+		slog.String("mode", string(result.Mode)),
+		slog.Int("posts_deleted", int(result.PostsDeleted)),
+		slog.Int("posts_anonymized", int(result.PostsAnonymized)),
+		slog.Int("comments_anonymized", int(result.CommentsAnonymized)),
+	)
+
+	return &model.AuthorErasePayload{
+		ClientMutationID: input.ClientMutationID,
+		Result:           modelErasureResult(result),
+		UserErrors:       []*model.UserError{},
+	}, nil
+}
+
+// DataImport загрузка постов и комментариев из NDJSON; ошибки записей возвращаются в userErrors
+func (r *mutationResolver) DataImport(ctx context.Context, input model.DataImportInput) (*model.DataImportPayload, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, domain.Forbidden("importing data requires an admin token")
+	}
+
+	src := transfer.NewDecoder(strings.NewReader(input.Ndjson))
+	result, err := transfer.Import(ctx, r.Storage, src, transfer.Options{DryRun: input.DryRun})
+
+	payload := &model.DataImportPayload{
+		ClientMutationID: input.ClientMutationID,
+		Posts:            int32(result.Posts),
+		Comments:         int32(result.Comments),
+		Imported:         int32(result.Imported),
+		Skipped:          int32(result.Skipped),
+		UserErrors:       []*model.UserError{},
+	}
+	field := "ndjson"
+	for _, recordErr := range result.Errors {
+		payload.UserErrors = append(payload.UserErrors, &model.UserError{
+			Field:   &field,
+			Message: recordErr.Error(),
+			Code:    domain.CodeValidationFailed,
+		})
+	}
+
+	if err != nil {
+		errs, ok := userErrors(err, field)
+		if !ok {
+			return nil, fmt.Errorf("failed to import data: %w", err)
+		}
+		payload.UserErrors = append(payload.UserErrors, errs...)
+	}
+
+	logging.FromContext(ctx).Info("data import finished",
+		slog.Bool("dry_run", input.DryRun),
+		slog.Int64("imported", result.Imported),
+		slog.Int64("skipped", result.Skipped),
+		slog.Int("errors", len(payload.UserErrors)),
+	)
+
+	return payload, nil
+}
+
+// createPost создает пост не больше одного раза для ключа идемпотентности
+func (r *Resolver) createPost(ctx context.Context, input model.CreatePostInput) (*model.Post, error) {
+	request := map[string]any{
+		"title":            input.Title,
+		"content":          input.Content,
+		"authorID":         input.AuthorID,
+		"commentsDisabled": input.CommentsDisabled,
+	}
+
+	return idempotency.Do(ctx, r.Idempotency, deref(input.IdempotencyKey), "createPost", request, func() (*model.Post, error) {
+		post, err := r.Storage.CreatePost(ctx, input.Title, input.Content, input.AuthorID, input.CommentsDisabled)
+		if err != nil {
+			return nil, err
+		}
+
+		return modelPost(post), nil
+	})
+}
+
+// addComment создает комментарий не больше одного раза для ключа идемпотентности.
+// Подписчики получают комментарий только при первом выполнении.
+func (r *Resolver) addComment(ctx context.Context, input model.AddCommentInput) (*model.Comment, error) {
+	request := map[string]any{
+		"postID":   input.PostID,
+		"parentID": input.ParentID,
+		"authorID": input.AuthorID,
+		"content":  input.Content,
+	}
+
+	return idempotency.Do(ctx, r.Idempotency, deref(input.IdempotencyKey), "addComment", request, func() (*model.Comment, error) {
+		comment, err := r.Storage.AddComment(ctx, input.PostID, input.ParentID, input.AuthorID, input.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		created := modelComment(comment)
+		r.broker.publish(created)
+		return created, nil
+	})
+}
+
+// deref значение строки или пустая строка для nil
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 // Post получение поста по id
 func (r *queryResolver) Post(ctx context.Context, id string) (*model.Post, error) {
 	post, err := r.Storage.GetPostByID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get post: %v", err)
+		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
 
-	if post == nil {
-		return nil, fmt.Errorf("post not found")
+	return tombstonePost(ctx, modelPost(post)), nil
+}
+
+// AuthorExport выгрузка всех данных автора по запросу GDPR
+func (r *queryResolver) AuthorExport(ctx context.Context, authorID string) (*model.AuthorArchive, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, domain.Forbidden("exporting author data requires an admin token")
 	}
 
-	return post, nil
+	archive, err := r.Storage.ExportAuthor(ctx, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export author: %w", err)
+	}
+
+	return modelAuthorArchive(archive), nil
 }
 
 // Posts получение списка всех постов
 func (r *queryResolver) Posts(ctx context.Context) ([]*model.Post, error) {
-	log.Println("Fetching posts")
+	logger := logging.FromContext(ctx)
+	logger.Debug("fetching posts")
 
 	posts, err := r.Storage.GetPosts(ctx)
 	if err != nil {
-		log.Printf("Error fetching posts: %v", err)
-		return nil, fmt.Errorf("failed to get posts: %v", err)
+		logger.Error("failed to fetch posts", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get posts: %w", err)
 	}
 
-	log.Println("Successfully fetched posts")
-	return posts, nil
+	// Удаленные посты видны в списке только администраторам
+	visible := make([]*model.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.Deleted() && !auth.IsAdmin(ctx) {
+			continue
+		}
+		visible = append(visible, tombstonePost(ctx, modelPost(post)))
+	}
+
+	logger.Debug("fetched posts", slog.Int("count", len(visible)))
+	return visible, nil
 }
 
 // Comments получение комментариев с пагинацией
 func (r *queryResolver) Comments(ctx context.Context, postID string, limit *int32, cursor *string) (*model.CommentConnection, error) {
-	var limitInt int
-	if limit != nil {
-		limitInt = int(*limit)
-	}
-
-	if limitInt == 0 {
-		limitInt = 1000
-	}
-
-	comments, err := r.Storage.GetCommentsForPost(ctx, postID, limitInt, cursor)
+	comments, err := r.Storage.GetCommentsForPost(ctx, postID, listSize(limit, defaultCommentsLimit, maxCommentsLimit), cursor)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get comments: %w", err)
 	}
 
 	return &model.CommentConnection{
-		Edges:    comments,
+		Edges:    tombstoneComments(ctx, modelComments(comments)),
 		PageInfo: &model.PageInfo{},
 	}, nil
 }
+
+// Comments дерево комментариев поста; читается, только если поле запрошено
+func (r *postResolver) Comments(ctx context.Context, obj *model.Post, limit *int32, cursor *string) ([]*model.CommentWithReplies, error) {
+	comments, err := r.Storage.GetCommentsForPost(ctx, obj.ID, listSize(limit, defaultCommentsLimit, maxCommentsLimit), cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	return tombstoneComments(ctx, modelComments(comments)), nil
+}
@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/graph/model"
+)
+
+// maxDiffCells ограничение на размер таблицы LCS; для текстов больше
+// diff вырождается в удаление всех старых строк и вставку новых
+const maxDiffCells = 4_000_000
+
+// revisionsFetcher загружает историю изменений сущности
+type revisionsFetcher func(ctx context.Context, id string, limit int, after *int32) ([]*domain.Revision, error)
+
+// Revisions история изменений поста
+func (r *postResolver) Revisions(ctx context.Context, obj *model.Post, first *int32, after *string) (*model.RevisionConnection, error) {
+	if hiddenFromReader(ctx, obj.Deleted()) {
+		return emptyRevisions(), nil
+	}
+	return revisions(ctx, r.Storage.GetPostRevisions, obj.ID, first, after)
+}
+
+// Diff построчный diff текста поста между версиями
+func (r *postResolver) Diff(ctx context.Context, obj *model.Post, fromRevision int32, toRevision int32) ([]*model.DiffLine, error) {
+	if hiddenFromReader(ctx, obj.Deleted()) {
+		return []*model.DiffLine{}, nil
+	}
+	return revisionDiff(ctx, r.Storage.GetPostRevisions, obj.ID, obj.Version, obj.Content, fromRevision, toRevision)
+}
+
+// Revisions история изменений комментария
+func (r *commentWithRepliesResolver) Revisions(ctx context.Context, obj *model.CommentWithReplies, first *int32, after *string) (*model.RevisionConnection, error) {
+	if hiddenFromReader(ctx, obj.Deleted()) {
+		return emptyRevisions(), nil
+	}
+	return revisions(ctx, r.Storage.GetCommentRevisions, obj.ID, first, after)
+}
+
+// Diff построчный diff текста комментария между версиями
+func (r *commentWithRepliesResolver) Diff(ctx context.Context, obj *model.CommentWithReplies, fromRevision int32, toRevision int32) ([]*model.DiffLine, error) {
+	if hiddenFromReader(ctx, obj.Deleted()) {
+		return []*model.DiffLine{}, nil
+	}
+	return revisionDiff(ctx, r.Storage.GetCommentRevisions, obj.ID, obj.Version, obj.Content, fromRevision, toRevision)
+}
+
+// revisions страница истории изменений; курсор — номер версии
+func revisions(ctx context.Context, fetch revisionsFetcher, id string, first *int32, after *string) (*model.RevisionConnection, error) {
+	limit := listSize(first, defaultRevisionsLimit, maxRevisionsLimit)
+
+	var afterVersion *int32
+	if after != nil && *after != "" {
+		v, err := strconv.ParseInt(*after, 10, 32)
+		if err != nil {
+			return nil, domain.ValidationFailed(domain.FieldError{Field: "after", Message: "invalid cursor"})
+		}
+		version := int32(v)
+		afterVersion = &version
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	revs, err := fetch(ctx, id, limit+1, afterVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revisions: %w", err)
+	}
+
+	pageInfo := &model.PageInfo{HasNextPage: len(revs) > limit}
+	if pageInfo.HasNextPage {
+		revs = revs[:limit]
+	}
+	if len(revs) > 0 {
+		cursor := strconv.Itoa(int(revs[len(revs)-1].Version))
+		pageInfo.EndCursor = &cursor
+	}
+
+	return &model.RevisionConnection{Edges: modelRevisions(revs), PageInfo: pageInfo}, nil
+}
+
+// emptyRevisions пустая история для удаленных записей
+func emptyRevisions() *model.RevisionConnection {
+	return &model.RevisionConnection{Edges: []*model.Revision{}, PageInfo: &model.PageInfo{}}
+}
+
+// revisionDiff diff между двумя версиями; текущая версия берется из самой сущности
+func revisionDiff(ctx context.Context, fetch revisionsFetcher, id string, currentVersion int32, currentContent string, from, to int32) ([]*model.DiffLine, error) {
+	// Все предыдущие версии помещаются в currentVersion-1 записей
+	revs, err := fetch(ctx, id, int(currentVersion), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revisions: %w", err)
+	}
+
+	content := func(version int32) (string, bool) {
+		if version == currentVersion {
+			return currentContent, true
+		}
+		for _, r := range revs {
+			if r.Version == version {
+				return r.Content, true
+			}
+		}
+		return "", false
+	}
+
+	var v []domain.FieldError
+	fromContent, ok := content(from)
+	if !ok {
+		v = append(v, domain.FieldError{Field: "fromRevision", Message: "revision not found"})
+	}
+	toContent, ok := content(to)
+	if !ok {
+		v = append(v, domain.FieldError{Field: "toRevision", Message: "revision not found"})
+	}
+	if len(v) > 0 {
+		return nil, domain.ValidationFailed(v...)
+	}
+
+	return diffLines(fromContent, toContent), nil
+}
+
+// diffLines построчный diff через наибольшую общую подпоследовательность
+func diffLines(from, to string) []*model.DiffLine {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	// Общие начало и конец не участвуют в LCS
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]*model.DiffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		result = append(result, &model.DiffLine{Op: model.DiffOpEqual, Text: line})
+	}
+	result = append(result, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		result = append(result, &model.DiffLine{Op: model.DiffOpEqual, Text: line})
+	}
+
+	return result
+}
+
+// diffMiddle diff различающейся части текстов
+func diffMiddle(a, b []string) []*model.DiffLine {
+	result := make([]*model.DiffLine, 0, len(a)+len(b))
+
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			result = append(result, &model.DiffLine{Op: model.DiffOpDelete, Text: line})
+		}
+		for _, line := range b {
+			result = append(result, &model.DiffLine{Op: model.DiffOpInsert, Text: line})
+		}
+		return result
+	}
+
+	// lcs[i][j] длина LCS для a[i:] и b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, &model.DiffLine{Op: model.DiffOpEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, &model.DiffLine{Op: model.DiffOpDelete, Text: a[i]})
+			i++
+		default:
+			result = append(result, &model.DiffLine{Op: model.DiffOpInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		result = append(result, &model.DiffLine{Op: model.DiffOpDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		result = append(result, &model.DiffLine{Op: model.DiffOpInsert, Text: b[j]})
+	}
+
+	return result
+}
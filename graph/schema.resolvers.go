@@ -6,24 +6,40 @@ package graph
 
 import (
 	"context"
-	"fmt"
 	"github.com/YakovlevIgA/forozon/graph/model"
 )
 
 // CommentAdded is the resolver for the commentAdded field.
 func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string) (<-chan *model.Comment, error) {
-	panic(fmt.Errorf("not implemented: CommentAdded - commentAdded"))
+	ch := r.broker.subscribe(postID)
+
+	go func() {
+		<-ctx.Done()
+		r.broker.unsubscribe(postID, ch)
+	}()
+
+	return ch, nil
+}
+
+// CommentWithReplies returns CommentWithRepliesResolver implementation.
+func (r *Resolver) CommentWithReplies() CommentWithRepliesResolver {
+	return &commentWithRepliesResolver{r}
 }
 
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Post returns PostResolver implementation.
+func (r *Resolver) Post() PostResolver { return &postResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
 // Subscription returns SubscriptionResolver implementation.
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
+type commentWithRepliesResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
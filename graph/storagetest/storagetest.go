@@ -0,0 +1,1139 @@
+// Package storagetest общий набор проверок, который должна проходить любая реализация domain.Storage.
+//
+// Реализация подключает его из своего теста:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) domain.Storage { return NewInMemoryRepository() })
+//	}
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+// Factory создает пустое хранилище для одного теста; ресурсы освобождаются через t.Cleanup
+type Factory func(t *testing.T) domain.Storage
+
+// Run запускает все проверки, каждую на новом хранилище
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s domain.Storage)
+	}{
+		{"CreatePost", testCreatePost},
+		{"CreatePostValidation", testCreatePostValidation},
+		{"GetPostByIDNotFound", testGetPostByIDNotFound},
+		{"AddComment", testAddComment},
+		{"AddCommentValidation", testAddCommentValidation},
+		{"CommentTree", testCommentTree},
+		{"GetPostsOrder", testGetPostsOrder},
+		{"Latest", testLatest},
+		{"CommentsPagination", testCommentsPagination},
+		{"UpdateVersions", testUpdateVersions},
+		{"SoftDelete", testSoftDelete},
+		{"PurgeDeleted", testPurgeDeleted},
+		{"Counters", testCounters},
+		{"ConcurrentComments", testConcurrentComments},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+		{"ExportImport", testExportImport},
+		{"EraseAuthorAnonymize", testEraseAuthorAnonymize},
+		{"EraseAuthorDeletePosts", testEraseAuthorDeletePosts},
+		{"ExportAuthor", testExportAuthor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStorage(t))
+		})
+	}
+}
+
+func testCreatePost(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+
+	post, err := s.CreatePost(ctx, "title", "content", "author", true)
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	if post.ID == "" {
+		t.Fatal("CreatePost returned empty ID")
+	}
+	if post.Title != "title" || post.Content != "content" || post.AuthorID != "author" || !post.CommentsDisabled {
+		t.Errorf("CreatePost returned %+v", post)
+	}
+	if post.Version != 1 {
+		t.Errorf("new post version = %d, want 1", post.Version)
+	}
+	if post.Deleted() {
+		t.Error("new post is deleted")
+	}
+
+	got, err := s.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if got.ID != post.ID || got.Title != post.Title || got.Content != post.Content ||
+		got.AuthorID != post.AuthorID || got.CommentsDisabled != post.CommentsDisabled ||
+		got.CreatedAt != post.CreatedAt || got.Version != post.Version {
+		t.Errorf("GetPostByID = %+v, want %+v", got, post)
+	}
+	if comments := mustGetComments(t, s, post.ID); len(comments) != 0 {
+		t.Errorf("new post has %d comments", len(comments))
+	}
+}
+
+func testCreatePostValidation(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+
+	_, err := s.CreatePost(ctx, "", "", "", false)
+	requireCode(t, err, domain.ErrValidationFailed)
+	requireFields(t, err, "title", "content", "authorID")
+
+	_, err = s.CreatePost(ctx, "title", "   ", "author", false)
+	requireCode(t, err, domain.ErrValidationFailed)
+	requireFields(t, err, "content")
+
+	posts, err := s.GetPosts(ctx)
+	if err != nil {
+		t.Fatalf("GetPosts: %v", err)
+	}
+	if len(posts) != 0 {
+		t.Errorf("invalid posts were stored: %d", len(posts))
+	}
+}
+
+func testGetPostByIDNotFound(t *testing.T, s domain.Storage) {
+	post, err := s.GetPostByID(context.Background(), "00000000-0000-0000-0000-000000000000")
+	requireCode(t, err, domain.ErrNotFound)
+	if post != nil {
+		t.Errorf("GetPostByID returned %+v for missing post", post)
+	}
+}
+
+func testAddComment(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+
+	comment, err := s.AddComment(ctx, post.ID, nil, "commenter", "hello")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if comment.ID == "" || comment.PostID != post.ID || comment.ParentID != nil ||
+		comment.AuthorID != "commenter" || comment.Content != "hello" || comment.Version != 1 {
+		t.Errorf("AddComment returned %+v", comment)
+	}
+
+	reply, err := s.AddComment(ctx, post.ID, &comment.ID, "replier", "hi")
+	if err != nil {
+		t.Fatalf("AddComment reply: %v", err)
+	}
+	if reply.ParentID == nil || *reply.ParentID != comment.ID {
+		t.Errorf("reply parentID = %v, want %s", reply.ParentID, comment.ID)
+	}
+}
+
+func testAddCommentValidation(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+	other := mustCreatePost(t, s)
+	otherComment := mustAddComment(t, s, other.ID, nil)
+
+	disabled, err := s.CreatePost(ctx, "title", "content", "author", true)
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+
+	missing := "00000000-0000-0000-0000-000000000000"
+
+	tests := []struct {
+		name     string
+		postID   string
+		parentID *string
+		authorID string
+		content  string
+		want     error
+		fields   []string
+	}{
+		{"missing post", missing, nil, "author", "text", domain.ErrNotFound, nil},
+		{"comments disabled", disabled.ID, nil, "author", "text", domain.ErrCommentsDisabled, nil},
+		{"empty fields", post.ID, nil, "", "", domain.ErrValidationFailed, []string{"authorID", "content"}},
+		{"missing parent", post.ID, &missing, "author", "text", domain.ErrValidationFailed, []string{"parentID"}},
+		{"parent in another post", post.ID, &otherComment.ID, "author", "text", domain.ErrValidationFailed, []string{"parentID"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment, err := s.AddComment(ctx, tt.postID, tt.parentID, tt.authorID, tt.content)
+			requireCode(t, err, tt.want)
+			requireFields(t, err, tt.fields...)
+			if comment != nil {
+				t.Errorf("AddComment returned %+v with error", comment)
+			}
+		})
+	}
+
+	if comments := mustGetComments(t, s, post.ID); len(comments) != 0 {
+		t.Errorf("invalid comments were stored: %d", len(comments))
+	}
+}
+
+func testCommentTree(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+
+	first := mustAddComment(t, s, post.ID, nil)
+	reply := mustAddComment(t, s, post.ID, &first.ID)
+	nested := mustAddComment(t, s, post.ID, &reply.ID)
+	second := mustAddComment(t, s, post.ID, nil)
+	secondReply := mustAddComment(t, s, post.ID, &first.ID)
+
+	want := []treeNode{
+		{first.ID, []treeNode{
+			{reply.ID, []treeNode{{nested.ID, nil}}},
+			{secondReply.ID, nil},
+		}},
+		{second.ID, nil},
+	}
+	if diff := compareTree(mustGetComments(t, s, post.ID), want); diff != "" {
+		t.Errorf("GetCommentsForPost comment tree: %s", diff)
+	}
+
+	// Посты читаются без комментариев, их число хранится в счетчике
+	posts, err := s.GetPosts(ctx)
+	if err != nil {
+		t.Fatalf("GetPosts: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("GetPosts returned %d posts, want 1", len(posts))
+	}
+	if posts[0].CommentCount != 5 {
+		t.Errorf("GetPosts commentCount = %d, want 5", posts[0].CommentCount)
+	}
+}
+
+func testGetPostsOrder(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+
+	var want []string
+	for range 5 {
+		want = append(want, mustCreatePost(t, s).ID)
+	}
+
+	posts, err := s.GetPosts(ctx)
+	if err != nil {
+		t.Fatalf("GetPosts: %v", err)
+	}
+
+	var got []string
+	for _, p := range posts {
+		got = append(got, p.ID)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("GetPosts order = %v, want creation order %v", got, want)
+	}
+}
+
+func testLatest(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+
+	first := mustCreatePost(t, s)
+	other, err := s.CreatePost(ctx, "title", "content", "other", false)
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	deleted := mustCreatePost(t, s)
+	last := mustCreatePost(t, s)
+	if _, err := s.DeletePost(ctx, deleted.ID, "moderator"); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+
+	for _, tt := range []struct {
+		authorID string
+		limit    int
+		want     []string
+	}{
+		{"", 10, []string{last.ID, other.ID, first.ID}},
+		{"", 2, []string{last.ID, other.ID}},
+		{"author", 10, []string{last.ID, first.ID}},
+		{"other", 10, []string{other.ID}},
+	} {
+		posts, err := s.GetLatestPosts(ctx, tt.authorID, tt.limit)
+		if err != nil {
+			t.Fatalf("GetLatestPosts: %v", err)
+		}
+		var got []string
+		for _, p := range posts {
+			got = append(got, p.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("GetLatestPosts(%q, %d) = %v, want %v", tt.authorID, tt.limit, got, tt.want)
+		}
+	}
+
+	// Комментарии всех уровней, без удаленных и без комментариев других постов
+	root := mustAddComment(t, s, first.ID, nil)
+	reply := mustAddComment(t, s, first.ID, &root.ID)
+	mustAddComment(t, s, other.ID, nil)
+	gone := mustAddComment(t, s, first.ID, nil)
+	newest := mustAddComment(t, s, first.ID, &reply.ID)
+	if _, err := s.DeleteComment(ctx, gone.ID, "moderator"); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+
+	for limit, want := range map[int][]string{
+		10: {newest.ID, reply.ID, root.ID},
+		2:  {newest.ID, reply.ID},
+	} {
+		comments, err := s.GetLatestComments(ctx, first.ID, limit)
+		if err != nil {
+			t.Fatalf("GetLatestComments: %v", err)
+		}
+		var got []string
+		for _, c := range comments {
+			got = append(got, c.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("GetLatestComments(%d) = %v, want %v", limit, got, want)
+		}
+	}
+}
+
+func testCommentsPagination(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+	other := mustCreatePost(t, s)
+
+	var want []string
+	for range 5 {
+		want = append(want, mustAddComment(t, s, post.ID, nil).ID)
+		mustAddComment(t, s, other.ID, nil)
+	}
+
+	var got []string
+	var cursor *string
+	for page := 0; ; page++ {
+		if page > len(want) {
+			t.Fatal("pagination does not terminate")
+		}
+
+		comments, err := s.GetCommentsForPost(ctx, post.ID, 2, cursor)
+		if err != nil {
+			t.Fatalf("GetCommentsForPost: %v", err)
+		}
+		if len(comments) > 2 {
+			t.Fatalf("page has %d comments, limit 2", len(comments))
+		}
+		if len(comments) == 0 {
+			break
+		}
+
+		for _, c := range comments {
+			if c.PostID != post.ID {
+				t.Errorf("comment %s of post %s on page of post %s", c.ID, c.PostID, post.ID)
+			}
+			got = append(got, c.ID)
+		}
+		cursor = &comments[len(comments)-1].ID
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("paginated comments = %v, want creation order %v", got, want)
+	}
+
+	comments, err := s.GetCommentsForPost(ctx, "00000000-0000-0000-0000-000000000000", 10, nil)
+	if err != nil {
+		t.Fatalf("GetCommentsForPost for missing post: %v", err)
+	}
+	if len(comments) != 0 {
+		t.Errorf("missing post has %d comments", len(comments))
+	}
+}
+
+func testUpdateVersions(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+	comment := mustAddComment(t, s, post.ID, nil)
+
+	title := "new title"
+	reason := "typo"
+	updated, err := s.UpdatePost(ctx, post.ID, 1, &title, nil, "editor", &reason)
+	if err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if updated.Version != 2 || updated.Title != title || updated.Content != post.Content {
+		t.Errorf("UpdatePost returned %+v", updated)
+	}
+
+	_, err = s.UpdatePost(ctx, post.ID, 1, &title, nil, "editor", nil)
+	requireCode(t, err, domain.ErrConflict)
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) && fmt.Sprint(domainErr.Details["currentVersion"]) != "2" {
+		t.Errorf("conflict currentVersion = %v, want 2", domainErr.Details["currentVersion"])
+	}
+
+	_, err = s.UpdatePost(ctx, "00000000-0000-0000-0000-000000000000", 1, &title, nil, "editor", nil)
+	requireCode(t, err, domain.ErrNotFound)
+
+	revisions, err := s.GetPostRevisions(ctx, post.ID, 10, nil)
+	if err != nil {
+		t.Fatalf("GetPostRevisions: %v", err)
+	}
+	if len(revisions) != 1 || revisions[0].Version != 1 || derefString(revisions[0].Title) != post.Title ||
+		revisions[0].EditorID != "editor" || derefString(revisions[0].Reason) != reason {
+		t.Errorf("GetPostRevisions = %s", formatRevisions(revisions))
+	}
+	if post.UpdatedAt != nil || len(revisions) == 1 && derefString(updated.UpdatedAt) != revisions[0].EditedAt {
+		t.Errorf("post updatedAt = %q before and %q after the edit, want the revision editedAt",
+			derefString(post.UpdatedAt), derefString(updated.UpdatedAt))
+	}
+	if got, err := s.GetPostByID(ctx, post.ID); err != nil || derefString(got.UpdatedAt) != derefString(updated.UpdatedAt) {
+		t.Errorf("GetPostByID updatedAt = %v, %v; want %q", got, err, derefString(updated.UpdatedAt))
+	}
+
+	updatedComment, err := s.UpdateComment(ctx, comment.ID, 1, "edited", "editor", nil)
+	if err != nil {
+		t.Fatalf("UpdateComment: %v", err)
+	}
+	if updatedComment.Version != 2 || updatedComment.Content != "edited" {
+		t.Errorf("UpdateComment returned %+v", updatedComment)
+	}
+
+	_, err = s.UpdateComment(ctx, comment.ID, 1, "again", "editor", nil)
+	requireCode(t, err, domain.ErrConflict)
+
+	revisions, err = s.GetCommentRevisions(ctx, comment.ID, 10, nil)
+	if err != nil {
+		t.Fatalf("GetCommentRevisions: %v", err)
+	}
+	if len(revisions) != 1 || revisions[0].Version != 1 || revisions[0].Content != comment.Content {
+		t.Errorf("GetCommentRevisions = %s", formatRevisions(revisions))
+	}
+	if comment.UpdatedAt != nil || len(revisions) == 1 && derefString(updatedComment.UpdatedAt) != revisions[0].EditedAt {
+		t.Errorf("comment updatedAt = %q before and %q after the edit, want the revision editedAt",
+			derefString(comment.UpdatedAt), derefString(updatedComment.UpdatedAt))
+	}
+}
+
+func testSoftDelete(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+	comment := mustAddComment(t, s, post.ID, nil)
+
+	deleted, err := s.DeleteComment(ctx, comment.ID, "moderator")
+	if err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	if !deleted.Deleted() || derefString(deleted.DeletedBy) != "moderator" || deleted.Content != comment.Content {
+		t.Errorf("DeleteComment returned %+v", deleted)
+	}
+
+	_, err = s.AddComment(ctx, post.ID, &comment.ID, "author", "reply to deleted")
+	requireCode(t, err, domain.ErrValidationFailed)
+
+	_, err = s.UpdateComment(ctx, comment.ID, 1, "edit deleted", "editor", nil)
+	requireCode(t, err, domain.ErrNotFound)
+
+	restored, err := s.RestoreComment(ctx, comment.ID)
+	if err != nil {
+		t.Fatalf("RestoreComment: %v", err)
+	}
+	if restored.Deleted() || restored.DeletedBy != nil {
+		t.Errorf("RestoreComment returned %+v", restored)
+	}
+
+	if _, err := s.DeletePost(ctx, post.ID, "moderator"); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+
+	// Удаленный пост остается доступным по ID, но не принимает комментарии
+	got, err := s.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("GetPostByID for deleted post: %v", err)
+	}
+	if !got.Deleted() {
+		t.Error("GetPostByID returned deleted post without deletedAt")
+	}
+
+	_, err = s.AddComment(ctx, post.ID, nil, "author", "text")
+	requireCode(t, err, domain.ErrNotFound)
+
+	_, err = s.DeletePost(ctx, "00000000-0000-0000-0000-000000000000", "moderator")
+	requireCode(t, err, domain.ErrNotFound)
+}
+
+func testPurgeDeleted(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+	parent := mustAddComment(t, s, post.ID, nil)
+	reply := mustAddComment(t, s, post.ID, &parent.ID)
+	gone := mustCreatePost(t, s)
+
+	for _, id := range []string{parent.ID} {
+		if _, err := s.DeleteComment(ctx, id, "moderator"); err != nil {
+			t.Fatalf("DeleteComment: %v", err)
+		}
+	}
+	if _, err := s.DeletePost(ctx, gone.ID, "moderator"); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+
+	// Удаленный комментарий с живым ответом сохраняется
+	purged, err := s.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("PurgeDeleted: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("PurgeDeleted purged %d records, want 1", purged)
+	}
+
+	_, err = s.GetPostByID(ctx, gone.ID)
+	requireCode(t, err, domain.ErrNotFound)
+
+	if diff := compareTree(mustGetComments(t, s, post.ID), []treeNode{{parent.ID, []treeNode{{reply.ID, nil}}}}); diff != "" {
+		t.Errorf("comment tree after purge: %s", diff)
+	}
+
+	// Когда удален и ответ, ветка удаляется целиком
+	if _, err := s.DeleteComment(ctx, reply.ID, "moderator"); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	purged, err = s.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("PurgeDeleted: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("PurgeDeleted purged %d records, want 2", purged)
+	}
+
+	if comments := mustGetComments(t, s, post.ID); len(comments) != 0 {
+		t.Errorf("post has %d comments after purge", len(comments))
+	}
+}
+
+func testCounters(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+	root := mustAddComment(t, s, post.ID, nil)
+	first := mustAddComment(t, s, post.ID, &root.ID)
+	second := mustAddComment(t, s, post.ID, &root.ID)
+
+	requireCounters(t, s, post.ID, 3, &second.CreatedAt, 2)
+
+	// Удаление последнего комментария возвращает время к предыдущему, восстановление — обратно
+	if _, err := s.DeleteComment(ctx, second.ID, "moderator"); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	requireCounters(t, s, post.ID, 2, &first.CreatedAt, 1)
+
+	// Повторное удаление счетчики не меняет
+	if _, err := s.DeleteComment(ctx, second.ID, "moderator"); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	requireCounters(t, s, post.ID, 2, &first.CreatedAt, 1)
+
+	if _, err := s.RestoreComment(ctx, second.ID); err != nil {
+		t.Fatalf("RestoreComment: %v", err)
+	}
+	requireCounters(t, s, post.ID, 3, &second.CreatedAt, 2)
+
+	for _, id := range []string{root.ID, first.ID, second.ID} {
+		if _, err := s.DeleteComment(ctx, id, "moderator"); err != nil {
+			t.Fatalf("DeleteComment: %v", err)
+		}
+	}
+	requireCounters(t, s, post.ID, 0, nil, 0)
+
+	// Счетчики поддерживались верно: пересчитывать нечего
+	if r, ok := s.(domain.CounterRepairer); ok {
+		repaired, err := r.RepairCounters(ctx)
+		if err != nil {
+			t.Fatalf("RepairCounters: %v", err)
+		}
+		if repaired != 0 {
+			t.Errorf("RepairCounters repaired %d records, want 0", repaired)
+		}
+	}
+}
+
+// requireCounters проверяет счетчики поста и ответов первого корневого комментария
+// и в GetPostByID, и в GetPosts
+func requireCounters(t *testing.T, s domain.Storage, postID string, comments int32, lastCommentAt *string, replies int32) {
+	t.Helper()
+	ctx := context.Background()
+
+	got, err := s.GetPostByID(ctx, postID)
+	if err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+
+	posts, err := s.GetPosts(ctx)
+	if err != nil {
+		t.Fatalf("GetPosts: %v", err)
+	}
+
+	for _, post := range posts {
+		if post.ID != postID {
+			continue
+		}
+		for name, p := range map[string]*domain.Post{"GetPostByID": got, "GetPosts": post} {
+			if p.CommentCount != comments || derefString(p.LastCommentAt) != derefString(lastCommentAt) {
+				t.Errorf("%s: commentCount = %d, lastCommentAt = %q; want %d, %q",
+					name, p.CommentCount, derefString(p.LastCommentAt), comments, derefString(lastCommentAt))
+			}
+		}
+		if comments := mustGetComments(t, s, postID); len(comments) > 0 && comments[0].ReplyCount != replies {
+			t.Errorf("replyCount = %d, want %d", comments[0].ReplyCount, replies)
+		}
+		return
+	}
+	t.Fatalf("GetPosts did not return post %s", postID)
+}
+
+func testConcurrentComments(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+	root := mustAddComment(t, s, post.ID, nil)
+
+	const writers, perWriter = 8, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				var parentID *string
+				if i%2 == 1 {
+					parentID = &root.ID
+				}
+				if _, err := s.AddComment(ctx, post.ID, parentID, fmt.Sprintf("writer-%d", w), "text"); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent AddComment: %v", err)
+	}
+
+	got, err := s.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+
+	ids := map[string]bool{}
+	var walk func(comments []*domain.CommentWithReplies)
+	walk = func(comments []*domain.CommentWithReplies) {
+		for _, c := range comments {
+			if ids[c.ID] {
+				t.Errorf("duplicate comment %s", c.ID)
+			}
+			ids[c.ID] = true
+			walk(c.Replies)
+		}
+	}
+	comments := mustGetComments(t, s, post.ID)
+	walk(comments)
+
+	if want := writers*perWriter + 1; len(ids) != want {
+		t.Errorf("post has %d comments, want %d", len(ids), want)
+	}
+
+	// Счетчики не теряют параллельные изменения
+	if want := int32(writers*perWriter + 1); got.CommentCount != want {
+		t.Errorf("commentCount = %d, want %d", got.CommentCount, want)
+	}
+	if want := int32(writers * perWriter / 2); comments[0].ReplyCount != want {
+		t.Errorf("replyCount = %d, want %d", comments[0].ReplyCount, want)
+	}
+}
+
+func testConcurrentUpdates(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+
+	const writers = 8
+
+	var wg sync.WaitGroup
+	results := make(chan error, writers)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			title := fmt.Sprintf("title %d", w)
+			_, err := s.UpdatePost(ctx, post.ID, 1, &title, nil, "editor", nil)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, domain.ErrConflict):
+		default:
+			t.Errorf("concurrent UpdatePost: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d concurrent updates of the same version succeeded, want 1", succeeded)
+	}
+
+	got, err := s.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if got.Version != 2 {
+		t.Errorf("post version = %d, want 2", got.Version)
+	}
+
+	revisions, err := s.GetPostRevisions(ctx, post.ID, 10, nil)
+	if err != nil {
+		t.Fatalf("GetPostRevisions: %v", err)
+	}
+	if len(revisions) != 1 {
+		t.Errorf("post has %d revisions, want 1", len(revisions))
+	}
+}
+
+// testExportImport выгруженные записи загружаются под другими id: связи, время, удаление
+// и история сохраняются, счетчики пересчитываются, повторная загрузка ничего не меняет
+func testExportImport(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	post := mustCreatePost(t, s)
+	root := mustAddComment(t, s, post.ID, nil)
+	reply := mustAddComment(t, s, post.ID, &root.ID)
+	deleted := mustAddComment(t, s, post.ID, &root.ID)
+	if _, err := s.DeleteComment(ctx, deleted.ID, "moderator"); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	title := "new title"
+	if _, err := s.UpdatePost(ctx, post.ID, 1, &title, nil, "editor", nil); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+
+	records, err := s.ExportRecords(ctx, 10, nil)
+	if err != nil {
+		t.Fatalf("ExportRecords: %v", err)
+	}
+	var ids []string
+	for _, r := range records {
+		ids = append(ids, r.ID())
+	}
+	if want := []string{post.ID, root.ID, reply.ID, deleted.ID}; fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Fatalf("ExportRecords ids = %v, want %v", ids, want)
+	}
+	if len(records[0].Revisions) != 1 || records[3].Comment.DeletedAt == nil {
+		t.Errorf("ExportRecords lost history or deletion: %s, %+v", formatRevisions(records[0].Revisions), records[3].Comment)
+	}
+
+	page, err := s.ExportRecords(ctx, 10, domain.CursorAfter(post))
+	if err != nil {
+		t.Fatalf("ExportRecords after cursor: %v", err)
+	}
+	if len(page) != 0 {
+		t.Errorf("ExportRecords after the last post returned %d records", len(page))
+	}
+
+	// Позиция сравнивается по значениям: поста из курсора может уже не быть
+	page, err = s.ExportRecords(ctx, 10, &domain.RecordCursor{CreatedAt: "0", ID: "purged"})
+	if err != nil {
+		t.Fatalf("ExportRecords after a missing post: %v", err)
+	}
+	if len(page) != len(records) {
+		t.Errorf("ExportRecords after a missing post returned %d records, want %d", len(page), len(records))
+	}
+
+	_, err = s.ExportRecords(ctx, 10, &domain.RecordCursor{ID: post.ID})
+	requireCode(t, err, domain.ErrValidationFailed)
+	requireFields(t, err, "cursor")
+
+	copies := renameRecords(records, func(id string) string { return "copy-" + id })
+	// Счетчики из записей не загружаются, а пересчитываются
+	copies[0].Post.CommentCount = 100
+	for i := 0; i < 2; i++ {
+		imported, err := s.ImportRecords(ctx, copies)
+		if err != nil {
+			t.Fatalf("ImportRecords: %v", err)
+		}
+		if want := int64(len(copies)) * int64(1-i); imported != want {
+			t.Errorf("ImportRecords run %d imported %d records, want %d", i+1, imported, want)
+		}
+	}
+	requireCounters(t, s, "copy-"+post.ID, 2, &reply.CreatedAt, 1)
+
+	all, err := s.ExportRecords(ctx, 10, nil)
+	if err != nil {
+		t.Fatalf("ExportRecords: %v", err)
+	}
+	var got []*domain.Record
+	for _, r := range all {
+		if strings.HasPrefix(r.ID(), "copy-") {
+			got = append(got, r)
+		}
+	}
+	got = renameRecords(got, func(id string) string { return strings.TrimPrefix(id, "copy-") })
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(records)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("imported records differ:\n got %s\nwant %s", gotJSON, wantJSON)
+	}
+
+	orphan := renameRecords(records[1:2], func(id string) string { return "orphan-" + id })
+	_, err = s.ImportRecords(ctx, orphan)
+	requireCode(t, err, domain.ErrValidationFailed)
+	requireFields(t, err, "comment.postID")
+}
+
+// renameRecords копии записей с id, переименованными через rename
+// erasureFixture записи автора "erased" и его следы в чужих: правка и удаление чужого поста
+// и комментария, собственные правки и удаление себя
+type erasureFixture struct {
+	own, other                           *domain.Post
+	replyToOwn, ownComment, otherComment *domain.Comment
+}
+
+func newErasureFixture(t *testing.T, s domain.Storage) *erasureFixture {
+	t.Helper()
+
+	ctx := context.Background()
+	must := func(_ any, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("fixture: %v", err)
+		}
+	}
+	post := func(authorID string) *domain.Post {
+		t.Helper()
+		p, err := s.CreatePost(ctx, "title", "content", authorID, false)
+		must(p, err)
+		return p
+	}
+	comment := func(postID, authorID, content string) *domain.Comment {
+		t.Helper()
+		c, err := s.AddComment(ctx, postID, nil, authorID, content)
+		must(c, err)
+		return c
+	}
+	ptr := func(s string) *string { return &s }
+
+	f := &erasureFixture{own: post("erased"), other: post("other")}
+	f.replyToOwn = comment(f.own.ID, "other", "reply")
+	f.ownComment = comment(f.other.ID, "erased", "secret")
+	f.otherComment = comment(f.other.ID, "other", "text")
+
+	must(s.UpdatePost(ctx, f.own.ID, 1, ptr("secret title"), nil, "erased", nil))
+	must(s.UpdatePost(ctx, f.other.ID, 1, nil, ptr("edited"), "erased", nil))
+	must(s.UpdateComment(ctx, f.ownComment.ID, 1, "secret edit", "erased", nil))
+	must(s.UpdateComment(ctx, f.otherComment.ID, 1, "edited", "erased", nil))
+	must(s.DeletePost(ctx, f.own.ID, "erased"))
+	must(s.DeleteComment(ctx, f.ownComment.ID, "erased"))
+	must(s.DeleteComment(ctx, f.otherComment.ID, "erased"))
+
+	return f
+}
+
+// mustExport все записи хранилища, включая удаленные и историю изменений, по id
+func mustExport(t *testing.T, s domain.Storage) map[string]*domain.Record {
+	t.Helper()
+
+	records, err := s.ExportRecords(context.Background(), 1000, nil)
+	if err != nil {
+		t.Fatalf("ExportRecords: %v", err)
+	}
+	byID := make(map[string]*domain.Record, len(records))
+	for _, r := range records {
+		byID[r.ID()] = r
+	}
+
+	// Ни id автора, ни его текст не должны остаться ни в записях, ни в истории
+	data, _ := json.Marshal(records)
+	for _, leaked := range []string{`"erased"`, "secret"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("records still contain %s: %s", leaked, data)
+		}
+	}
+	return byID
+}
+
+// requireErasedTraces проверяет чужие записи: текст на месте, автор правок и удаления стерт
+func requireErasedTraces(t *testing.T, records map[string]*domain.Record, f *erasureFixture) {
+	t.Helper()
+
+	other := records[f.other.ID]
+	if other == nil || other.Post.AuthorID != "other" || other.Post.Content != "edited" {
+		t.Fatalf("other post after erasure: %+v", other)
+	}
+	if len(other.Revisions) != 1 || other.Revisions[0].EditorID != domain.ErasedAuthor {
+		t.Errorf("other post revisions = %s, want one by %q", formatRevisions(other.Revisions), domain.ErasedAuthor)
+	}
+
+	comment := records[f.otherComment.ID]
+	if comment == nil || comment.Comment.AuthorID != "other" || comment.Comment.Content != "edited" {
+		t.Fatalf("other comment after erasure: %+v", comment)
+	}
+	if derefString(comment.Comment.DeletedBy) != domain.ErasedAuthor {
+		t.Errorf("other comment deletedBy = %q, want %q", derefString(comment.Comment.DeletedBy), domain.ErasedAuthor)
+	}
+	if len(comment.Revisions) != 1 || comment.Revisions[0].EditorID != domain.ErasedAuthor {
+		t.Errorf("other comment revisions = %s, want one by %q", formatRevisions(comment.Revisions), domain.ErasedAuthor)
+	}
+
+	// Комментарий автора к чужому посту анонимизируется в обоих режимах
+	own := records[f.ownComment.ID]
+	if own == nil {
+		t.Fatal("own comment on other post was removed")
+	}
+	if own.Comment.AuthorID != domain.ErasedAuthor || own.Comment.Content != domain.ErasedAuthor ||
+		derefString(own.Comment.DeletedBy) != domain.ErasedAuthor || len(own.Revisions) != 0 {
+		t.Errorf("own comment after erasure: %+v, revisions %s", own.Comment, formatRevisions(own.Revisions))
+	}
+}
+
+func testEraseAuthorAnonymize(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	f := newErasureFixture(t, s)
+
+	result, err := s.EraseAuthor(ctx, "erased", domain.ErasureModeAnonymize)
+	if err != nil {
+		t.Fatalf("EraseAuthor: %v", err)
+	}
+	want := domain.ErasureResult{AuthorID: "erased", Mode: domain.ErasureModeAnonymize, PostsAnonymized: 1, CommentsAnonymized: 1}
+	if *result != want {
+		t.Errorf("EraseAuthor = %+v, want %+v", *result, want)
+	}
+
+	records := mustExport(t, s)
+	requireErasedTraces(t, records, f)
+
+	own := records[f.own.ID]
+	if own == nil {
+		t.Fatal("anonymized post was removed")
+	}
+	if own.Post.AuthorID != domain.ErasedAuthor || own.Post.Title != domain.ErasedAuthor || own.Post.Content != domain.ErasedAuthor ||
+		derefString(own.Post.DeletedBy) != domain.ErasedAuthor || len(own.Revisions) != 0 {
+		t.Errorf("anonymized post: %+v, revisions %s", own.Post, formatRevisions(own.Revisions))
+	}
+	if reply := records[f.replyToOwn.ID]; reply == nil || reply.Comment.AuthorID != "other" || reply.Comment.Content != "reply" {
+		t.Errorf("reply to anonymized post: %+v", reply)
+	}
+
+	_, err = s.EraseAuthor(ctx, domain.ErasedAuthor, domain.ErasureModeAnonymize)
+	requireCode(t, err, domain.ErrValidationFailed)
+	requireFields(t, err, "authorID")
+	_, err = s.EraseAuthor(ctx, "other", "UNKNOWN")
+	requireCode(t, err, domain.ErrValidationFailed)
+	requireFields(t, err, "mode")
+}
+
+func testEraseAuthorDeletePosts(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	f := newErasureFixture(t, s)
+
+	result, err := s.EraseAuthor(ctx, "erased", domain.ErasureModeDeletePosts)
+	if err != nil {
+		t.Fatalf("EraseAuthor: %v", err)
+	}
+	want := domain.ErasureResult{AuthorID: "erased", Mode: domain.ErasureModeDeletePosts, PostsDeleted: 1, CommentsAnonymized: 1}
+	if *result != want {
+		t.Errorf("EraseAuthor = %+v, want %+v", *result, want)
+	}
+
+	records := mustExport(t, s)
+	requireErasedTraces(t, records, f)
+
+	// Пост удаляется вместе с чужими комментариями к нему
+	if records[f.own.ID] != nil || records[f.replyToOwn.ID] != nil {
+		t.Errorf("deleted post or its comments remain: %+v, %+v", records[f.own.ID], records[f.replyToOwn.ID])
+	}
+	_, err = s.GetPostByID(ctx, f.own.ID)
+	requireCode(t, err, domain.ErrNotFound)
+}
+
+func testExportAuthor(t *testing.T, s domain.Storage) {
+	ctx := context.Background()
+	f := newErasureFixture(t, s)
+
+	archive, err := s.ExportAuthor(ctx, "erased")
+	if err != nil {
+		t.Fatalf("ExportAuthor: %v", err)
+	}
+	if archive.AuthorID != "erased" || archive.ExportedAt == "" {
+		t.Errorf("ExportAuthor = %+v", archive)
+	}
+
+	// Удаленные записи тоже выгружаются, в порядке создания
+	var posts, comments []string
+	for _, p := range archive.Posts {
+		posts = append(posts, p.ID)
+	}
+	for _, c := range archive.Comments {
+		comments = append(comments, c.ID)
+	}
+	if want := []string{f.own.ID}; fmt.Sprint(posts) != fmt.Sprint(want) {
+		t.Errorf("exported posts = %v, want %v", posts, want)
+	}
+	if want := []string{f.ownComment.ID}; fmt.Sprint(comments) != fmt.Sprint(want) {
+		t.Errorf("exported comments = %v, want %v", comments, want)
+	}
+	if len(archive.Posts) == 1 && (archive.Posts[0].Title != "secret title" || !archive.Posts[0].Deleted()) {
+		t.Errorf("exported post: %+v", archive.Posts[0])
+	}
+
+	if _, err := s.EraseAuthor(ctx, "erased", domain.ErasureModeAnonymize); err != nil {
+		t.Fatalf("EraseAuthor: %v", err)
+	}
+	archive, err = s.ExportAuthor(ctx, "erased")
+	if err != nil {
+		t.Fatalf("ExportAuthor after erasure: %v", err)
+	}
+	if archive.Posts == nil || archive.Comments == nil || len(archive.Posts)+len(archive.Comments) != 0 {
+		t.Errorf("ExportAuthor after erasure = %+v, want empty lists", archive)
+	}
+
+	_, err = s.ExportAuthor(ctx, "")
+	requireCode(t, err, domain.ErrValidationFailed)
+	requireFields(t, err, "authorID")
+}
+
+func renameRecords(records []*domain.Record, rename func(string) string) []*domain.Record {
+	renamed := make([]*domain.Record, 0, len(records))
+	for _, r := range records {
+		c := *r
+		if r.Post != nil {
+			post := *r.Post
+			post.ID = rename(post.ID)
+			c.Post = &post
+		}
+		if r.Comment != nil {
+			comment := *r.Comment
+			comment.ID = rename(comment.ID)
+			comment.PostID = rename(comment.PostID)
+			if comment.ParentID != nil {
+				parentID := rename(*comment.ParentID)
+				comment.ParentID = &parentID
+			}
+			c.Comment = &comment
+		}
+		renamed = append(renamed, &c)
+	}
+	return renamed
+}
+
+func mustCreatePost(t *testing.T, s domain.Storage) *domain.Post {
+	t.Helper()
+
+	post, err := s.CreatePost(context.Background(), "title", "content", "author", false)
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	return post
+}
+
+func mustAddComment(t *testing.T, s domain.Storage, postID string, parentID *string) *domain.Comment {
+	t.Helper()
+
+	comment, err := s.AddComment(context.Background(), postID, parentID, "author", "comment")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	return comment
+}
+
+// mustGetComments дерево всех комментариев поста
+func mustGetComments(t *testing.T, s domain.Storage, postID string) []*domain.CommentWithReplies {
+	t.Helper()
+
+	comments, err := s.GetCommentsForPost(context.Background(), postID, 1000, nil)
+	if err != nil {
+		t.Fatalf("GetCommentsForPost: %v", err)
+	}
+	return comments
+}
+
+// requireCode проверяет код доменной ошибки
+func requireCode(t *testing.T, err error, want error) {
+	t.Helper()
+
+	if !errors.Is(err, want) {
+		t.Fatalf("error = %v, want %v", err, want)
+	}
+}
+
+// requireFields проверяет, что ошибка валидации указывает на поля fields
+func requireFields(t *testing.T, err error, fields ...string) {
+	t.Helper()
+
+	if len(fields) == 0 {
+		return
+	}
+
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		t.Fatalf("error %v is not *domain.Error", err)
+	}
+
+	got := map[string]bool{}
+	for _, f := range domainErr.Fields {
+		got[f.Field] = true
+	}
+	for _, f := range fields {
+		if !got[f] {
+			t.Errorf("validation error does not mention field %q: %+v", f, domainErr.Fields)
+		}
+	}
+}
+
+// treeNode ожидаемая форма дерева комментариев
+type treeNode struct {
+	id      string
+	replies []treeNode
+}
+
+// compareTree сравнивает дерево комментариев с ожидаемым, включая порядок; пустая строка — совпадает
+func compareTree(got []*domain.CommentWithReplies, want []treeNode) string {
+	if len(got) != len(want) {
+		return fmt.Sprintf("got %d comments, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i].id {
+			return fmt.Sprintf("comment %d is %s, want %s", i, got[i].ID, want[i].id)
+		}
+		if diff := compareTree(got[i].Replies, want[i].replies); diff != "" {
+			return fmt.Sprintf("replies of %s: %s", want[i].id, diff)
+		}
+	}
+	return ""
+}
+
+func formatRevisions(revisions []*domain.Revision) string {
+	var out []string
+	for _, r := range revisions {
+		out = append(out, fmt.Sprintf("{version:%d title:%q content:%q editor:%q reason:%q}",
+			r.Version, derefString(r.Title), r.Content, r.EditorID, derefString(r.Reason)))
+	}
+	return fmt.Sprint(out)
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
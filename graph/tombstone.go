@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/YakovlevIgA/forozon/auth"
+	"github.com/YakovlevIgA/forozon/graph/model"
+)
+
+// tombstoneText текст, который читатели видят вместо удаленного содержимого
+const tombstoneText = "[deleted]"
+
+// tombstonePost копия поста, в которой содержимое удаленного поста скрыто;
+// администраторы видят оригинал, чтобы решить, восстанавливать ли
+func tombstonePost(ctx context.Context, post *model.Post) *model.Post {
+	if post == nil {
+		return nil
+	}
+
+	p := *post
+	if p.Deleted() && !auth.IsAdmin(ctx) {
+		p.Title = tombstoneText
+		p.Content = tombstoneText
+		p.AuthorID = ""
+	}
+
+	return &p
+}
+
+// tombstoneComment копия комментария со скрытым содержимым, если он удален
+func tombstoneComment(ctx context.Context, comment *model.Comment) *model.Comment {
+	if comment == nil {
+		return nil
+	}
+
+	c := *comment
+	if c.Deleted() && !auth.IsAdmin(ctx) {
+		c.Content = tombstoneText
+		c.AuthorID = ""
+	}
+
+	return &c
+}
+
+// tombstoneComments копия ветки комментариев; удаленные остаются на своих местах,
+// чтобы ответы на них не потерялись
+func tombstoneComments(ctx context.Context, comments []*model.CommentWithReplies) []*model.CommentWithReplies {
+	if comments == nil {
+		return nil
+	}
+
+	admin := auth.IsAdmin(ctx)
+	result := make([]*model.CommentWithReplies, 0, len(comments))
+	for _, comment := range comments {
+		c := *comment
+		if c.Deleted() && !admin {
+			c.Content = tombstoneText
+			c.AuthorID = ""
+		}
+		c.Replies = tombstoneComments(ctx, comment.Replies)
+		result = append(result, &c)
+	}
+
+	return result
+}
+
+// hiddenFromReader скрыта ли история изменений удаленной записи от текущего запроса
+func hiddenFromReader(ctx context.Context, deleted bool) bool {
+	return deleted && !auth.IsAdmin(ctx)
+}
@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"errors"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/graph/model"
+)
+
+// userErrors превращает доменную ошибку хранилища в userErrors мутации.
+// field — поле входных данных для ошибок без списка полей (например, пост не найден).
+// Возвращает false для внутренних ошибок: они остаются ошибками GraphQL.
+func userErrors(err error, field string) ([]*model.UserError, bool) {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		return nil, false
+	}
+
+	if len(domainErr.Fields) > 0 {
+		errs := make([]*model.UserError, 0, len(domainErr.Fields))
+		for _, f := range domainErr.Fields {
+			errs = append(errs, &model.UserError{
+				Field:   &f.Field,
+				Message: f.Message,
+				Code:    domainErr.Code,
+			})
+		}
+		return errs, true
+	}
+
+	userErr := &model.UserError{Message: domainErr.Error(), Code: domainErr.Code}
+	if field != "" {
+		userErr.Field = &field
+	}
+
+	return []*model.UserError{userErr}, true
+}
+
+// currentVersion текущая версия сущности из ошибки CONFLICT или nil
+func currentVersion(err error) *int32 {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) || domainErr.Code != domain.CodeConflict {
+		return nil
+	}
+
+	version, ok := domainErr.Details["currentVersion"].(int32)
+	if !ok {
+		return nil
+	}
+	return &version
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/logging"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// codeInternal код внутренней ошибки; подробности есть только в логе
+const codeInternal = "INTERNAL"
+
+// presentError превращает ошибку резолвера в ответ клиенту:
+// доменные ошибки получают extensions.code, ошибки GraphQL (разбор,
+// валидация, лимиты) проходят как есть, остальные скрываются за общим
+// сообщением с идентификатором для поиска в логах
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, domainErr)
+		gqlErr.Message = domainErr.Error()
+		gqlErr.Extensions = map[string]any{"code": domainErr.Code}
+		if len(domainErr.Fields) > 0 {
+			gqlErr.Extensions["fields"] = domainErr.Fields
+		}
+		for k, v := range domainErr.Details {
+			gqlErr.Extensions[k] = v
+		}
+		return gqlErr
+	}
+
+	var gqlErr *gqlerror.Error
+	if errors.As(err, &gqlErr) {
+		return graphql.DefaultErrorPresenter(ctx, err)
+	}
+
+	return internalError(ctx, err, nil)
+}
+
+// recoverPanic перехватывает панику резолвера, чтобы она не роняла процесс
+func recoverPanic(ctx context.Context, p any) error {
+	return internalError(ctx, fmt.Errorf("panic: %v", p), debug.Stack())
+}
+
+// internalError пишет ошибку в лог и возвращает клиенту только идентификатор
+func internalError(ctx context.Context, err error, stack []byte) *gqlerror.Error {
+	errorID := uuid.New().String()
+
+	attrs := []any{slog.String("error_id", errorID), slog.Any("error", err)}
+	if graphql.GetFieldContext(ctx) != nil {
+		attrs = append(attrs, slog.String("path", graphql.GetPath(ctx).String()))
+	}
+	if stack != nil {
+		attrs = append(attrs, slog.String("stack", string(stack)))
+	}
+	logging.FromContext(ctx).Error("internal error", attrs...)
+
+	return &gqlerror.Error{
+		Message: "internal server error",
+		Path:    graphql.GetPath(ctx),
+		Extensions: map[string]any{
+			"code":    codeInternal,
+			"errorId": errorID,
+		},
+	}
+}
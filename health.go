@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthCheck проверка доступности хранилища
+type healthCheck func(ctx context.Context) error
+
+// healthChecker периодически проверяет хранилище и отвечает на пробы оркестратора
+type healthChecker struct {
+	check     healthCheck
+	interval  time.Duration
+	threshold time.Duration
+
+	shuttingDown atomic.Bool
+
+	mu        sync.RWMutex
+	lastOK    time.Time
+	lastError error
+}
+
+// newHealthChecker создает новый экземпляр healthChecker.
+// check может быть nil, если хранилищу нечего проверять (in-memory).
+func newHealthChecker(check healthCheck, interval, threshold time.Duration) *healthChecker {
+	return &healthChecker{
+		check:     check,
+		interval:  interval,
+		threshold: threshold,
+		lastOK:    time.Now(),
+	}
+}
+
+// run выполняет проверки до отмены контекста
+func (h *healthChecker) run(ctx context.Context) {
+	if h.check == nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe выполняет одну проверку хранилища
+func (h *healthChecker) probe(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, h.interval)
+	defer cancel()
+
+	err := h.check(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastError = err
+	if err == nil {
+		h.lastOK = time.Now()
+	}
+}
+
+// setShuttingDown переводит сервис в состояние "не готов" на время остановки
+func (h *healthChecker) setShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// ready готов ли сервис принимать трафик; кратковременные ошибки хранилища
+// не снимают готовность, пока не превышен порог
+func (h *healthChecker) ready() (bool, string) {
+	if h.shuttingDown.Load() {
+		return false, "shutting down"
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.lastError == nil {
+		return true, "ok"
+	}
+
+	if time.Since(h.lastOK) > h.threshold {
+		return false, "storage unavailable: " + h.lastError.Error()
+	}
+
+	return true, "storage degraded: " + h.lastError.Error()
+}
+
+// healthzHandler liveness: процесс жив и обслуживает HTTP
+func (h *healthChecker) healthzHandler(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyzHandler readiness: хранилище доступно и сервис не останавливается
+func (h *healthChecker) readyzHandler(w http.ResponseWriter, _ *http.Request) {
+	ok, reason := h.ready()
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, map[string]any{"ready": ok, "status": reason})
+}
+
+// versionHandler информация о сборке из debug.ReadBuildInfo
+func versionHandler(w http.ResponseWriter, _ *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]string{"version": "unknown"})
+		return
+	}
+
+	resp := map[string]string{
+		"module":    info.Main.Path,
+		"version":   info.Main.Version,
+		"goVersion": info.GoVersion,
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			resp["revision"] = s.Value
+		case "vcs.time":
+			resp["buildTime"] = s.Value
+		case "vcs.modified":
+			resp["modified"] = s.Value
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writeJSON отправляет ответ в формате JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// redactContent скрывать ли пользовательский контент в логах
+var redactContent atomic.Bool
+
+func init() {
+	redactContent.Store(true)
+}
+
+// New создает JSON-логгер; уровень можно менять на лету через level
+func New(w io.Writer, level *slog.LevelVar) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// ParseLevel разбирает уровень логирования: debug, info, warn, error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+	return level, nil
+}
+
+// SetRedaction включает или выключает скрытие пользовательского контента
+func SetRedaction(enabled bool) {
+	redactContent.Store(enabled)
+}
+
+type ctxKey struct{}
+
+// WithLogger кладет логгер в контекст
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext достает логгер из контекста, по умолчанию slog.Default()
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Content атрибут с пользовательским текстом; по умолчанию пишется только длина
+func Content(key, value string) slog.Attr {
+	return slog.Any(key, userContent(value))
+}
+
+// userContent пользовательский текст, скрываемый при логировании
+type userContent string
+
+// LogValue реализует slog.LogValuer
+func (c userContent) LogValue() slog.Value {
+	if redactContent.Load() {
+		return slog.StringValue(fmt.Sprintf("[redacted %d chars]", len([]rune(c))))
+	}
+	return slog.StringValue(string(c))
+}
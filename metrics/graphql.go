@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// otherOperation метка для имен операций вне списка известных
+const otherOperation = "other"
+
+// Tracer gqlgen-расширение, собирающее метрики операций и резолверов
+type Tracer struct {
+	m *Metrics
+	// operations имена операций, которые попадают в метки как есть; имя задает
+	// клиент, поэтому остальные сводятся к "other", чтобы не плодить временные ряды
+	operations map[string]struct{}
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = Tracer{}
+
+// Tracer создает расширение для handler.Server.Use; operations — имена операций,
+// допустимые в метках (обычно из манифеста доверенных документов)
+func (m *Metrics) Tracer(operations ...string) Tracer {
+	known := make(map[string]struct{}, len(operations))
+	for _, name := range operations {
+		known[name] = struct{}{}
+	}
+	return Tracer{m: m, operations: known}
+}
+
+// ExtensionName имя расширения
+func (Tracer) ExtensionName() string {
+	return "PrometheusMetrics"
+}
+
+// Validate проверка схемы не требуется
+func (Tracer) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation считает подписки: они живут дольше одного ответа
+func (t Tracer) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	name, typ := t.operationLabels(ctx)
+	if typ != string(ast.Subscription) {
+		return next(ctx)
+	}
+
+	t.m.requests.WithLabelValues(name, typ).Inc()
+	t.m.activeSubscriptions.Inc()
+
+	var once sync.Once
+	done := func() { once.Do(t.m.activeSubscriptions.Dec) }
+
+	handler := next(ctx)
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+		if resp == nil {
+			done()
+		}
+		return resp
+	}
+}
+
+// InterceptResponse считает запросы, время и ошибки операций
+func (t Tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	start := graphql.Now()
+	resp := next(ctx)
+
+	name, typ := t.operationLabels(ctx)
+	if typ != string(ast.Subscription) {
+		if graphql.HasOperationContext(ctx) {
+			if opStart := graphql.GetOperationContext(ctx).Stats.OperationStart; !opStart.IsZero() {
+				start = opStart
+			}
+		}
+
+		t.m.requests.WithLabelValues(name, typ).Inc()
+		t.m.requestDuration.WithLabelValues(name, typ).Observe(time.Since(start).Seconds())
+	}
+
+	if resp != nil && len(resp.Errors) > 0 {
+		t.m.requestErrors.WithLabelValues(name, typ).Inc()
+	}
+
+	return resp
+}
+
+// InterceptField замеряет время резолверов; тривиальные поля структур пропускаются
+func (t Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	start := time.Now()
+	res, err := next(ctx)
+	t.m.fieldDuration.WithLabelValues(fc.Object, fc.Field.Name).Observe(time.Since(start).Seconds())
+
+	return res, err
+}
+
+// operationLabels имя и тип операции для меток
+func (t Tracer) operationLabels(ctx context.Context) (string, string) {
+	if !graphql.HasOperationContext(ctx) {
+		return "unknown", "unknown"
+	}
+
+	op := graphql.GetOperationContext(ctx).Operation
+	if op == nil {
+		return "unknown", "unknown"
+	}
+
+	name := op.Name
+	if name == "" {
+		name = "anonymous"
+	} else if _, ok := t.operations[name]; !ok {
+		name = otherOperation
+	}
+
+	return name, string(op.Operation)
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "forozon"
+
+// Metrics набор prometheus-метрик приложения
+type Metrics struct {
+	requests            *prometheus.CounterVec
+	requestDuration     *prometheus.HistogramVec
+	requestErrors       *prometheus.CounterVec
+	fieldDuration       *prometheus.HistogramVec
+	activeSubscriptions prometheus.Gauge
+
+	storageDuration *prometheus.HistogramVec
+	storageErrors   *prometheus.CounterVec
+
+	reg prometheus.Registerer
+}
+
+// New создает метрики и регистрирует их в reg
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "requests_total",
+			Help:      "Number of GraphQL operations.",
+		}, []string{"operation", "type"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "request_duration_seconds",
+			Help:      "GraphQL operation latency.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "type"}),
+		requestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "errors_total",
+			Help:      "Number of GraphQL responses with errors.",
+		}, []string{"operation", "type"}),
+		fieldDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "field_duration_seconds",
+			Help:      "Field resolver latency.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"object", "field"}),
+		activeSubscriptions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "active_subscriptions",
+			Help:      "Number of active subscriptions.",
+		}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "call_duration_seconds",
+			Help:      "Storage method latency.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend", "method"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "errors_total",
+			Help:      "Number of storage method errors.",
+		}, []string{"backend", "method"}),
+		reg: reg,
+	}
+
+	reg.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.requestErrors,
+		m.fieldDuration,
+		m.activeSubscriptions,
+		m.storageDuration,
+		m.storageErrors,
+	)
+
+	return m
+}
+
+// RegisterPool добавляет метрики использования пула соединений postgres
+func (m *Metrics) RegisterPool(pool *pgxpool.Pool) {
+	gauge := func(name, help string, value func(s *pgxpool.Stat) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "db_pool",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return value(pool.Stat()) })
+	}
+
+	m.reg.MustRegister(
+		gauge("acquired_conns", "Connections currently acquired from the pool.", func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) }),
+		gauge("idle_conns", "Idle connections in the pool.", func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) }),
+		gauge("total_conns", "Total connections in the pool.", func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) }),
+		gauge("max_conns", "Maximum size of the pool.", func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) }),
+	)
+}
@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+// Storage декоратор domain.Storage, записывающий время и ошибки каждого метода
+type Storage struct {
+	next    domain.Storage
+	backend string
+	m       *Metrics
+}
+
+var _ domain.Storage = (*Storage)(nil)
+
+// WrapStorage оборачивает хранилище; backend попадает в метку метрик
+func (m *Metrics) WrapStorage(next domain.Storage, backend string) *Storage {
+	return &Storage{next: next, backend: backend, m: m}
+}
+
+// observe записывает метрики вызова метода
+func (s *Storage) observe(method string, start time.Time, err error) {
+	s.m.storageDuration.WithLabelValues(s.backend, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		s.m.storageErrors.WithLabelValues(s.backend, method).Inc()
+	}
+}
+
+// GetPosts получение всех постов
+func (s *Storage) GetPosts(ctx context.Context) ([]*domain.Post, error) {
+	start := time.Now()
+	posts, err := s.next.GetPosts(ctx)
+	s.observe("GetPosts", start, err)
+	return posts, err
+}
+
+// GetLatestPosts последние посты
+func (s *Storage) GetLatestPosts(ctx context.Context, authorID string, limit int) ([]*domain.Post, error) {
+	start := time.Now()
+	posts, err := s.next.GetLatestPosts(ctx, authorID, limit)
+	s.observe("GetLatestPosts", start, err)
+	return posts, err
+}
+
+// GetPostByID получение поста по ID
+func (s *Storage) GetPostByID(ctx context.Context, id string) (*domain.Post, error) {
+	start := time.Now()
+	post, err := s.next.GetPostByID(ctx, id)
+	s.observe("GetPostByID", start, err)
+	return post, err
+}
+
+// CreatePost создание поста
+func (s *Storage) CreatePost(ctx context.Context, title, content, authorID string, commentsDisabled bool) (*domain.Post, error) {
+	start := time.Now()
+	post, err := s.next.CreatePost(ctx, title, content, authorID, commentsDisabled)
+	s.observe("CreatePost", start, err)
+	return post, err
+}
+
+// AddComment добавление комментария
+func (s *Storage) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*domain.Comment, error) {
+	start := time.Now()
+	comment, err := s.next.AddComment(ctx, postID, parentID, authorID, content)
+	s.observe("AddComment", start, err)
+	return comment, err
+}
+
+// GetCommentsForPost получение комментариев поста с пагинацией
+func (s *Storage) GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*domain.CommentWithReplies, error) {
+	start := time.Now()
+	comments, err := s.next.GetCommentsForPost(ctx, postID, limit, cursor)
+	s.observe("GetCommentsForPost", start, err)
+	return comments, err
+}
+
+// GetLatestComments последние комментарии поста
+func (s *Storage) GetLatestComments(ctx context.Context, postID string, limit int) ([]*domain.Comment, error) {
+	start := time.Now()
+	comments, err := s.next.GetLatestComments(ctx, postID, limit)
+	s.observe("GetLatestComments", start, err)
+	return comments, err
+}
+
+// UpdatePost изменение поста
+func (s *Storage) UpdatePost(ctx context.Context, id string, expectedVersion int32, title, content *string, editorID string, reason *string) (*domain.Post, error) {
+	start := time.Now()
+	post, err := s.next.UpdatePost(ctx, id, expectedVersion, title, content, editorID, reason)
+	s.observe("UpdatePost", start, err)
+	return post, err
+}
+
+// UpdateComment изменение комментария
+func (s *Storage) UpdateComment(ctx context.Context, id string, expectedVersion int32, content string, editorID string, reason *string) (*domain.Comment, error) {
+	start := time.Now()
+	comment, err := s.next.UpdateComment(ctx, id, expectedVersion, content, editorID, reason)
+	s.observe("UpdateComment", start, err)
+	return comment, err
+}
+
+// GetPostRevisions история изменений поста
+func (s *Storage) GetPostRevisions(ctx context.Context, postID string, limit int, after *int32) ([]*domain.Revision, error) {
+	start := time.Now()
+	revisions, err := s.next.GetPostRevisions(ctx, postID, limit, after)
+	s.observe("GetPostRevisions", start, err)
+	return revisions, err
+}
+
+// GetCommentRevisions история изменений комментария
+func (s *Storage) GetCommentRevisions(ctx context.Context, commentID string, limit int, after *int32) ([]*domain.Revision, error) {
+	start := time.Now()
+	revisions, err := s.next.GetCommentRevisions(ctx, commentID, limit, after)
+	s.observe("GetCommentRevisions", start, err)
+	return revisions, err
+}
+
+// DeletePost мягкое удаление поста
+func (s *Storage) DeletePost(ctx context.Context, id, deletedBy string) (*domain.Post, error) {
+	start := time.Now()
+	post, err := s.next.DeletePost(ctx, id, deletedBy)
+	s.observe("DeletePost", start, err)
+	return post, err
+}
+
+// DeleteComment мягкое удаление комментария
+func (s *Storage) DeleteComment(ctx context.Context, id, deletedBy string) (*domain.Comment, error) {
+	start := time.Now()
+	comment, err := s.next.DeleteComment(ctx, id, deletedBy)
+	s.observe("DeleteComment", start, err)
+	return comment, err
+}
+
+// RestorePost восстановление поста
+func (s *Storage) RestorePost(ctx context.Context, id string) (*domain.Post, error) {
+	start := time.Now()
+	post, err := s.next.RestorePost(ctx, id)
+	s.observe("RestorePost", start, err)
+	return post, err
+}
+
+// RestoreComment восстановление комментария
+func (s *Storage) RestoreComment(ctx context.Context, id string) (*domain.Comment, error) {
+	start := time.Now()
+	comment, err := s.next.RestoreComment(ctx, id)
+	s.observe("RestoreComment", start, err)
+	return comment, err
+}
+
+// PurgeDeleted окончательное удаление устаревших записей
+func (s *Storage) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	start := time.Now()
+	purged, err := s.next.PurgeDeleted(ctx, before)
+	s.observe("PurgeDeleted", start, err)
+	return purged, err
+}
+
+// EraseAuthor удаление данных автора
+func (s *Storage) EraseAuthor(ctx context.Context, authorID string, mode domain.ErasureMode) (*domain.ErasureResult, error) {
+	start := time.Now()
+	result, err := s.next.EraseAuthor(ctx, authorID, mode)
+	s.observe("EraseAuthor", start, err)
+	return result, err
+}
+
+// ExportAuthor выгрузка данных автора
+func (s *Storage) ExportAuthor(ctx context.Context, authorID string) (*domain.AuthorArchive, error) {
+	start := time.Now()
+	archive, err := s.next.ExportAuthor(ctx, authorID)
+	s.observe("ExportAuthor", start, err)
+	return archive, err
+}
+
+// ExportRecords страница выгрузки
+func (s *Storage) ExportRecords(ctx context.Context, limit int, after *domain.RecordCursor) ([]*domain.Record, error) {
+	start := time.Now()
+	records, err := s.next.ExportRecords(ctx, limit, after)
+	s.observe("ExportRecords", start, err)
+	return records, err
+}
+
+// ImportRecords загрузка записей
+func (s *Storage) ImportRecords(ctx context.Context, records []*domain.Record) (int64, error) {
+	start := time.Now()
+	imported, err := s.next.ImportRecords(ctx, records)
+	s.observe("ImportRecords", start, err)
+	return imported, err
+}
@@ -0,0 +1,6 @@
+-- Automatic Persisted Queries: sha256 текста запроса -> текст
+CREATE TABLE persisted_queries (
+  hash CHAR(64) PRIMARY KEY,
+  query TEXT NOT NULL,
+  createdAt TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
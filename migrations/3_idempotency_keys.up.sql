@@ -0,0 +1,10 @@
+-- Ключи идемпотентности мутаций: хэш запроса и результат в JSON
+CREATE TABLE idempotency_keys (
+  key VARCHAR(512) PRIMARY KEY,
+  requestHash CHAR(64) NOT NULL,
+  result JSONB,
+  createdAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expiresAt TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expiresAt_idx ON idempotency_keys (expiresAt);
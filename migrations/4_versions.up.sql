@@ -0,0 +1,3 @@
+-- Версии для оптимистичной блокировки при изменении постов и комментариев
+ALTER TABLE posts ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE comments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
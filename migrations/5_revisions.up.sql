@@ -0,0 +1,21 @@
+-- История изменений: предыдущие версии постов и комментариев и правка, которая их заменила
+CREATE TABLE post_revisions (
+  postID VARCHAR(255) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  title VARCHAR(255) NOT NULL,
+  content TEXT NOT NULL,
+  editorID VARCHAR(255) NOT NULL,
+  reason TEXT,
+  editedAt VARCHAR(255) NOT NULL,
+  PRIMARY KEY (postID, version)
+);
+
+CREATE TABLE comment_revisions (
+  commentID VARCHAR(255) NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  content TEXT NOT NULL,
+  editorID VARCHAR(255) NOT NULL,
+  reason TEXT,
+  editedAt VARCHAR(255) NOT NULL,
+  PRIMARY KEY (commentID, version)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS comments_deletedAt_idx;
+DROP INDEX IF EXISTS posts_deletedAt_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS deletedAt, DROP COLUMN IF EXISTS deletedBy;
+ALTER TABLE posts DROP COLUMN IF EXISTS deletedAt, DROP COLUMN IF EXISTS deletedBy;
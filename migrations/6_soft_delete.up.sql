@@ -0,0 +1,6 @@
+-- Мягкое удаление: записи помечаются и окончательно удаляются фоновой очисткой после срока хранения
+ALTER TABLE posts ADD COLUMN deletedAt TIMESTAMPTZ, ADD COLUMN deletedBy VARCHAR(255);
+ALTER TABLE comments ADD COLUMN deletedAt TIMESTAMPTZ, ADD COLUMN deletedBy VARCHAR(255);
+
+CREATE INDEX posts_deletedAt_idx ON posts (deletedAt) WHERE deletedAt IS NOT NULL;
+CREATE INDEX comments_deletedAt_idx ON comments (deletedAt) WHERE deletedAt IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS comments_parentID_idx;
+DROP INDEX IF EXISTS comments_postID_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS replyCount;
+ALTER TABLE posts DROP COLUMN IF EXISTS commentCount, DROP COLUMN IF EXISTS lastCommentAt;
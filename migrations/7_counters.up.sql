@@ -0,0 +1,22 @@
+-- Денормализованные счетчики: видимые (не удаленные) комментарии поста, время последнего из них
+-- и видимые прямые ответы комментария. Поддерживаются при добавлении, удалении и восстановлении
+-- комментариев; пересчитать с нуля: go run . repair-counters
+ALTER TABLE posts ADD COLUMN commentCount INTEGER NOT NULL DEFAULT 0, ADD COLUMN lastCommentAt VARCHAR(255);
+ALTER TABLE comments ADD COLUMN replyCount INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX comments_postID_idx ON comments (postID);
+CREATE INDEX comments_parentID_idx ON comments (parentID);
+
+UPDATE posts SET commentCount = counts.n, lastCommentAt = counts.last
+FROM (
+  SELECT postID, COUNT(*) AS n, MAX(createdAt) AS last FROM comments
+  WHERE deletedAt IS NULL GROUP BY postID
+) counts
+WHERE posts.id = counts.postID;
+
+UPDATE comments SET replyCount = counts.n
+FROM (
+  SELECT parentID, COUNT(*) AS n FROM comments
+  WHERE parentID IS NOT NULL AND deletedAt IS NULL GROUP BY parentID
+) counts
+WHERE comments.id = counts.parentID;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS persisted_queries_lastUsedAt_idx;
+ALTER TABLE persisted_queries DROP COLUMN IF EXISTS lastUsedAt;
@@ -0,0 +1,4 @@
+-- Время последнего использования APQ: по нему запросы вытесняются из таблицы
+ALTER TABLE persisted_queries ADD COLUMN lastUsedAt TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX persisted_queries_lastUsedAt_idx ON persisted_queries (lastUsedAt);
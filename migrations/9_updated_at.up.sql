@@ -0,0 +1,17 @@
+-- Время последней правки поста и комментария: ленты берут его из записи, а не из истории.
+-- Для уже измененных записей заполняется временем последней версии в истории
+ALTER TABLE posts ADD COLUMN updatedAt VARCHAR(255);
+ALTER TABLE comments ADD COLUMN updatedAt VARCHAR(255);
+
+UPDATE posts SET updatedAt = revisions.last
+FROM (SELECT postID, MAX(editedAt) AS last FROM post_revisions GROUP BY postID) revisions
+WHERE posts.id = revisions.postID;
+
+UPDATE comments SET updatedAt = revisions.last
+FROM (SELECT commentID, MAX(editedAt) AS last FROM comment_revisions GROUP BY commentID) revisions
+WHERE comments.id = revisions.commentID;
+
+-- Последние посты (в том числе автора) и последние комментарии поста для лент
+CREATE INDEX posts_createdAt_idx ON posts (createdAt DESC, id DESC) WHERE deletedAt IS NULL;
+CREATE INDEX posts_authorID_createdAt_idx ON posts (authorID, createdAt DESC, id DESC) WHERE deletedAt IS NULL;
+CREATE INDEX comments_postID_createdAt_idx ON comments (postID, createdAt DESC, id DESC) WHERE deletedAt IS NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS comment_revisions;
+DROP TABLE IF EXISTS post_revisions;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
@@ -0,0 +1,53 @@
+-- Схема sqlite: та же, что у postgres после миграции 6.
+-- Время удаления хранится текстом фиксированной ширины, чтобы сравнение строк совпадало со сравнением времени.
+CREATE TABLE posts (
+  id TEXT PRIMARY KEY,
+  title TEXT NOT NULL,
+  content TEXT NOT NULL,
+  authorID TEXT NOT NULL,
+  createdAt TEXT NOT NULL,
+  commentsDisabled INTEGER NOT NULL DEFAULT 0,
+  version INTEGER NOT NULL DEFAULT 1,
+  deletedAt TEXT,
+  deletedBy TEXT
+);
+
+CREATE TABLE comments (
+  id TEXT PRIMARY KEY,
+  postID TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  parentID TEXT REFERENCES comments(id) ON DELETE CASCADE,
+  authorID TEXT NOT NULL,
+  content TEXT NOT NULL,
+  createdAt TEXT NOT NULL,
+  version INTEGER NOT NULL DEFAULT 1,
+  deletedAt TEXT,
+  deletedBy TEXT
+);
+
+CREATE INDEX comments_postID_idx ON comments (postID);
+CREATE INDEX comments_parentID_idx ON comments (parentID);
+CREATE INDEX posts_authorID_idx ON posts (authorID);
+CREATE INDEX comments_authorID_idx ON comments (authorID);
+CREATE INDEX posts_deletedAt_idx ON posts (deletedAt) WHERE deletedAt IS NOT NULL;
+CREATE INDEX comments_deletedAt_idx ON comments (deletedAt) WHERE deletedAt IS NOT NULL;
+
+CREATE TABLE post_revisions (
+  postID TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  content TEXT NOT NULL,
+  editorID TEXT NOT NULL,
+  reason TEXT,
+  editedAt TEXT NOT NULL,
+  PRIMARY KEY (postID, version)
+);
+
+CREATE TABLE comment_revisions (
+  commentID TEXT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  content TEXT NOT NULL,
+  editorID TEXT NOT NULL,
+  reason TEXT,
+  editedAt TEXT NOT NULL,
+  PRIMARY KEY (commentID, version)
+);
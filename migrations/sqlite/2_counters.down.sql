@@ -0,0 +1,3 @@
+ALTER TABLE comments DROP COLUMN replyCount;
+ALTER TABLE posts DROP COLUMN lastCommentAt;
+ALTER TABLE posts DROP COLUMN commentCount;
@@ -0,0 +1,11 @@
+-- Денормализованные счетчики, как в миграции 7 postgres
+ALTER TABLE posts ADD COLUMN commentCount INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN lastCommentAt TEXT;
+ALTER TABLE comments ADD COLUMN replyCount INTEGER NOT NULL DEFAULT 0;
+
+UPDATE posts SET
+  commentCount = (SELECT COUNT(*) FROM comments c WHERE c.postID = posts.id AND c.deletedAt IS NULL),
+  lastCommentAt = (SELECT MAX(createdAt) FROM comments c WHERE c.postID = posts.id AND c.deletedAt IS NULL);
+
+UPDATE comments SET
+  replyCount = (SELECT COUNT(*) FROM comments r WHERE r.parentID = comments.id AND r.deletedAt IS NULL);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS comments_postID_createdAt_idx;
+DROP INDEX IF EXISTS posts_authorID_createdAt_idx;
+DROP INDEX IF EXISTS posts_createdAt_idx;
+ALTER TABLE comments DROP COLUMN updatedAt;
+ALTER TABLE posts DROP COLUMN updatedAt;
@@ -0,0 +1,10 @@
+-- Время последней правки, как в миграции 9 postgres
+ALTER TABLE posts ADD COLUMN updatedAt TEXT;
+ALTER TABLE comments ADD COLUMN updatedAt TEXT;
+
+UPDATE posts SET updatedAt = (SELECT MAX(editedAt) FROM post_revisions r WHERE r.postID = posts.id);
+UPDATE comments SET updatedAt = (SELECT MAX(editedAt) FROM comment_revisions r WHERE r.commentID = comments.id);
+
+CREATE INDEX posts_createdAt_idx ON posts (createdAt DESC, id DESC) WHERE deletedAt IS NULL;
+CREATE INDEX posts_authorID_createdAt_idx ON posts (authorID, createdAt DESC, id DESC) WHERE deletedAt IS NULL;
+CREATE INDEX comments_postID_createdAt_idx ON comments (postID, createdAt DESC, id DESC) WHERE deletedAt IS NULL;
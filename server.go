@@ -6,133 +6,742 @@ import (
 	"errors"
 	"fmt"
 	"github.com/YakovlevIgA/forozon/graph/repository"
-	"github.com/jackc/pgx/v4"
-	"log"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/YakovlevIgA/forozon/auth"
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/feed"
 	"github.com/YakovlevIgA/forozon/graph"
+	"github.com/YakovlevIgA/forozon/graph/idempotency"
+	"github.com/YakovlevIgA/forozon/graph/persisted"
+	"github.com/YakovlevIgA/forozon/logging"
+	"github.com/YakovlevIgA/forozon/metrics"
+	"github.com/YakovlevIgA/forozon/sticky"
+	"github.com/YakovlevIgA/forozon/storagemw"
+	"github.com/YakovlevIgA/forozon/tracing"
+	"github.com/YakovlevIgA/forozon/transfer"
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/vektah/gqlparser/v2/ast"
 )
 
 const defaultPort = "8080"
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	err := godotenv.Load("go.env")
 	if err != nil {
-		log.Fatalf("Some error occured. Err: %s", err)
+		fatal("failed to load go.env", slog.Any("error", err))
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
+	cfg := loadConfig()
+
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.LogLevel)
+	logging.SetRedaction(cfg.LogRedactContent)
+	// Команды пишут журнал в stderr: stdout занят результатом, например выгрузкой
+	logOutput := os.Stdout
+	if len(os.Args) > 1 {
+		logOutput = os.Stderr
+	}
+	logger := logging.New(logOutput, logLevel)
+	slog.SetDefault(logger)
+
+	if len(os.Args) > 1 {
+		runCommand(ctx, cfg, os.Args[1], os.Args[2:])
+		return
 	}
 
-	storageType := os.Getenv("STORAGE")
-	var storage graph.Storage
+	appMetrics := metrics.New(prometheus.DefaultRegisterer)
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		ServiceName: "forozon",
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		fatal("failed to set up tracing", slog.Any("error", err))
+	}
+
+	var storage domain.Storage
+	var pool *pgxpool.Pool
+	var check healthCheck
+	var stickyWindow time.Duration
+	var backend string
+	var classify func(error) storagemw.Transient
+	closers := []closer{}
 
 	// Инициализация репозитория нужного типа
-	switch storageType {
+	switch cfg.Storage {
 	case "postgres":
-		storage = initPG(ctx)
-		log.Println("Используется postgres хранилище")
+		var repo *repository.PostgresRepository
+		var replicas []*pgxpool.Pool
+		var migrationVersion uint
+		repo, pool, replicas, migrationVersion = initPG(ctx, cfg.PostgresReplicaURLs)
+		storage = repo
+		closers = append(closers, closer{"postgres", closePool(pool)})
+		for i, replica := range replicas {
+			closers = append(closers, closer{fmt.Sprintf("postgres replica %d", i), closePool(replica)})
+		}
+		if len(replicas) > 0 {
+			go repo.WatchReplicas(ctx, cfg.PostgresReplicaCheckInterval)
+			stickyWindow = cfg.ReadYourWritesWindow
+		}
+		check = postgresHealthCheck(pool, migrationVersion)
+		appMetrics.RegisterPool(pool)
+		backend, classify = "postgres", storagemw.PostgresTransient
+		logger.Info("using postgres storage", slog.Int("replicas", len(replicas)))
+	case "sqlite":
+		var db *sql.DB
+		var migrationVersion uint
+		storage, db, migrationVersion = initSQLite(ctx, cfg.SQLitePath)
+		closers = append(closers, closer{"sqlite", func(context.Context) error { return db.Close() }})
+		check = sqliteHealthCheck(db, migrationVersion)
+		backend, classify = "sqlite", storagemw.SQLiteTransient
+		logger.Info("using sqlite storage", slog.String("path", cfg.SQLitePath))
 	default:
-		storage = repository.NewInMemoryRepository()
-		log.Println("Используется in-memory хранилище")
+		repo := initInMemory(ctx, cfg)
+		closers = append(closers, closer{"inmemory", repo.Close})
+		storage, backend = repo, "inmemory"
+		logger.Info("using in-memory storage", slog.Bool("durable", cfg.InMemoryDataDir != ""))
 	}
 
+	storage = storagemw.Chain(storage, storageMiddlewares(cfg, appMetrics, backend, classify)...)
+
+	go purgeDeleted(ctx, storage, cfg.DeletedRetention, cfg.PurgeInterval)
+
 	// Инициализация сервиса
 	resolver := graph.NewResolver(storage)
+	resolver.Idempotency = newIdempotencyGuard(ctx, cfg, pool)
+	resolver.ErasureMode = domain.ErasureMode(strings.ToUpper(cfg.ErasureMode))
 
 	// Инициализация GraphQL сервера и playground для него
-	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	srv := newGraphQLServer(ctx, cfg, resolver, appMetrics, pool)
+
+	subscriptions := newSubscriptionTracker()
+
+	health := newHealthChecker(check, cfg.ReadinessCheckInterval, cfg.ReadinessFailureThreshold)
+	go health.run(ctx)
+
+	adminTokens := auth.ParseTokens(cfg.AdminTokens)
+
+	mux := http.NewServeMux()
+	mux.Handle("/query", queryHandler(logger, adminTokens, subscriptions, stickyWindow, srv))
+	if ide := ideHandler(cfg.IDE, "/query"); ide != nil {
+		if cfg.IDEAccess == "admin" {
+			ide = adminTokens.RequireAdmin(ide)
+		}
+		mux.Handle(cfg.IDEPath, ide)
+	}
+	mux.HandleFunc("/healthz", health.healthzHandler)
+	mux.HandleFunc("/readyz", health.readyzHandler)
+	mux.HandleFunc("/version", versionHandler)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/debug/loglevel", adminTokens.RequireAdmin(logging.LevelHandler(logLevel)))
+	mux.Handle("/feeds/", logging.Middleware(logger, tracing.Middleware(feed.Handler(storage, feedConfig(cfg)))))
+	mux.Handle("/admin/export", logging.Middleware(logger, tracing.Middleware(adminTokens.RequireAdmin(transfer.ExportHandler(storage)))))
+
+	httpServer := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           mux,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening",
+			slog.String("addr", httpServer.Addr),
+			slog.Bool("tls", cfg.TLSEnabled()),
+			slog.String("env", cfg.Env),
+			slog.String("ide", cfg.IDE),
+			slog.String("ide_path", cfg.IDEPath),
+		)
+
+		if cfg.TLSEnabled() {
+			serveErr <- httpServer.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+			return
+		}
+
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fatal("http server failed", slog.Any("error", err))
+		}
+	case <-ctx.Done():
+		logger.Info("shutdown signal received")
+	}
 
+	health.setShuttingDown()
+	if cfg.ShutdownDrain > 0 {
+		logger.Info("draining before shutdown", slog.Duration("delay", cfg.ShutdownDrain))
+		time.Sleep(cfg.ShutdownDrain)
+	}
+
+	closers = append(closers, closer{"tracing", shutdownTracing})
+	shutdown(httpServer, subscriptions, closers, cfg.ShutdownTimeout)
+}
+
+// newGraphQLServer GraphQL сервер со всеми транспортами и расширениями
+func newGraphQLServer(ctx context.Context, cfg config, resolver *graph.Resolver, appMetrics *metrics.Metrics, pool *pgxpool.Pool) *handler.Server {
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  resolver,
+		Complexity: graph.NewComplexity(),
+	}))
+
+	srv.AddTransport(transport.Websocket{KeepAlivePingInterval: 10 * time.Second})
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
 
-	srv.Use(extension.Introspection{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](cfg.QueryCacheSize))
+	srv.SetErrorPresenter(presentError)
+	srv.SetRecoverFunc(recoverPanic)
+
+	switch cfg.Introspection {
+	case "on":
+		srv.Use(extension.Introspection{})
+	case "admin":
+		srv.Use(auth.AdminIntrospection{})
+	}
+	manifest := loadTrustedDocuments(cfg)
+	usePersistedQueries(ctx, srv, cfg, pool, manifest)
+	srv.Use(graph.QueryLimits{MaxDepth: cfg.MaxDepth, MaxAliases: cfg.MaxAliases})
+	srv.Use(extension.FixedComplexityLimit(cfg.MaxComplexity))
+	srv.Use(appMetrics.Tracer(append(manifest.OperationNames(), cfg.MetricsOperations...)...))
+	srv.Use(tracing.NewTracer())
+	srv.Use(sticky.Mutations{})
+
+	return srv
+}
+
+// storageMiddlewares декораторы хранилища от внешнего к внутреннему. Метрики и трассировка
+// видят и попадания в кэш; breaker считает сбоем вызов, не удавшийся после всех повторов.
+// classify nil отключает повторы (in-memory хранилищу нечего повторять).
+func storageMiddlewares(cfg config, appMetrics *metrics.Metrics, backend string, classify func(error) storagemw.Transient) []storagemw.Middleware {
+	middlewares := []storagemw.Middleware{
+		func(next domain.Storage) domain.Storage { return appMetrics.WrapStorage(next, backend) },
+		func(next domain.Storage) domain.Storage { return tracing.WrapStorage(next) },
+	}
+
+	if cfg.StorageCacheSize > 0 {
+		middlewares = append(middlewares, storagemw.Cache(storagemw.CacheOptions{
+			Size:          cfg.StorageCacheSize,
+			TTL:           cfg.StorageCacheTTL,
+			PrimaryWindow: cfg.ReadYourWritesWindow,
+		}))
+	}
+
+	middlewares = append(middlewares, storagemw.Timeout(storagemw.Timeouts{
+		Default:   cfg.StorageTimeout,
+		PerMethod: cfg.StorageTimeouts,
+	}))
+
+	if cfg.StorageBreakerThreshold > 0 {
+		middlewares = append(middlewares, storagemw.Breaker(storagemw.BreakerOptions{
+			Threshold: cfg.StorageBreakerThreshold,
+			Cooldown:  cfg.StorageBreakerCooldown,
+			OnStateChange: func(from, to storagemw.BreakerState) {
+				slog.Warn("storage circuit breaker state changed",
+					slog.String("backend", backend),
+					slog.String("from", string(from)),
+					slog.String("to", string(to)),
+				)
+			},
+		}))
+	}
+
+	if classify != nil && cfg.StorageRetryAttempts > 1 {
+		middlewares = append(middlewares, storagemw.Retry(storagemw.RetryOptions{
+			Attempts:  cfg.StorageRetryAttempts,
+			BaseDelay: cfg.StorageRetryBaseDelay,
+			MaxDelay:  cfg.StorageRetryMaxDelay,
+			Classify:  classify,
+		}))
+	}
+
+	return middlewares
+}
+
+// queryHandler обработчик /query: GraphQL сервер за логированием, трассировкой,
+// админ-токенами, идемпотентностью, привязкой чтений к основному серверу после
+// изменений (stickyWindow > 0) и учетом подписок
+func queryHandler(logger *slog.Logger, adminTokens auth.Tokens, subscriptions *subscriptionTracker, stickyWindow time.Duration, srv http.Handler) http.Handler {
+	return logging.Middleware(logger, tracing.Middleware(adminTokens.Middleware(idempotency.Middleware(sticky.Middleware(stickyWindow, subscriptions.wrap(srv))))))
+}
+
+// feedConfig параметры RSS/Atom лент
+func feedConfig(cfg config) feed.Config {
+	return feed.Config{
+		Title:    cfg.FeedTitle,
+		BaseURL:  cfg.FeedBaseURL,
+		PostURL:  cfg.FeedPostURL,
+		Limit:    cfg.FeedLimit,
+		MaxLimit: cfg.FeedMaxLimit,
+		Authors:  cfg.FeedAuthors,
+	}
+}
+
+// ideHandler страница GraphQL IDE; nil, если IDE отключена
+func ideHandler(ide, endpoint string) http.Handler {
+	switch ide {
+	case "graphiql":
+		return playground.Handler("forozon GraphiQL", endpoint)
+	case "apollo-sandbox":
+		return playground.ApolloSandboxHandler("forozon Apollo Sandbox", endpoint)
+	case "altair":
+		return playground.AltairHandler("forozon Altair", endpoint, nil)
+	default:
+		return nil
+	}
+}
+
+// loadTrustedDocuments манифест доверенных документов; nil, если он не задан
+func loadTrustedDocuments(cfg config) persisted.Manifest {
+	if cfg.TrustedDocumentsFile == "" {
+		return nil
+	}
+
+	manifest, err := persisted.LoadManifest(cfg.TrustedDocumentsFile)
+	if err != nil {
+		fatal("failed to load trusted documents", slog.Any("error", err))
+	}
+	slog.Info("trusted documents loaded", slog.Int("count", len(manifest)), slog.Bool("strict", cfg.TrustedDocumentsStrict))
+
+	return manifest
+}
+
+// usePersistedQueries включает APQ и/или строгий режим доверенных документов
+func usePersistedQueries(ctx context.Context, srv *handler.Server, cfg config, pool *pgxpool.Pool, manifest persisted.Manifest) {
+	// В строгом режиме документы берутся только из манифеста, APQ не нужен
+	if cfg.TrustedDocumentsStrict {
+		if manifest == nil {
+			fatal("TRUSTED_DOCUMENTS_STRICT requires TRUSTED_DOCUMENTS_FILE")
+		}
+		srv.Use(persisted.TrustedDocuments{Manifest: manifest})
+		return
+	}
+
+	if !cfg.APQEnabled {
+		return
+	}
+
+	var store graphql.Cache[string] = lru.New[string](cfg.APQCacheSize)
+	switch cfg.APQStore {
+	case "memory":
+	case "postgres":
+		if pool == nil {
+			fatal("GRAPHQL_APQ_STORE=postgres requires STORAGE=postgres")
+		}
+		pgCache := persisted.NewPostgresCache(pool, store)
+		go purgePersistedQueries(ctx, pgCache, cfg.APQTTL, cfg.APQMaxStored)
+		store = pgCache
+	default:
+		fatal("unknown APQ store", slog.String("store", cfg.APQStore))
+	}
+
+	// Документы из манифеста доступны по хэшу и без строгого режима
+	srv.Use(extension.AutomaticPersistedQuery{Cache: persisted.APQCache{
+		Manifest:      manifest,
+		Store:         store,
+		MaxQueryBytes: cfg.APQMaxQueryBytes,
+	}})
+}
+
+// purgePersistedQueries периодически вытесняет старые APQ из postgres
+func purgePersistedQueries(ctx context.Context, cache *persisted.PostgresCache, ttl time.Duration, maxStored int) {
+	ticker := time.NewTicker(min(ttl, time.Hour))
+	defer ticker.Stop()
 
-	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 
-	log.Printf("Подключитесь к http://localhost:%s/ для GraphQL Playground", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+		n, err := cache.Purge(ctx, ttl, maxStored)
+		if err != nil {
+			slog.Warn("failed to purge persisted queries", slog.Any("error", err))
+			continue
+		}
+		slog.Debug("purged persisted queries", slog.Int64("count", n))
+	}
+}
+
+// newIdempotencyGuard хранилище ключей идемпотентности; nil, если защита отключена
+func newIdempotencyGuard(ctx context.Context, cfg config, pool *pgxpool.Pool) *idempotency.Guard {
+	switch cfg.IdempotencyStore {
+	case "memory":
+		return &idempotency.Guard{Store: idempotency.NewMemoryStore(), TTL: cfg.IdempotencyTTL, Lease: cfg.IdempotencyLease}
+	case "postgres":
+		if pool == nil {
+			fatal("IDEMPOTENCY_STORE=postgres requires STORAGE=postgres")
+		}
+		store := idempotency.NewPostgresStore(pool)
+		go purgeIdempotencyKeys(ctx, store, cfg.IdempotencyTTL)
+		return &idempotency.Guard{Store: store, TTL: cfg.IdempotencyTTL, Lease: cfg.IdempotencyLease}
+	default:
+		return nil
+	}
+}
+
+// purgeIdempotencyKeys периодически удаляет истекшие ключи из postgres
+func purgeIdempotencyKeys(ctx context.Context, store *idempotency.PostgresStore, ttl time.Duration) {
+	ticker := time.NewTicker(min(ttl, time.Hour))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := store.Purge(ctx)
+		if err != nil {
+			slog.Warn("failed to purge idempotency keys", slog.Any("error", err))
+			continue
+		}
+		slog.Debug("purged idempotency keys", slog.Int64("count", n))
+	}
+}
+
+// purgeDeleted периодически окончательно удаляет записи, удаленные раньше срока хранения
+func purgeDeleted(ctx context.Context, storage domain.Storage, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := storage.PurgeDeleted(ctx, time.Now().Add(-retention)); err != nil {
+			slog.Warn("failed to purge deleted records", slog.Any("error", err))
+		}
+	}
 }
 
-// initPG инициализация postgres
-func initPG(ctx context.Context) graph.Storage {
+// snapshotInMemory периодически записывает снимок in-memory хранилища и сокращает журнал
+func snapshotInMemory(ctx context.Context, repo *repository.InMemoryRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := repo.Snapshot(ctx); err != nil {
+			slog.Warn("failed to write in-memory snapshot", slog.Any("error", err))
+		}
+	}
+}
+
+// closer ресурс, который освобождается при остановке сервера
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// shutdown корректно останавливает сервер: перестает принимать запросы,
+// дожидается текущих, закрывает подписки, а затем ресурсы в порядке closers
+func shutdown(httpServer *http.Server, subscriptions *subscriptionTracker, closers []closer, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	subscriptions.closeAll()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		slog.Warn("http server did not stop in time", slog.Any("error", err))
+	}
+
+	if err := subscriptions.wait(ctx); err != nil {
+		slog.Warn("subscriptions did not close in time", slog.Any("error", err))
+	}
+
+	for _, c := range closers {
+		if err := c.close(ctx); err != nil {
+			slog.Warn("failed to close resource", slog.String("resource", c.name), slog.Any("error", err))
+		}
+	}
+
+	slog.Info("server stopped")
+}
+
+// initInMemory инициализация in-memory хранилища; с INMEMORY_DATA_DIR
+// изменения пишутся в журнал и переживают перезапуск
+func initInMemory(ctx context.Context, cfg config) *repository.InMemoryRepository {
+	if cfg.InMemoryDataDir == "" {
+		return repository.NewInMemoryRepository()
+	}
+
+	repo, err := repository.NewDurableInMemoryRepository(ctx, cfg.InMemoryDataDir, repository.DurabilityOptions{
+		Fsync:         repository.FsyncPolicy(cfg.InMemoryFsync),
+		FsyncInterval: cfg.InMemoryFsyncInterval,
+	})
+	if err != nil {
+		fatal("failed to open in-memory data dir", slog.String("dir", cfg.InMemoryDataDir), slog.Any("error", err))
+	}
+
+	go snapshotInMemory(ctx, repo, cfg.InMemorySnapshotInterval)
+
+	return repo
+}
+
+// initPG инициализация postgres и реплик для чтения
+func initPG(ctx context.Context, replicaURLs []string) (*repository.PostgresRepository, *pgxpool.Pool, []*pgxpool.Pool, uint) {
 	connStr := os.Getenv("POSTGRES_URL")
 
 	if connStr == "" {
-		log.Fatal("POSTGRES_URL не указан")
+		fatal("POSTGRES_URL is not set")
 	}
 
-	conn, err := pgx.Connect(ctx, connStr)
+	pool, err := pgxpool.Connect(ctx, connStr)
 	if err != nil {
-		log.Fatalf("не удалось подключиться к базе данных: %v", err)
+		fatal("failed to connect to postgres", slog.Any("error", err))
 	}
 
-	if err := conn.Ping(ctx); err != nil {
-		log.Fatalf("не удалось подключиться к базе данных: %v", err)
+	if err := pool.Ping(ctx); err != nil {
+		fatal("failed to connect to postgres", slog.Any("error", err))
 	}
 
-	log.Println("Подключились к postgres")
+	slog.Info("connected to postgres")
+
+	// Реплики подключаются лениво: недоступная при запуске реплика не мешает
+	// старту, чтения с нее уйдут на основной сервер
+	replicas := make([]*pgxpool.Pool, 0, len(replicaURLs))
+	for i, url := range replicaURLs {
+		replicaCfg, err := pgxpool.ParseConfig(url)
+		if err != nil {
+			fatal("invalid postgres replica url", slog.Int("replica", i), slog.Any("error", err))
+		}
+		replicaCfg.LazyConnect = true
 
-	if err := runMigrations(); err != nil {
-		log.Fatalf("Не удалось применить миграции: %v", err)
+		replica, err := pgxpool.ConnectConfig(ctx, replicaCfg)
+		if err != nil {
+			fatal("failed to connect to postgres replica", slog.Int("replica", i), slog.Any("error", err))
+		}
+		replicas = append(replicas, replica)
 	}
 
-	log.Println("Миграции успешно выполнены")
+	version, err := runMigrations()
+	if err != nil {
+		fatal("failed to apply migrations", slog.Any("error", err))
+	}
+
+	slog.Info("migrations applied", slog.Uint64("version", uint64(version)))
 
-	storage, err := repository.NewPostgresRepository(conn)
+	repo, err := repository.NewPostgresRepository(pool, replicas...)
 	if err != nil {
-		log.Fatalf("Ошибка инициализации PostgreSQL: %v", err)
+		fatal("failed to init postgres repository", slog.Any("error", err))
 	}
 
-	return storage
+	return repo, pool, replicas, version
 }
 
-// runMigrations применение миграций к postgres
-func runMigrations() error {
+// initSQLite открывает файл sqlite и применяет миграции из migrations/sqlite
+func initSQLite(ctx context.Context, path string) (domain.Storage, *sql.DB, uint) {
+	db, err := repository.OpenSQLite(ctx, path)
+	if err != nil {
+		fatal("failed to open sqlite", slog.String("path", path), slog.Any("error", err))
+	}
+
+	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		fatal("failed to apply migrations", slog.Any("error", err))
+	}
+
+	version, err := applyMigrations("./migrations/sqlite", "sqlite", driver)
+	if err != nil {
+		fatal("failed to apply migrations", slog.Any("error", err))
+	}
+
+	slog.Info("migrations applied", slog.Uint64("version", uint64(version)))
+
+	storage, err := repository.NewSQLiteRepository(db)
+	if err != nil {
+		fatal("failed to init sqlite repository", slog.Any("error", err))
+	}
+
+	return storage, db, version
+}
+
+// closePool закрывает пул соединений, не дольше дедлайна контекста
+func closePool(pool *pgxpool.Pool) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			pool.Close()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// postgresHealthCheck проверяет доступность postgres и что схема не отстает от миграций
+func postgresHealthCheck(pool *pgxpool.Pool, migrationVersion uint) healthCheck {
+	return func(ctx context.Context) error {
+		if err := pool.Ping(ctx); err != nil {
+			return fmt.Errorf("ping: %w", err)
+		}
+
+		var version uint
+		var dirty bool
+		err := pool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if err != nil {
+			return fmt.Errorf("migration version: %w", err)
+		}
+
+		return checkSchemaVersion(version, dirty, migrationVersion)
+	}
+}
+
+// sqliteHealthCheck проверяет, что файл sqlite доступен и схема не отстает от миграций
+func sqliteHealthCheck(db *sql.DB, migrationVersion uint) healthCheck {
+	return func(ctx context.Context) error {
+		var version uint
+		var dirty bool
+		err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if err != nil {
+			return fmt.Errorf("migration version: %w", err)
+		}
+
+		return checkSchemaVersion(version, dirty, migrationVersion)
+	}
+}
+
+// checkSchemaVersion ошибка, если миграция не завершилась или схема старше ожидаемой
+func checkSchemaVersion(version uint, dirty bool, expected uint) error {
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+
+	if version < expected {
+		return fmt.Errorf("schema version %d is behind expected %d", version, expected)
+	}
+
+	return nil
+}
+
+// runMigrations применение миграций к postgres, возвращает текущую версию схемы
+func runMigrations() (uint, error) {
 	db, err := sql.Open("postgres", os.Getenv("POSTGRES_URL"))
 	if err != nil {
-		log.Fatalf("Ошибка при подключении к базе данных: %v", err)
+		return 0, err
 	}
+	defer db.Close()
 
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	absPath, err := filepath.Abs("./migrations")
+	return applyMigrations("./migrations", os.Getenv("POSTGRES_DB"), driver)
+}
+
+// applyMigrations применяет миграции из каталога dir, возвращает текущую версию схемы
+func applyMigrations(dir, dbName string, driver database.Driver) (uint, error) {
+	absPath, err := filepath.Abs(dir)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	m, err := migrate.NewWithDatabaseInstance(
 		fmt.Sprintf("file://%s", filepath.ToSlash(absPath)),
-		os.Getenv("POSTGRES_DB"),
+		dbName,
 		driver,
 	)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return err
+		return 0, err
 	}
 
-	return nil
+	version, _, err := m.Version()
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// latestMigration номер последней миграции в каталоге
+func latestMigration(dir string) (uint, error) {
+	absPath, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	src, err := source.Open(fmt.Sprintf("file://%s", filepath.ToSlash(absPath)))
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+// fatal пишет ошибку в лог и завершает процесс
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
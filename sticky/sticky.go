@@ -0,0 +1,101 @@
+// Package sticky read-your-writes при чтении с реплик: после мутации клиент получает
+// cookie, и пока она действует, его чтения идут на основной сервер
+package sticky
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Cookie cookie со временем (unix ms), до которого чтения клиента идут на основной сервер
+const Cookie = "read_primary_until"
+
+type stateKey struct{}
+
+// state ответ текущего запроса, в который мутация ставит cookie
+type state struct {
+	w      http.ResponseWriter
+	window time.Duration
+}
+
+// Middleware помечает контекст запроса, если cookie клиента еще действует,
+// и дает Mutations поставить ее в ответ. window <= 0 отключает привязку.
+func Middleware(window time.Duration, next http.Handler) http.Handler {
+	if window <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), stateKey{}, &state{w: w, window: window})
+		if active(r, time.Now()) {
+			ctx = domain.WithReadYourWrites(ctx)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// active не истек ли срок из cookie. Срок проверяется и на сервере: клиент
+// без поддержки Max-Age может прислать cookie позже
+func active(r *http.Request, now time.Time) bool {
+	c, err := r.Cookie(Cookie)
+	if err != nil {
+		return false
+	}
+
+	until, err := strconv.ParseInt(c.Value, 10, 64)
+	return err == nil && now.UnixMilli() < until
+}
+
+// markWritten ставит cookie в ответ текущего запроса
+func markWritten(ctx context.Context) {
+	s, ok := ctx.Value(stateKey{}).(*state)
+	if !ok {
+		return
+	}
+
+	until := time.Now().Add(s.window)
+	http.SetCookie(s.w, &http.Cookie{
+		Name:     Cookie,
+		Value:    strconv.FormatInt(until.UnixMilli(), 10),
+		Path:     "/",
+		Expires:  until,
+		MaxAge:   int(s.window.Round(time.Second) / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Mutations ставит cookie после каждой мутации. Заголовки ответа POST и GET
+// еще не отправлены, когда выполняется операция; по WebSocket cookie не ставится.
+type Mutations struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+} = Mutations{}
+
+// ExtensionName имя расширения
+func (Mutations) ExtensionName() string {
+	return "ReadYourWrites"
+}
+
+// Validate проверка схемы не требуется
+func (Mutations) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation ставит cookie перед выполнением мутации; чтения внутри самой
+// мутации тоже идут на основной сервер
+func (Mutations) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	if op := graphql.GetOperationContext(ctx).Operation; op != nil && op.Operation == ast.Mutation {
+		markWritten(ctx)
+		ctx = domain.WithReadYourWrites(ctx)
+	}
+	return next(ctx)
+}
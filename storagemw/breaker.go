@@ -0,0 +1,145 @@
+package storagemw
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+// ErrCircuitOpen хранилище временно не вызывается после серии ошибок
+var ErrCircuitOpen = errors.New("storage circuit breaker is open")
+
+// BreakerOptions параметры circuit breaker
+type BreakerOptions struct {
+	// Threshold сколько ошибок подряд размыкают цепь
+	Threshold int
+	// Cooldown сколько цепь остается разомкнутой до пробного вызова
+	Cooldown time.Duration
+	// OnStateChange вызывается при смене состояния (для логов)
+	OnStateChange func(from, to BreakerState)
+}
+
+// BreakerState состояние circuit breaker
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// breaker после Threshold ошибок подряд отвечает ErrCircuitOpen, не вызывая хранилище.
+// Через Cooldown пропускается один пробный вызов: успех замыкает цепь, ошибка снова размыкает.
+type breaker struct {
+	opts BreakerOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// Breaker circuit breaker для хранилища. Доменные ошибки и отмена запроса клиентом
+// не считаются сбоями: хранилище на них ответило.
+func Breaker(opts BreakerOptions) Middleware {
+	b := &breaker{opts: opts, now: time.Now, state: BreakerClosed}
+	return Intercept(b.intercept)
+}
+
+// intercept пропускает вызов, если цепь замкнута или пришло время пробного вызова
+func (b *breaker) intercept(ctx context.Context, _ Op, call func(ctx context.Context) error) error {
+	probe, ok := b.allow()
+	if !ok {
+		return ErrCircuitOpen
+	}
+
+	err := call(ctx)
+	b.record(probe, isFailure(ctx, err))
+
+	return err
+}
+
+// allow можно ли выполнить вызов; probe — это пробный вызов в состоянии half-open
+func (b *breaker) allow() (probe bool, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.opts.Cooldown {
+			return false, false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return true, true
+	case BreakerHalfOpen:
+		// Пока идет пробный вызов, остальные получают отказ
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	default:
+		return false, true
+	}
+}
+
+// record учитывает результат вызова
+func (b *breaker) record(probe, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+
+	if !failed {
+		b.failures = 0
+		if probe {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if probe || (b.state == BreakerClosed && b.failures >= b.opts.Threshold) {
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// setState меняет состояние и сообщает об этом; вызывается под mu
+func (b *breaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	if b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, state)
+	}
+}
+
+// isFailure признак сбоя хранилища
+func isFailure(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return false
+	}
+
+	// Запрос отменил клиент, а не хранилище не ответило
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,104 @@
+package storagemw
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+// newTestBreaker breaker с управляемыми часами
+func newTestBreaker(next domain.Storage, threshold int) (domain.Storage, *time.Time, *[]BreakerState) {
+	now := time.Unix(0, 0)
+	var states []BreakerState
+	b := &breaker{
+		opts: BreakerOptions{
+			Threshold:     threshold,
+			Cooldown:      time.Minute,
+			OnStateChange: func(_, to BreakerState) { states = append(states, to) },
+		},
+		now:   func() time.Time { return now },
+		state: BreakerClosed,
+	}
+	return Intercept(b.intercept)(next), &now, &states
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeStorage()
+	s, now, states := newTestBreaker(fake, 2)
+
+	fake.failNext("GetPostByID", errBackend, errBackend)
+	for range 2 {
+		if _, err := s.GetPostByID(ctx, "p1"); !errors.Is(err, errBackend) {
+			t.Fatalf("err = %v, want backend error", err)
+		}
+	}
+
+	// Цепь разомкнута: хранилище не вызывается
+	if _, err := s.GetPostByID(ctx, "p1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if n := fake.count("GetPostByID"); n != 2 {
+		t.Fatalf("GetPostByID called %d times while open, want 2", n)
+	}
+
+	// После паузы пробный вызов; его успех замыкает цепь
+	*now = now.Add(time.Minute)
+	if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+		t.Fatalf("after recovery: %v", err)
+	}
+
+	want := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	if len(*states) != len(want) {
+		t.Fatalf("states = %v, want %v", *states, want)
+	}
+	for i := range want {
+		if (*states)[i] != want[i] {
+			t.Fatalf("states = %v, want %v", *states, want)
+		}
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeStorage()
+	s, now, _ := newTestBreaker(fake, 1)
+
+	fake.failNext("GetPostByID", errBackend, errBackend)
+	_, _ = s.GetPostByID(ctx, "p1")
+
+	*now = now.Add(time.Minute)
+	if _, err := s.GetPostByID(ctx, "p1"); !errors.Is(err, errBackend) {
+		t.Fatalf("probe err = %v, want backend error", err)
+	}
+	if _, err := s.GetPostByID(ctx, "p1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen after failed probe", err)
+	}
+}
+
+func TestBreakerIgnoresExpectedErrors(t *testing.T) {
+	fake := newFakeStorage()
+	s, _, _ := newTestBreaker(fake, 2)
+
+	fake.failNext("GetPostByID", domain.NotFound("post", "p1"), domain.NotFound("post", "p2"))
+	for range 2 {
+		_, _ = s.GetPostByID(context.Background(), "p1")
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	fake.failNext("GetPostByID", context.Canceled, context.Canceled)
+	for range 2 {
+		_, _ = s.GetPostByID(canceled, "p1")
+	}
+
+	if _, err := s.GetPostByID(context.Background(), "p1"); err != nil {
+		t.Fatalf("err = %v, want closed breaker", err)
+	}
+}
@@ -0,0 +1,250 @@
+package storagemw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// CacheOptions параметры кэша чтения
+type CacheOptions struct {
+	// Size сколько ответов хранить
+	Size int
+	// TTL сколько хранится ответ; ограничивает устаревание, если пишет другой экземпляр сервиса
+	TTL time.Duration
+	// PrimaryWindow сколько после сброса кэша поста промахи по нему читаются с основного
+	// сервера: реплика может еще не получить изменение, а ответ хранится до TTL
+	PrimaryWindow time.Duration
+}
+
+// cacheStripes число групп постов для инвалидации; запись в пост сбрасывает кэш
+// примерно 1/cacheStripes постов, зато память на учет не растет с числом постов
+const cacheStripes = 256
+
+// Cache read-through кэш GetPostByID и GetCommentsForPost с инвалидацией при изменениях.
+// Закэшированные значения общие для всех запросов, поэтому вызывающий не должен их изменять.
+// Чтения с domain.ReadYourWrites не берут значение из кэша: его могли прочитать с отстающей
+// реплики, — но сохраняют свой свежий результат. В течение PrimaryWindow после сброса
+// промахи тоже читают с основного сервера, чтобы не сохранить отставшее значение с реплики.
+func Cache(opts CacheOptions) Middleware {
+	return func(next domain.Storage) domain.Storage {
+		return &cache{
+			Storage: next,
+			entries: expirable.NewLRU[string, any](opts.Size, nil, opts.TTL),
+			window:  opts.PrimaryWindow,
+		}
+	}
+}
+
+// cache кэширующее хранилище; методы без кэша проходят напрямую во встроенное хранилище.
+// Ключ включает эпохи: инвалидация увеличивает эпоху, и старые записи больше не находятся.
+// Чтение, начатое до изменения, сохранит результат под старой эпохой, поэтому устаревшее
+// значение не попадет в кэш.
+type cache struct {
+	domain.Storage
+	entries *expirable.LRU[string, any]
+	window  time.Duration
+
+	mu     sync.Mutex
+	epoch  uint64
+	stripe [cacheStripes]uint64
+	// primaryUntil до какого времени промахи читают с основного сервера, по группам и для всех
+	primaryUntil    [cacheStripes]time.Time
+	allPrimaryUntil time.Time
+}
+
+var _ domain.Storage = (*cache)(nil)
+
+// key ключ записи кэша поста postID с текущими эпохами
+func (c *cache) key(postID, kind string, args ...any) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return fmt.Sprintf("%d:%d:%s:%s:%v", c.epoch, c.stripe[stripeOf(postID)], kind, postID, args)
+}
+
+// miss контекст чтения при промахе: недавно сброшенный пост читается с основного сервера
+func (c *cache) miss(ctx context.Context, postID string) context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(c.primaryUntil[stripeOf(postID)]) || now.Before(c.allPrimaryUntil) {
+		return domain.WithReadYourWrites(ctx)
+	}
+	return ctx
+}
+
+// lookup значение из кэша, если чтению не нужны недавние изменения клиента
+func (c *cache) lookup(ctx context.Context, key string) (any, bool) {
+	if domain.ReadYourWrites(ctx) {
+		return nil, false
+	}
+	return c.entries.Get(key)
+}
+
+// invalidatePost сбрасывает записи поста postID
+func (c *cache) invalidatePost(postID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stripe := stripeOf(postID)
+	c.stripe[stripe]++
+	c.primaryUntil[stripe] = time.Now().Add(c.window)
+}
+
+// invalidateAll сбрасывает весь кэш
+func (c *cache) invalidateAll() {
+	c.mu.Lock()
+	c.epoch++
+	c.allPrimaryUntil = time.Now().Add(c.window)
+	c.mu.Unlock()
+
+	c.entries.Purge()
+}
+
+// written сбрасывает кэш после изменения поста postID. После внутренней ошибки
+// изменение могло примениться, поэтому кэш тоже сбрасывается; если пост неизвестен — весь.
+// Доменная ошибка означает, что ничего не изменилось.
+func (c *cache) written(postID string, err error) {
+	var domainErr *domain.Error
+	switch {
+	case errors.As(err, &domainErr):
+	case postID == "":
+		c.invalidateAll()
+	default:
+		c.invalidatePost(postID)
+	}
+}
+
+// commentPostID пост комментария или пустая строка, если комментария нет
+func commentPostID(comment *domain.Comment) string {
+	if comment == nil {
+		return ""
+	}
+	return comment.PostID
+}
+
+// stripeOf группа поста
+func stripeOf(postID string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(postID))
+	return int(h.Sum32() % cacheStripes)
+}
+
+// GetPostByID пост из кэша или хранилища
+func (c *cache) GetPostByID(ctx context.Context, id string) (*domain.Post, error) {
+	key := c.key(id, "post")
+	if v, ok := c.lookup(ctx, key); ok {
+		return v.(*domain.Post), nil
+	}
+
+	post, err := c.Storage.GetPostByID(c.miss(ctx, id), id)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries.Add(key, post)
+	return post, nil
+}
+
+// GetCommentsForPost страница комментариев из кэша или хранилища
+func (c *cache) GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*domain.CommentWithReplies, error) {
+	var after string
+	if cursor != nil {
+		after = *cursor
+	}
+
+	key := c.key(postID, "comments", limit, after)
+	if v, ok := c.lookup(ctx, key); ok {
+		return v.([]*domain.CommentWithReplies), nil
+	}
+
+	comments, err := c.Storage.GetCommentsForPost(c.miss(ctx, postID), postID, limit, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries.Add(key, comments)
+	return comments, nil
+}
+
+// AddComment добавление комментария сбрасывает кэш поста
+func (c *cache) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*domain.Comment, error) {
+	comment, err := c.Storage.AddComment(ctx, postID, parentID, authorID, content)
+	c.written(postID, err)
+	return comment, err
+}
+
+// UpdatePost изменение поста сбрасывает его кэш
+func (c *cache) UpdatePost(ctx context.Context, id string, expectedVersion int32, title, content *string, editorID string, reason *string) (*domain.Post, error) {
+	post, err := c.Storage.UpdatePost(ctx, id, expectedVersion, title, content, editorID, reason)
+	c.written(id, err)
+	return post, err
+}
+
+// UpdateComment изменение комментария сбрасывает кэш его поста
+func (c *cache) UpdateComment(ctx context.Context, id string, expectedVersion int32, content string, editorID string, reason *string) (*domain.Comment, error) {
+	comment, err := c.Storage.UpdateComment(ctx, id, expectedVersion, content, editorID, reason)
+	c.written(commentPostID(comment), err)
+	return comment, err
+}
+
+// DeletePost удаление поста сбрасывает его кэш
+func (c *cache) DeletePost(ctx context.Context, id, deletedBy string) (*domain.Post, error) {
+	post, err := c.Storage.DeletePost(ctx, id, deletedBy)
+	c.written(id, err)
+	return post, err
+}
+
+// DeleteComment удаление комментария сбрасывает кэш его поста
+func (c *cache) DeleteComment(ctx context.Context, id, deletedBy string) (*domain.Comment, error) {
+	comment, err := c.Storage.DeleteComment(ctx, id, deletedBy)
+	c.written(commentPostID(comment), err)
+	return comment, err
+}
+
+// RestorePost восстановление поста сбрасывает его кэш
+func (c *cache) RestorePost(ctx context.Context, id string) (*domain.Post, error) {
+	post, err := c.Storage.RestorePost(ctx, id)
+	c.written(id, err)
+	return post, err
+}
+
+// RestoreComment восстановление комментария сбрасывает кэш его поста
+func (c *cache) RestoreComment(ctx context.Context, id string) (*domain.Comment, error) {
+	comment, err := c.Storage.RestoreComment(ctx, id)
+	c.written(commentPostID(comment), err)
+	return comment, err
+}
+
+// PurgeDeleted затрагивает неизвестный набор постов, поэтому сбрасывает весь кэш
+func (c *cache) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	n, err := c.Storage.PurgeDeleted(ctx, before)
+	if n > 0 || err != nil {
+		c.invalidateAll()
+	}
+	return n, err
+}
+
+// EraseAuthor затрагивает все посты автора и его комментарии, поэтому сбрасывает весь кэш
+func (c *cache) EraseAuthor(ctx context.Context, authorID string, mode domain.ErasureMode) (*domain.ErasureResult, error) {
+	result, err := c.Storage.EraseAuthor(ctx, authorID, mode)
+	c.invalidateAll()
+	return result, err
+}
+
+// ImportRecords затрагивает неизвестный набор постов, поэтому сбрасывает весь кэш
+func (c *cache) ImportRecords(ctx context.Context, records []*domain.Record) (int64, error) {
+	n, err := c.Storage.ImportRecords(ctx, records)
+	if n > 0 || err != nil {
+		c.invalidateAll()
+	}
+	return n, err
+}
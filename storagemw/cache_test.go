@@ -0,0 +1,264 @@
+package storagemw
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+func newTestCache(next domain.Storage) domain.Storage {
+	return Cache(CacheOptions{Size: 100, TTL: time.Minute})(next)
+}
+
+func TestCacheReadThrough(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeStorage()
+	s := newTestCache(fake)
+
+	for range 3 {
+		if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+			t.Fatalf("GetPostByID: %v", err)
+		}
+		if _, err := s.GetCommentsForPost(ctx, "p1", 10, nil); err != nil {
+			t.Fatalf("GetCommentsForPost: %v", err)
+		}
+	}
+
+	if n := fake.count("GetPostByID"); n != 1 {
+		t.Errorf("GetPostByID reached storage %d times, want 1", n)
+	}
+	if n := fake.count("GetCommentsForPost"); n != 1 {
+		t.Errorf("GetCommentsForPost reached storage %d times, want 1", n)
+	}
+
+	// Другая страница — другой ключ
+	cursor := "c1"
+	if _, err := s.GetCommentsForPost(ctx, "p1", 10, &cursor); err != nil {
+		t.Fatalf("GetCommentsForPost: %v", err)
+	}
+	if n := fake.count("GetCommentsForPost"); n != 2 {
+		t.Errorf("GetCommentsForPost with cursor reached storage %d times, want 2", n)
+	}
+}
+
+func TestCacheDoesNotStoreErrors(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeStorage()
+	fake.failNext("GetPostByID", domain.NotFound("post", "p1"))
+	s := newTestCache(fake)
+
+	if _, err := s.GetPostByID(ctx, "p1"); err == nil {
+		t.Fatal("expected NOT_FOUND")
+	}
+	if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if n := fake.count("GetPostByID"); n != 2 {
+		t.Errorf("GetPostByID reached storage %d times, want 2", n)
+	}
+}
+
+func TestCacheReadYourWrites(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeStorage()
+	s := newTestCache(fake)
+
+	if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+
+	// Клиент после изменения читает мимо кэша, но его результат сохраняется
+	if _, err := s.GetPostByID(domain.WithReadYourWrites(ctx), "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if n := fake.count("GetPostByID"); n != 2 {
+		t.Errorf("GetPostByID with read-your-writes reached storage %d times, want 2", n)
+	}
+
+	if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if n := fake.count("GetPostByID"); n != 2 {
+		t.Errorf("GetPostByID after read-your-writes reached storage %d times, want 2", n)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	if stripeOf("p1") == stripeOf("p3") {
+		t.Fatal("p1 and p3 must fall into different stripes")
+	}
+
+	tests := []struct {
+		name string
+		// write изменение, после которого кэш поста p1 должен сброситься (или нет)
+		write       func(ctx context.Context, s domain.Storage, fake *fakeStorage)
+		invalidates bool
+	}{
+		{
+			name: "AddComment",
+			write: func(ctx context.Context, s domain.Storage, _ *fakeStorage) {
+				_, _ = s.AddComment(ctx, "p1", nil, "a1", "text")
+			},
+			invalidates: true,
+		},
+		{
+			name: "UpdateComment uses post of returned comment",
+			write: func(ctx context.Context, s domain.Storage, _ *fakeStorage) {
+				_, _ = s.UpdateComment(ctx, "c1", 1, "new", "a1", nil)
+			},
+			invalidates: true,
+		},
+		{
+			name: "EraseAuthor invalidates everything",
+			write: func(ctx context.Context, s domain.Storage, _ *fakeStorage) {
+				_, _ = s.EraseAuthor(ctx, "a1", domain.ErasureModeAnonymize)
+			},
+			invalidates: true,
+		},
+		{
+			name: "internal error may have applied the write",
+			write: func(ctx context.Context, s domain.Storage, fake *fakeStorage) {
+				fake.failNext("UpdateComment", errBackend)
+				_, _ = s.UpdateComment(ctx, "c1", 1, "new", "a1", nil)
+			},
+			invalidates: true,
+		},
+		{
+			name: "domain error changes nothing",
+			write: func(ctx context.Context, s domain.Storage, fake *fakeStorage) {
+				fake.failNext("AddComment", domain.CommentsDisabled("p1"))
+				_, _ = s.AddComment(ctx, "p1", nil, "a1", "text")
+			},
+			invalidates: false,
+		},
+		{
+			name: "other post",
+			write: func(ctx context.Context, s domain.Storage, _ *fakeStorage) {
+				_, _ = s.AddComment(ctx, "p3", nil, "a1", "text")
+			},
+			invalidates: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := newFakeStorage()
+			s := newTestCache(fake)
+
+			if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+				t.Fatalf("GetPostByID: %v", err)
+			}
+			tt.write(ctx, s, fake)
+			post, err := s.GetPostByID(ctx, "p1")
+			if err != nil {
+				t.Fatalf("GetPostByID: %v", err)
+			}
+
+			want := 1
+			if tt.invalidates {
+				want = 2
+			}
+			if n := fake.count("GetPostByID"); n != want {
+				t.Errorf("GetPostByID reached storage %d times, want %d", n, want)
+			}
+			// Версия в фейке — номер вызова: после сброса кэша приходит новое значение
+			if post.Version != int32(want) {
+				t.Errorf("post version = %d, want %d", post.Version, want)
+			}
+		})
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeStorage()
+	s := Cache(CacheOptions{Size: 100, TTL: 20 * time.Millisecond})(fake)
+
+	if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+
+	if n := fake.count("GetPostByID"); n != 2 {
+		t.Errorf("GetPostByID reached storage %d times, want 2 after TTL", n)
+	}
+}
+
+// laggingStorage хранилище с отстающей репликой: запись меняет только основной сервер,
+// и чтения без domain.ReadYourWrites видят старое значение
+type laggingStorage struct {
+	domain.Storage
+
+	mu               sync.Mutex
+	primary, replica string
+}
+
+func (l *laggingStorage) read(ctx context.Context) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if domain.ReadYourWrites(ctx) {
+		return l.primary
+	}
+	return l.replica
+}
+
+func (l *laggingStorage) GetPostByID(ctx context.Context, id string) (*domain.Post, error) {
+	return &domain.Post{ID: id, Title: l.read(ctx)}, nil
+}
+
+func (l *laggingStorage) GetCommentsForPost(ctx context.Context, postID string, _ int, _ *string) ([]*domain.CommentWithReplies, error) {
+	return []*domain.CommentWithReplies{{ID: "c1", PostID: postID, Content: l.read(ctx)}}, nil
+}
+
+func (l *laggingStorage) UpdatePost(_ context.Context, id string, _ int32, title, _ *string, _ string, _ *string) (*domain.Post, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.primary = *title
+	return &domain.Post{ID: id, Title: l.primary}, nil
+}
+
+func TestCacheReadsPrimaryAfterInvalidation(t *testing.T) {
+	ctx := context.Background()
+	lagging := &laggingStorage{primary: "old", replica: "old"}
+	s := Cache(CacheOptions{Size: 100, TTL: time.Minute, PrimaryWindow: time.Minute})(lagging)
+
+	if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if _, err := s.GetCommentsForPost(ctx, "p1", 10, nil); err != nil {
+		t.Fatalf("GetCommentsForPost: %v", err)
+	}
+
+	title := "new"
+	if _, err := s.UpdatePost(ctx, "p1", 1, &title, nil, "a1", nil); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+
+	// Второе чтение берет значение из кэша: в нем не должно остаться старого значения с реплики
+	for range 2 {
+		post, err := s.GetPostByID(ctx, "p1")
+		if err != nil {
+			t.Fatalf("GetPostByID: %v", err)
+		}
+		if post.Title != "new" {
+			t.Errorf("post title = %q, want %q", post.Title, "new")
+		}
+
+		comments, err := s.GetCommentsForPost(ctx, "p1", 10, nil)
+		if err != nil {
+			t.Fatalf("GetCommentsForPost: %v", err)
+		}
+		if comments[0].Content != "new" {
+			t.Errorf("comment content = %q, want %q", comments[0].Content, "new")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package storagemw
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Transient насколько безопасно повторить вызов после ошибки
+type Transient int
+
+const (
+	// NotTransient ошибка не исчезнет при повторе (или это доменная ошибка)
+	NotTransient Transient = iota
+	// TransientRead временная ошибка, но изменение могло примениться
+	// (например, соединение оборвалось после отправки запроса): повторяются только чтения
+	TransientRead
+	// TransientAny временная ошибка, изменение точно не применилось
+	// (конфликт сериализации, deadlock): повторять можно и запись
+	TransientAny
+)
+
+// RetryOptions параметры повторов
+type RetryOptions struct {
+	// Attempts сколько всего попыток, включая первую
+	Attempts int
+	// BaseDelay пауза перед первым повтором; дальше растет вдвое до MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Classify определяет, временная ли ошибка
+	Classify func(err error) Transient
+}
+
+// Retry повторяет вызовы, завершившиеся временной ошибкой, с экспоненциальной паузой и jitter
+func Retry(opts RetryOptions) Middleware {
+	return Intercept(func(ctx context.Context, op Op, call func(ctx context.Context) error) error {
+		var err error
+		for attempt := 1; ; attempt++ {
+			err = call(ctx)
+			if err == nil || attempt >= opts.Attempts || !retryable(opts.Classify(err), op) {
+				return err
+			}
+
+			timer := time.NewTimer(backoff(opts.BaseDelay, opts.MaxDelay, attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	})
+}
+
+// retryable можно ли повторить метод после ошибки такого вида
+func retryable(t Transient, op Op) bool {
+	switch t {
+	case TransientAny:
+		return true
+	case TransientRead:
+		return !op.Write
+	default:
+		return false
+	}
+}
+
+// backoff пауза перед повтором attempt: base*2^(attempt-1), не больше max, со случайным разбросом до половины
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	d := base << (attempt - 1)
+	if d <= 0 || d > max {
+		d = max
+	}
+	if half := int64(d / 2); half > 0 {
+		d = d/2 + time.Duration(rand.Int64N(half+1))
+	}
+	return d
+}
@@ -0,0 +1,101 @@
+package storagemw
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+var (
+	errTransientRead = errors.New("connection reset")
+	errTransientAny  = errors.New("serialization failure")
+)
+
+func classifyTest(err error) Transient {
+	switch {
+	case errors.Is(err, errTransientAny):
+		return TransientAny
+	case errors.Is(err, errTransientRead):
+		return TransientRead
+	default:
+		return NotTransient
+	}
+}
+
+func newTestRetry(next domain.Storage) domain.Storage {
+	return Retry(RetryOptions{
+		Attempts:  3,
+		BaseDelay: time.Millisecond,
+		MaxDelay:  5 * time.Millisecond,
+		Classify:  classifyTest,
+	})(next)
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "read recovers", method: "GetPostByID", errs: []error{errTransientRead, errTransientRead}, wantCalls: 3},
+		{name: "read gives up", method: "GetPostByID", errs: []error{errTransientRead, errTransientRead, errTransientRead}, wantCalls: 3, wantErr: errTransientRead},
+		{name: "write not retried after ambiguous error", method: "CreatePost", errs: []error{errTransientRead}, wantCalls: 1, wantErr: errTransientRead},
+		{name: "write retried when not applied", method: "CreatePost", errs: []error{errTransientAny}, wantCalls: 2},
+		{name: "permanent error", method: "GetPostByID", errs: []error{errBackend}, wantCalls: 1, wantErr: errBackend},
+		{name: "domain error", method: "GetPostByID", errs: []error{domain.NotFound("post", "p1")}, wantCalls: 1, wantErr: domain.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeStorage()
+			fake.failNext(tt.method, tt.errs...)
+			s := newTestRetry(fake)
+
+			var err error
+			switch tt.method {
+			case "GetPostByID":
+				_, err = s.GetPostByID(context.Background(), "p1")
+			case "CreatePost":
+				_, err = s.CreatePost(context.Background(), "t", "c", "a1", false)
+			}
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if n := fake.count(tt.method); n != tt.wantCalls {
+				t.Errorf("%s called %d times, want %d", tt.method, n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnContextCancel(t *testing.T) {
+	fake := newFakeStorage()
+	fake.failNext("GetPostByID", errTransientRead, errTransientRead)
+	s := Retry(RetryOptions{Attempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour, Classify: classifyTest})(fake)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := s.GetPostByID(ctx, "p1"); !errors.Is(err, errTransientRead) {
+		t.Fatalf("err = %v, want last storage error", err)
+	}
+	if n := fake.count("GetPostByID"); n != 1 {
+		t.Errorf("GetPostByID called %d times, want 1", n)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := backoff(10*time.Millisecond, 100*time.Millisecond, attempt)
+		upper := min(10*time.Millisecond<<(attempt-1), 100*time.Millisecond)
+		if d < upper/2 || d > upper {
+			t.Errorf("attempt %d: backoff %s not in [%s, %s]", attempt, d, upper/2, upper)
+		}
+	}
+}
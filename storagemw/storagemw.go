@@ -0,0 +1,250 @@
+// Package storagemw декораторы domain.Storage, которые собираются в цепочку при запуске:
+// кэш чтения, повтор при временных ошибках, таймауты и circuit breaker
+package storagemw
+
+import (
+	"context"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+// Middleware декоратор хранилища
+type Middleware func(next domain.Storage) domain.Storage
+
+// Chain оборачивает хранилище: первый middleware оказывается внешним
+func Chain(storage domain.Storage, middlewares ...Middleware) domain.Storage {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		storage = middlewares[i](storage)
+	}
+	return storage
+}
+
+// Op вызываемый метод хранилища
+type Op struct {
+	Method string
+	// Write метод изменяет данные
+	Write bool
+}
+
+// Interceptor выполняет call (возможно, несколько раз или не выполняет вовсе)
+type Interceptor func(ctx context.Context, op Op, call func(ctx context.Context) error) error
+
+// Intercept декоратор, пропускающий каждый вызов хранилища через interceptor
+func Intercept(interceptor Interceptor) Middleware {
+	return func(next domain.Storage) domain.Storage {
+		return &intercepted{next: next, intercept: interceptor}
+	}
+}
+
+// intercepted хранилище, все методы которого проходят через intercept
+type intercepted struct {
+	next      domain.Storage
+	intercept Interceptor
+}
+
+var _ domain.Storage = (*intercepted)(nil)
+
+// Методы чтения и изменения
+var (
+	opGetPosts            = Op{Method: "GetPosts"}
+	opGetLatestPosts      = Op{Method: "GetLatestPosts"}
+	opGetPostByID         = Op{Method: "GetPostByID"}
+	opCreatePost          = Op{Method: "CreatePost", Write: true}
+	opAddComment          = Op{Method: "AddComment", Write: true}
+	opGetCommentsForPost  = Op{Method: "GetCommentsForPost"}
+	opGetLatestComments   = Op{Method: "GetLatestComments"}
+	opUpdatePost          = Op{Method: "UpdatePost", Write: true}
+	opUpdateComment       = Op{Method: "UpdateComment", Write: true}
+	opGetPostRevisions    = Op{Method: "GetPostRevisions"}
+	opGetCommentRevisions = Op{Method: "GetCommentRevisions"}
+	opDeletePost          = Op{Method: "DeletePost", Write: true}
+	opDeleteComment       = Op{Method: "DeleteComment", Write: true}
+	opRestorePost         = Op{Method: "RestorePost", Write: true}
+	opRestoreComment      = Op{Method: "RestoreComment", Write: true}
+	opPurgeDeleted        = Op{Method: "PurgeDeleted", Write: true}
+	opEraseAuthor         = Op{Method: "EraseAuthor", Write: true}
+	opExportAuthor        = Op{Method: "ExportAuthor"}
+	opExportRecords       = Op{Method: "ExportRecords"}
+	opImportRecords       = Op{Method: "ImportRecords", Write: true}
+)
+
+// GetPosts получение всех постов
+func (s *intercepted) GetPosts(ctx context.Context) (posts []*domain.Post, err error) {
+	err = s.intercept(ctx, opGetPosts, func(ctx context.Context) error {
+		posts, err = s.next.GetPosts(ctx)
+		return err
+	})
+	return posts, err
+}
+
+// GetLatestPosts последние посты
+func (s *intercepted) GetLatestPosts(ctx context.Context, authorID string, limit int) (posts []*domain.Post, err error) {
+	err = s.intercept(ctx, opGetLatestPosts, func(ctx context.Context) error {
+		posts, err = s.next.GetLatestPosts(ctx, authorID, limit)
+		return err
+	})
+	return posts, err
+}
+
+// GetPostByID получение поста по ID
+func (s *intercepted) GetPostByID(ctx context.Context, id string) (post *domain.Post, err error) {
+	err = s.intercept(ctx, opGetPostByID, func(ctx context.Context) error {
+		post, err = s.next.GetPostByID(ctx, id)
+		return err
+	})
+	return post, err
+}
+
+// CreatePost создание поста
+func (s *intercepted) CreatePost(ctx context.Context, title, content, authorID string, commentsDisabled bool) (post *domain.Post, err error) {
+	err = s.intercept(ctx, opCreatePost, func(ctx context.Context) error {
+		post, err = s.next.CreatePost(ctx, title, content, authorID, commentsDisabled)
+		return err
+	})
+	return post, err
+}
+
+// AddComment добавление комментария
+func (s *intercepted) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (comment *domain.Comment, err error) {
+	err = s.intercept(ctx, opAddComment, func(ctx context.Context) error {
+		comment, err = s.next.AddComment(ctx, postID, parentID, authorID, content)
+		return err
+	})
+	return comment, err
+}
+
+// GetCommentsForPost получение комментариев поста с пагинацией
+func (s *intercepted) GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) (comments []*domain.CommentWithReplies, err error) {
+	err = s.intercept(ctx, opGetCommentsForPost, func(ctx context.Context) error {
+		comments, err = s.next.GetCommentsForPost(ctx, postID, limit, cursor)
+		return err
+	})
+	return comments, err
+}
+
+// GetLatestComments последние комментарии поста
+func (s *intercepted) GetLatestComments(ctx context.Context, postID string, limit int) (comments []*domain.Comment, err error) {
+	err = s.intercept(ctx, opGetLatestComments, func(ctx context.Context) error {
+		comments, err = s.next.GetLatestComments(ctx, postID, limit)
+		return err
+	})
+	return comments, err
+}
+
+// UpdatePost изменение поста
+func (s *intercepted) UpdatePost(ctx context.Context, id string, expectedVersion int32, title, content *string, editorID string, reason *string) (post *domain.Post, err error) {
+	err = s.intercept(ctx, opUpdatePost, func(ctx context.Context) error {
+		post, err = s.next.UpdatePost(ctx, id, expectedVersion, title, content, editorID, reason)
+		return err
+	})
+	return post, err
+}
+
+// UpdateComment изменение комментария
+func (s *intercepted) UpdateComment(ctx context.Context, id string, expectedVersion int32, content string, editorID string, reason *string) (comment *domain.Comment, err error) {
+	err = s.intercept(ctx, opUpdateComment, func(ctx context.Context) error {
+		comment, err = s.next.UpdateComment(ctx, id, expectedVersion, content, editorID, reason)
+		return err
+	})
+	return comment, err
+}
+
+// GetPostRevisions история изменений поста
+func (s *intercepted) GetPostRevisions(ctx context.Context, postID string, limit int, after *int32) (revs []*domain.Revision, err error) {
+	err = s.intercept(ctx, opGetPostRevisions, func(ctx context.Context) error {
+		revs, err = s.next.GetPostRevisions(ctx, postID, limit, after)
+		return err
+	})
+	return revs, err
+}
+
+// GetCommentRevisions история изменений комментария
+func (s *intercepted) GetCommentRevisions(ctx context.Context, commentID string, limit int, after *int32) (revs []*domain.Revision, err error) {
+	err = s.intercept(ctx, opGetCommentRevisions, func(ctx context.Context) error {
+		revs, err = s.next.GetCommentRevisions(ctx, commentID, limit, after)
+		return err
+	})
+	return revs, err
+}
+
+// DeletePost мягкое удаление поста
+func (s *intercepted) DeletePost(ctx context.Context, id, deletedBy string) (post *domain.Post, err error) {
+	err = s.intercept(ctx, opDeletePost, func(ctx context.Context) error {
+		post, err = s.next.DeletePost(ctx, id, deletedBy)
+		return err
+	})
+	return post, err
+}
+
+// DeleteComment мягкое удаление комментария
+func (s *intercepted) DeleteComment(ctx context.Context, id, deletedBy string) (comment *domain.Comment, err error) {
+	err = s.intercept(ctx, opDeleteComment, func(ctx context.Context) error {
+		comment, err = s.next.DeleteComment(ctx, id, deletedBy)
+		return err
+	})
+	return comment, err
+}
+
+// RestorePost восстановление поста
+func (s *intercepted) RestorePost(ctx context.Context, id string) (post *domain.Post, err error) {
+	err = s.intercept(ctx, opRestorePost, func(ctx context.Context) error {
+		post, err = s.next.RestorePost(ctx, id)
+		return err
+	})
+	return post, err
+}
+
+// RestoreComment восстановление комментария
+func (s *intercepted) RestoreComment(ctx context.Context, id string) (comment *domain.Comment, err error) {
+	err = s.intercept(ctx, opRestoreComment, func(ctx context.Context) error {
+		comment, err = s.next.RestoreComment(ctx, id)
+		return err
+	})
+	return comment, err
+}
+
+// PurgeDeleted окончательное удаление старых удаленных записей
+func (s *intercepted) PurgeDeleted(ctx context.Context, before time.Time) (n int64, err error) {
+	err = s.intercept(ctx, opPurgeDeleted, func(ctx context.Context) error {
+		n, err = s.next.PurgeDeleted(ctx, before)
+		return err
+	})
+	return n, err
+}
+
+// EraseAuthor удаление данных автора
+func (s *intercepted) EraseAuthor(ctx context.Context, authorID string, mode domain.ErasureMode) (result *domain.ErasureResult, err error) {
+	err = s.intercept(ctx, opEraseAuthor, func(ctx context.Context) error {
+		result, err = s.next.EraseAuthor(ctx, authorID, mode)
+		return err
+	})
+	return result, err
+}
+
+// ExportAuthor выгрузка данных автора
+func (s *intercepted) ExportAuthor(ctx context.Context, authorID string) (archive *domain.AuthorArchive, err error) {
+	err = s.intercept(ctx, opExportAuthor, func(ctx context.Context) error {
+		archive, err = s.next.ExportAuthor(ctx, authorID)
+		return err
+	})
+	return archive, err
+}
+
+// ExportRecords страница выгрузки
+func (s *intercepted) ExportRecords(ctx context.Context, limit int, after *domain.RecordCursor) (records []*domain.Record, err error) {
+	err = s.intercept(ctx, opExportRecords, func(ctx context.Context) error {
+		records, err = s.next.ExportRecords(ctx, limit, after)
+		return err
+	})
+	return records, err
+}
+
+// ImportRecords загрузка записей
+func (s *intercepted) ImportRecords(ctx context.Context, records []*domain.Record) (n int64, err error) {
+	err = s.intercept(ctx, opImportRecords, func(ctx context.Context) error {
+		n, err = s.next.ImportRecords(ctx, records)
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,140 @@
+package storagemw
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+var errBackend = errors.New("backend failure")
+
+// fakeStorage хранилище для тестов: считает вызовы и возвращает заданные ошибки.
+// Методы, которые тесты не используют, паникуют через nil domain.Storage.
+type fakeStorage struct {
+	domain.Storage
+
+	mu    sync.Mutex
+	calls map[string]int
+	// errs ошибки, которые вернут следующие вызовы метода, по очереди
+	errs map[string][]error
+	// deadlines был ли у контекста дедлайн при последнем вызове метода
+	deadlines map[string]bool
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{calls: map[string]int{}, errs: map[string][]error{}, deadlines: map[string]bool{}}
+}
+
+// failNext задает ошибки следующих вызовов метода
+func (f *fakeStorage) failNext(method string, errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs[method] = append(f.errs[method], errs...)
+}
+
+// count сколько раз вызывался метод
+func (f *fakeStorage) count(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// call учитывает вызов и возвращает очередную ошибку
+func (f *fakeStorage) call(ctx context.Context, method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls[method]++
+	_, f.deadlines[method] = ctx.Deadline()
+
+	if errs := f.errs[method]; len(errs) > 0 {
+		f.errs[method] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
+
+func (f *fakeStorage) GetPostByID(ctx context.Context, id string) (*domain.Post, error) {
+	if err := f.call(ctx, "GetPostByID"); err != nil {
+		return nil, err
+	}
+	return &domain.Post{ID: id, Version: int32(f.count("GetPostByID"))}, nil
+}
+
+func (f *fakeStorage) GetCommentsForPost(ctx context.Context, postID string, _ int, _ *string) ([]*domain.CommentWithReplies, error) {
+	if err := f.call(ctx, "GetCommentsForPost"); err != nil {
+		return nil, err
+	}
+	return []*domain.CommentWithReplies{{ID: "c1", PostID: postID}}, nil
+}
+
+func (f *fakeStorage) CreatePost(ctx context.Context, title, content, authorID string, _ bool) (*domain.Post, error) {
+	if err := f.call(ctx, "CreatePost"); err != nil {
+		return nil, err
+	}
+	return &domain.Post{ID: "p-new", Title: title, Content: content, AuthorID: authorID, Version: 1}, nil
+}
+
+func (f *fakeStorage) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*domain.Comment, error) {
+	if err := f.call(ctx, "AddComment"); err != nil {
+		return nil, err
+	}
+	return &domain.Comment{ID: "c-new", PostID: postID, ParentID: parentID, AuthorID: authorID, Content: content, Version: 1}, nil
+}
+
+func (f *fakeStorage) UpdateComment(ctx context.Context, id string, _ int32, content string, _ string, _ *string) (*domain.Comment, error) {
+	if err := f.call(ctx, "UpdateComment"); err != nil {
+		return nil, err
+	}
+	return &domain.Comment{ID: id, PostID: "p1", Content: content, Version: 2}, nil
+}
+
+func (f *fakeStorage) EraseAuthor(ctx context.Context, authorID string, mode domain.ErasureMode) (*domain.ErasureResult, error) {
+	if err := f.call(ctx, "EraseAuthor"); err != nil {
+		return nil, err
+	}
+	return &domain.ErasureResult{AuthorID: authorID, Mode: mode}, nil
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	record := func(name string) Middleware {
+		return Intercept(func(ctx context.Context, op Op, call func(ctx context.Context) error) error {
+			order = append(order, name)
+			return call(ctx)
+		})
+	}
+
+	s := Chain(newFakeStorage(), record("outer"), record("inner"))
+	if _, err := s.GetPostByID(context.Background(), "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Fatalf("order = %v, want [outer inner]", order)
+	}
+}
+
+func TestInterceptPassesResults(t *testing.T) {
+	var ops []Op
+	s := Intercept(func(ctx context.Context, op Op, call func(ctx context.Context) error) error {
+		ops = append(ops, op)
+		return call(ctx)
+	})(newFakeStorage())
+
+	comment, err := s.AddComment(context.Background(), "p1", nil, "a1", "text")
+	if err != nil || comment.PostID != "p1" || comment.Content != "text" {
+		t.Fatalf("AddComment = %+v, %v", comment, err)
+	}
+	if _, err := s.GetPostByID(context.Background(), "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+
+	want := []Op{{Method: "AddComment", Write: true}, {Method: "GetPostByID"}}
+	if len(ops) != len(want) || ops[0] != want[0] || ops[1] != want[1] {
+		t.Fatalf("ops = %+v, want %+v", ops, want)
+	}
+}
@@ -0,0 +1,33 @@
+package storagemw
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts ограничение времени методов хранилища
+type Timeouts struct {
+	// Default таймаут методов, которых нет в PerMethod; 0 — без ограничения
+	Default time.Duration
+	// PerMethod таймауты отдельных методов ("GetPosts", "EraseAuthor", ...)
+	PerMethod map[string]time.Duration
+}
+
+// Timeout ограничивает каждый вызов хранилища дедлайном контекста.
+// Дедлайн запроса, если он раньше, остается в силе.
+func Timeout(timeouts Timeouts) Middleware {
+	return Intercept(func(ctx context.Context, op Op, call func(ctx context.Context) error) error {
+		d, ok := timeouts.PerMethod[op.Method]
+		if !ok {
+			d = timeouts.Default
+		}
+		if d <= 0 {
+			return call(ctx)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		return call(ctx)
+	})
+}
@@ -0,0 +1,51 @@
+package storagemw
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	fake := newFakeStorage()
+	s := Timeout(Timeouts{
+		Default:   time.Second,
+		PerMethod: map[string]time.Duration{"CreatePost": 0},
+	})(fake)
+
+	if _, err := s.GetPostByID(context.Background(), "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if _, err := s.CreatePost(context.Background(), "t", "c", "a1", false); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+
+	if !fake.deadlines["GetPostByID"] {
+		t.Error("GetPostByID has no deadline, want default timeout")
+	}
+	if fake.deadlines["CreatePost"] {
+		t.Error("CreatePost has a deadline, want none (0 disables timeout)")
+	}
+}
+
+func TestTimeoutKeepsEarlierDeadline(t *testing.T) {
+	var deadline time.Time
+	s := Chain(newFakeStorage(),
+		Timeout(Timeouts{Default: time.Hour}),
+		Intercept(func(ctx context.Context, op Op, call func(ctx context.Context) error) error {
+			deadline, _ = ctx.Deadline()
+			return call(ctx)
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	want, _ := ctx.Deadline()
+
+	if _, err := s.GetPostByID(ctx, "p1"); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if !deadline.Equal(want) {
+		t.Errorf("deadline = %v, want request deadline %v", deadline, want)
+	}
+}
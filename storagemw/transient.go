@@ -0,0 +1,62 @@
+package storagemw
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/jackc/pgconn"
+	"modernc.org/sqlite"
+)
+
+// PostgresTransient классификация ошибок postgres для Retry
+func PostgresTransient(err error) Transient {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return NotTransient
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		// serialization_failure, deadlock_detected: транзакция откатена целиком
+		case pgErr.Code == "40001" || pgErr.Code == "40P01":
+			return TransientAny
+		// too_many_connections, cannot_connect_now: запрос не начинал выполняться
+		case pgErr.Code == "53300" || pgErr.Code == "57P03":
+			return TransientAny
+		// connection_exception, admin_shutdown, crash_shutdown
+		case strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01" || pgErr.Code == "57P02":
+			return TransientRead
+		default:
+			return NotTransient
+		}
+	}
+
+	// Ошибка до отправки запроса на сервер
+	if pgconn.SafeToRetry(err) {
+		return TransientAny
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return TransientRead
+	}
+
+	return NotTransient
+}
+
+// SQLiteTransient классификация ошибок sqlite для Retry: база занята другой записью
+func SQLiteTransient(err error) Transient {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// SQLITE_BUSY и SQLITE_LOCKED с расширенными кодами
+		if code := sqliteErr.Code() & 0xff; code == 5 || code == 6 {
+			return TransientAny
+		}
+	}
+
+	return NotTransient
+}
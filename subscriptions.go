@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// subscriptionTracker отслеживает websocket-подписки, чтобы закрыть их при остановке.
+// http.Server.Shutdown не ждет hijacked-соединения, поэтому делаем это сами.
+type subscriptionTracker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu упорядочивает wg.Add новых сокетов и closeAll, чтобы wait не гонялся с Add
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// newSubscriptionTracker создает новый экземпляр subscriptionTracker
+func newSubscriptionTracker() *subscriptionTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &subscriptionTracker{ctx: ctx, cancel: cancel}
+}
+
+// wrap привязывает контекст websocket-запросов к остановке сервера.
+// gqlgen закрывает сокет с кодом 1000, когда контекст запроса отменен.
+func (t *subscriptionTracker) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !t.add() {
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer t.wg.Done()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		stop := context.AfterFunc(t.ctx, cancel)
+		defer stop()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// add регистрирует новый сокет; false, если остановка уже началась
+func (t *subscriptionTracker) add() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return false
+	}
+	t.wg.Add(1)
+	return true
+}
+
+// closeAll закрывает все активные подписки и отклоняет новые
+func (t *subscriptionTracker) closeAll() {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+
+	t.cancel()
+}
+
+// wait дожидается закрытия всех подписок или истечения контекста
+func (t *subscriptionTracker) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
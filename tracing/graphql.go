@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer gqlgen-расширение: спан на операцию и на каждый резолвер, не являющийся простым полем структуры
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = Tracer{}
+
+// NewTracer создает расширение для handler.Server.Use
+func NewTracer() Tracer {
+	return Tracer{tracer: otel.Tracer(instrumentationName)}
+}
+
+// ExtensionName имя расширения
+func (Tracer) ExtensionName() string {
+	return "OpenTelemetryTracing"
+}
+
+// Validate проверка схемы не требуется
+func (Tracer) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse открывает спан операции
+func (t Tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	name, typ := "unknown", "unknown"
+	if graphql.HasOperationContext(ctx) {
+		if op := graphql.GetOperationContext(ctx).Operation; op != nil {
+			name, typ = op.Name, string(op.Operation)
+		}
+	}
+
+	spanName := "graphql." + typ
+	if name != "" {
+		spanName += " " + name
+	}
+
+	ctx, span := t.tracer.Start(ctx, spanName, trace.WithAttributes(
+		attribute.String("graphql.operation.name", name),
+		attribute.String("graphql.operation.type", typ),
+	))
+	defer span.End()
+
+	resp := next(ctx)
+	if resp != nil && len(resp.Errors) > 0 {
+		span.SetStatus(codes.Error, resp.Errors.Error())
+	}
+
+	return resp
+}
+
+// InterceptField открывает спан для резолверов; тривиальные поля структур пропускаются
+func (t Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := t.tracer.Start(ctx, fmt.Sprintf("%s.%s", fc.Object, fc.Field.Name), trace.WithAttributes(
+		attribute.String("graphql.field.path", fc.Path().String()),
+	))
+	defer span.End()
+
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return res, err
+}
@@ -0,0 +1,199 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Storage декоратор domain.Storage, открывающий дочерний спан на каждый метод
+type Storage struct {
+	next   domain.Storage
+	tracer trace.Tracer
+}
+
+var _ domain.Storage = (*Storage)(nil)
+
+// WrapStorage оборачивает хранилище
+func WrapStorage(next domain.Storage) *Storage {
+	return &Storage{next: next, tracer: otel.Tracer(instrumentationName)}
+}
+
+// start открывает спан метода хранилища
+func (s *Storage) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "storage."+method, trace.WithAttributes(attrs...))
+}
+
+// end закрывает спан, отмечая ошибку
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// GetPosts получение всех постов
+func (s *Storage) GetPosts(ctx context.Context) ([]*domain.Post, error) {
+	ctx, span := s.start(ctx, "GetPosts")
+	posts, err := s.next.GetPosts(ctx)
+	end(span, err)
+	return posts, err
+}
+
+// GetLatestPosts последние посты
+func (s *Storage) GetLatestPosts(ctx context.Context, authorID string, limit int) ([]*domain.Post, error) {
+	ctx, span := s.start(ctx, "GetLatestPosts", attribute.Int("limit", limit))
+	posts, err := s.next.GetLatestPosts(ctx, authorID, limit)
+	end(span, err)
+	return posts, err
+}
+
+// GetPostByID получение поста по ID
+func (s *Storage) GetPostByID(ctx context.Context, id string) (*domain.Post, error) {
+	ctx, span := s.start(ctx, "GetPostByID", attribute.String("post.id", id))
+	post, err := s.next.GetPostByID(ctx, id)
+	end(span, err)
+	return post, err
+}
+
+// CreatePost создание поста
+func (s *Storage) CreatePost(ctx context.Context, title, content, authorID string, commentsDisabled bool) (*domain.Post, error) {
+	ctx, span := s.start(ctx, "CreatePost")
+	post, err := s.next.CreatePost(ctx, title, content, authorID, commentsDisabled)
+	end(span, err)
+	return post, err
+}
+
+// AddComment добавление комментария
+func (s *Storage) AddComment(ctx context.Context, postID string, parentID *string, authorID string, content string) (*domain.Comment, error) {
+	ctx, span := s.start(ctx, "AddComment", attribute.String("post.id", postID))
+	comment, err := s.next.AddComment(ctx, postID, parentID, authorID, content)
+	end(span, err)
+	return comment, err
+}
+
+// GetCommentsForPost получение комментариев поста с пагинацией
+func (s *Storage) GetCommentsForPost(ctx context.Context, postID string, limit int, cursor *string) ([]*domain.CommentWithReplies, error) {
+	ctx, span := s.start(ctx, "GetCommentsForPost", attribute.String("post.id", postID), attribute.Int("limit", limit))
+	comments, err := s.next.GetCommentsForPost(ctx, postID, limit, cursor)
+	end(span, err)
+	return comments, err
+}
+
+// GetLatestComments последние комментарии поста
+func (s *Storage) GetLatestComments(ctx context.Context, postID string, limit int) ([]*domain.Comment, error) {
+	ctx, span := s.start(ctx, "GetLatestComments", attribute.String("post.id", postID), attribute.Int("limit", limit))
+	comments, err := s.next.GetLatestComments(ctx, postID, limit)
+	end(span, err)
+	return comments, err
+}
+
+// UpdatePost изменение поста
+func (s *Storage) UpdatePost(ctx context.Context, id string, expectedVersion int32, title, content *string, editorID string, reason *string) (*domain.Post, error) {
+	ctx, span := s.start(ctx, "UpdatePost", attribute.String("post.id", id))
+	post, err := s.next.UpdatePost(ctx, id, expectedVersion, title, content, editorID, reason)
+	end(span, err)
+	return post, err
+}
+
+// UpdateComment изменение комментария
+func (s *Storage) UpdateComment(ctx context.Context, id string, expectedVersion int32, content string, editorID string, reason *string) (*domain.Comment, error) {
+	ctx, span := s.start(ctx, "UpdateComment", attribute.String("comment.id", id))
+	comment, err := s.next.UpdateComment(ctx, id, expectedVersion, content, editorID, reason)
+	end(span, err)
+	return comment, err
+}
+
+// GetPostRevisions история изменений поста
+func (s *Storage) GetPostRevisions(ctx context.Context, postID string, limit int, after *int32) ([]*domain.Revision, error) {
+	ctx, span := s.start(ctx, "GetPostRevisions", attribute.String("post.id", postID), attribute.Int("limit", limit))
+	revisions, err := s.next.GetPostRevisions(ctx, postID, limit, after)
+	end(span, err)
+	return revisions, err
+}
+
+// GetCommentRevisions история изменений комментария
+func (s *Storage) GetCommentRevisions(ctx context.Context, commentID string, limit int, after *int32) ([]*domain.Revision, error) {
+	ctx, span := s.start(ctx, "GetCommentRevisions", attribute.String("comment.id", commentID), attribute.Int("limit", limit))
+	revisions, err := s.next.GetCommentRevisions(ctx, commentID, limit, after)
+	end(span, err)
+	return revisions, err
+}
+
+// DeletePost мягкое удаление поста
+func (s *Storage) DeletePost(ctx context.Context, id, deletedBy string) (*domain.Post, error) {
+	ctx, span := s.start(ctx, "DeletePost", attribute.String("post.id", id))
+	post, err := s.next.DeletePost(ctx, id, deletedBy)
+	end(span, err)
+	return post, err
+}
+
+// DeleteComment мягкое удаление комментария
+func (s *Storage) DeleteComment(ctx context.Context, id, deletedBy string) (*domain.Comment, error) {
+	ctx, span := s.start(ctx, "DeleteComment", attribute.String("comment.id", id))
+	comment, err := s.next.DeleteComment(ctx, id, deletedBy)
+	end(span, err)
+	return comment, err
+}
+
+// RestorePost восстановление поста
+func (s *Storage) RestorePost(ctx context.Context, id string) (*domain.Post, error) {
+	ctx, span := s.start(ctx, "RestorePost", attribute.String("post.id", id))
+	post, err := s.next.RestorePost(ctx, id)
+	end(span, err)
+	return post, err
+}
+
+// RestoreComment восстановление комментария
+func (s *Storage) RestoreComment(ctx context.Context, id string) (*domain.Comment, error) {
+	ctx, span := s.start(ctx, "RestoreComment", attribute.String("comment.id", id))
+	comment, err := s.next.RestoreComment(ctx, id)
+	end(span, err)
+	return comment, err
+}
+
+// PurgeDeleted окончательное удаление устаревших записей
+func (s *Storage) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := s.start(ctx, "PurgeDeleted")
+	purged, err := s.next.PurgeDeleted(ctx, before)
+	end(span, err)
+	return purged, err
+}
+
+// EraseAuthor удаление данных автора
+func (s *Storage) EraseAuthor(ctx context.Context, authorID string, mode domain.ErasureMode) (*domain.ErasureResult, error) {
+	ctx, span := s.start(ctx, "EraseAuthor", attribute.String("erasure.mode", string(mode)))
+	result, err := s.next.EraseAuthor(ctx, authorID, mode)
+	end(span, err)
+	return result, err
+}
+
+// ExportAuthor выгрузка данных автора
+func (s *Storage) ExportAuthor(ctx context.Context, authorID string) (*domain.AuthorArchive, error) {
+	ctx, span := s.start(ctx, "ExportAuthor")
+	archive, err := s.next.ExportAuthor(ctx, authorID)
+	end(span, err)
+	return archive, err
+}
+
+// ExportRecords страница выгрузки
+func (s *Storage) ExportRecords(ctx context.Context, limit int, after *domain.RecordCursor) ([]*domain.Record, error) {
+	ctx, span := s.start(ctx, "ExportRecords", attribute.Int("limit", limit))
+	records, err := s.next.ExportRecords(ctx, limit, after)
+	end(span, err)
+	return records, err
+}
+
+// ImportRecords загрузка записей
+func (s *Storage) ImportRecords(ctx context.Context, records []*domain.Record) (int64, error) {
+	ctx, span := s.start(ctx, "ImportRecords", attribute.Int("records", len(records)))
+	imported, err := s.next.ImportRecords(ctx, records)
+	end(span, err)
+	return imported, err
+}
@@ -0,0 +1,40 @@
+package transfer
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/logging"
+)
+
+// ExportHandler отдает все записи хранилища в формате NDJSON по мере чтения страниц.
+// Выгрузка может идти дольше WriteTimeout сервера, поэтому дедлайн записи снимается.
+func ExportHandler(storage domain.Storage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		logger := logging.FromContext(r.Context())
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			logger.Warn("failed to clear write deadline for export", slog.Any("error", err))
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="forozon.ndjson"`)
+
+		stats, err := Export(r.Context(), storage, w, DefaultPageSize)
+		if err != nil {
+			// Заголовки уже отправлены: соединение обрывается, чтобы клиент
+			// не принял неполную выгрузку за целую
+			logger.Error("export failed", slog.Any("error", err))
+			panic(http.ErrAbortHandler)
+		}
+
+		logger.Info("export finished", slog.Int64("posts", stats.Posts), slog.Int64("comments", stats.Comments))
+	})
+}
@@ -0,0 +1,108 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/YakovlevIgA/forozon/domain"
+	"github.com/YakovlevIgA/forozon/logging"
+)
+
+// maxErrors сколько ошибок записей собирает проверка без сохранения
+const maxErrors = 100
+
+// Options параметры загрузки
+type Options struct {
+	// DryRun только проверить записи, ничего не сохраняя
+	DryRun bool
+	// BatchSize сколько записей сохраняется одной транзакцией; <= 0 — DefaultBatchSize
+	BatchSize int
+}
+
+// Result итог загрузки
+type Result struct {
+	// Stats прочитанные записи без ошибок
+	Stats
+	// Imported сохраненные записи
+	Imported int64
+	// Skipped записи, id которых уже были в хранилище
+	Skipped int64
+	// Errors ошибки записей. Загрузка останавливается на первой ошибке, проверка без
+	// сохранения — после maxErrors ошибок.
+	Errors []*RecordError
+}
+
+// Import проверяет записи src и сохраняет их в storage партиями по BatchSize.
+// Ошибки записей возвращаются в Result.Errors, ошибки чтения и хранилища — как error.
+// Партии, сохраненные до ошибки, остаются в хранилище: после исправления загрузку
+// можно запустить с начала, уже сохраненные записи будут пропущены.
+func Import(ctx context.Context, storage domain.Storage, src Source, opts Options) (*Result, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	logger := logging.FromContext(ctx)
+	result := &Result{}
+	v := newValidator()
+
+	var batch []*domain.Record
+	flush := func(n int) error {
+		if opts.DryRun || len(batch) == 0 {
+			batch = nil
+			return nil
+		}
+
+		imported, err := storage.ImportRecords(ctx, batch)
+		if err != nil {
+			return err
+		}
+		result.Imported += imported
+		result.Skipped += int64(len(batch)) - imported
+		batch = nil
+
+		logger.Info("import batch saved",
+			slog.Int("records", n),
+			slog.Int64("imported", result.Imported),
+			slog.Int64("skipped", result.Skipped),
+		)
+		return nil
+	}
+
+	n := 0
+	for {
+		record, err := src.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		n++
+
+		var recordErr *RecordError
+		switch {
+		case errors.As(err, &recordErr):
+		case err != nil:
+			return result, err
+		default:
+			recordErr = v.check(n, record)
+		}
+
+		if recordErr != nil {
+			result.Errors = append(result.Errors, recordErr)
+			if !opts.DryRun || len(result.Errors) >= maxErrors {
+				return result, nil
+			}
+			continue
+		}
+
+		result.add(record)
+		batch = append(batch, record)
+		if len(batch) >= opts.BatchSize {
+			if err := flush(n); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	return result, flush(n)
+}
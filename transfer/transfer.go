@@ -0,0 +1,155 @@
+// Package transfer выгрузка и загрузка постов и комментариев в формате NDJSON: по записи
+// domain.Record на строку, посты в порядке создания, за каждым постом его комментарии,
+// родитель раньше ответов
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+const (
+	// DefaultPageSize сколько постов читается из хранилища за раз
+	DefaultPageSize = 100
+	// DefaultBatchSize сколько записей сохраняется одной транзакцией
+	DefaultBatchSize = 500
+)
+
+// Source поток записей; Next возвращает io.EOF после последней записи
+type Source interface {
+	Next(ctx context.Context) (*domain.Record, error)
+}
+
+// Stats сколько записей каждого вида прочитано или записано
+type Stats struct {
+	Posts    int64
+	Comments int64
+}
+
+func (s *Stats) add(r *domain.Record) {
+	if r.Type == domain.RecordPost {
+		s.Posts++
+	} else {
+		s.Comments++
+	}
+}
+
+// Decoder читает записи NDJSON. Номер записи совпадает с номером строки,
+// поэтому пустые строки считаются ошибкой.
+type Decoder struct {
+	r    *bufio.Reader
+	line int
+}
+
+// NewDecoder читает записи из r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Next следующая запись; ошибка разбора строки возвращается как *RecordError,
+// и чтение можно продолжить со следующей строки
+func (d *Decoder) Next(ctx context.Context) (*domain.Record, error) {
+	line, err := d.r.ReadBytes('\n')
+	if len(line) == 0 && err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	d.line++
+
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil, &RecordError{Record: d.line, Message: "empty line"}
+	}
+
+	var record domain.Record
+	if err := json.Unmarshal(line, &record); err != nil {
+		return nil, &RecordError{Record: d.line, Message: fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	return &record, nil
+}
+
+// storageSource записи хранилища, прочитанные страницами по pageSize постов
+type storageSource struct {
+	storage  domain.Storage
+	pageSize int
+	page     []*domain.Record
+	after    *domain.RecordCursor
+	done     bool
+}
+
+// FromStorage все записи хранилища, включая удаленные; pageSize <= 0 — DefaultPageSize
+func FromStorage(storage domain.Storage, pageSize int) Source {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &storageSource{storage: storage, pageSize: pageSize}
+}
+
+// Next следующая запись; очередная страница читается, когда закончилась предыдущая
+func (s *storageSource) Next(ctx context.Context) (*domain.Record, error) {
+	if len(s.page) == 0 {
+		if s.done {
+			return nil, io.EOF
+		}
+
+		page, err := s.storage.ExportRecords(ctx, s.pageSize, s.after)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export records: %w", err)
+		}
+
+		posts := 0
+		for _, r := range page {
+			if r.Type == domain.RecordPost {
+				posts++
+				s.after = domain.CursorAfter(r.Post)
+			}
+		}
+		s.done = posts < s.pageSize
+		s.page = page
+
+		if len(page) == 0 {
+			return nil, io.EOF
+		}
+	}
+
+	record := s.page[0]
+	s.page = s.page[1:]
+	return record, nil
+}
+
+// Export пишет все записи хранилища в w в формате NDJSON
+func Export(ctx context.Context, storage domain.Storage, w io.Writer, pageSize int) (*Stats, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	src := FromStorage(storage, pageSize)
+	stats := &Stats{}
+	for {
+		record, err := src.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		if err := enc.Encode(record); err != nil {
+			return stats, fmt.Errorf("failed to write record: %w", err)
+		}
+		stats.add(record)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return stats, fmt.Errorf("failed to write record: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,147 @@
+package transfer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/YakovlevIgA/forozon/domain"
+)
+
+// RecordError ошибка в записи потока; для NDJSON номер записи — номер строки
+type RecordError struct {
+	Record  int
+	ID      string
+	Message string
+}
+
+func (e *RecordError) Error() string {
+	if e.ID != "" {
+		return fmt.Sprintf("record %d (%s): %s", e.Record, e.ID, e.Message)
+	}
+	return fmt.Sprintf("record %d: %s", e.Record, e.Message)
+}
+
+// validator проверяет записи по порядку: обязательные поля, время, историю изменений,
+// уникальность id и то, что пост идет раньше своих комментариев, а родитель — раньше ответов
+type validator struct {
+	posts    map[string]bool
+	comments map[string]string // id комментария -> id поста
+}
+
+func newValidator() *validator {
+	return &validator{
+		posts:    make(map[string]bool),
+		comments: make(map[string]string),
+	}
+}
+
+// problems ошибки одной записи
+type problems []string
+
+func (p *problems) add(format string, args ...any) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p *problems) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		p.add("%s is required", field)
+	}
+}
+
+func (p *problems) timestamp(field, value string) {
+	if _, err := domain.ParseTimestamp(value); err != nil {
+		p.add("%s: %v", field, err)
+	}
+}
+
+// check проверяет запись номер n и запоминает ее id. Id запоминается и у записи
+// с ошибками, чтобы ее комментарии не получали лишних ошибок порядка.
+func (v *validator) check(n int, r *domain.Record) *RecordError {
+	var p problems
+
+	switch {
+	case r.Type == domain.RecordPost && r.Post != nil && r.Comment == nil:
+		post := r.Post
+		p.required("post.id", post.ID)
+		p.required("post.title", post.Title)
+		p.required("post.content", post.Content)
+		p.required("post.authorID", post.AuthorID)
+		p.timestamp("post.createdAt", post.CreatedAt)
+		if post.DeletedAt != nil {
+			p.timestamp("post.deletedAt", *post.DeletedAt)
+		}
+		if post.Version < 1 {
+			p.add("post.version must be positive")
+		}
+		checkRevisions(&p, r.Revisions, post.Version, true)
+
+		if v.posts[post.ID] {
+			p.add("duplicate post id")
+		} else if post.ID != "" {
+			v.posts[post.ID] = true
+		}
+
+	case r.Type == domain.RecordComment && r.Comment != nil && r.Post == nil:
+		comment := r.Comment
+		p.required("comment.id", comment.ID)
+		p.required("comment.postID", comment.PostID)
+		p.required("comment.authorID", comment.AuthorID)
+		p.required("comment.content", comment.Content)
+		p.timestamp("comment.createdAt", comment.CreatedAt)
+		if comment.DeletedAt != nil {
+			p.timestamp("comment.deletedAt", *comment.DeletedAt)
+		}
+		if comment.Version < 1 {
+			p.add("comment.version must be positive")
+		}
+		checkRevisions(&p, r.Revisions, comment.Version, false)
+
+		if comment.PostID != "" && !v.posts[comment.PostID] {
+			p.add("post %s must precede its comments", comment.PostID)
+		}
+		if comment.ParentID != nil {
+			parentPostID, ok := v.comments[*comment.ParentID]
+			switch {
+			case !ok:
+				p.add("parent comment %s must precede its replies", *comment.ParentID)
+			case parentPostID != comment.PostID:
+				p.add("parent comment %s belongs to another post", *comment.ParentID)
+			}
+		}
+
+		if _, ok := v.comments[comment.ID]; ok {
+			p.add("duplicate comment id")
+		} else if comment.ID != "" {
+			v.comments[comment.ID] = comment.PostID
+		}
+
+	default:
+		p.add(`record must be {"type":"post","post":{...}} or {"type":"comment","comment":{...}}`)
+	}
+
+	if len(p) == 0 {
+		return nil
+	}
+	return &RecordError{Record: n, ID: r.ID(), Message: strings.Join(p, "; ")}
+}
+
+// checkRevisions история изменений: версии по возрастанию и меньше текущей,
+// заголовок есть только у версий постов
+func checkRevisions(p *problems, revisions []*domain.Revision, version int32, post bool) {
+	var last int32
+	for _, rev := range revisions {
+		if rev.Version <= last || rev.Version >= version {
+			p.add("revision %d: versions must increase and stay below the record version", rev.Version)
+		}
+		last = rev.Version
+
+		if post && rev.Title == nil {
+			p.add("revision %d: title is required", rev.Version)
+		}
+		if !post && rev.Title != nil {
+			p.add("revision %d: comment revisions have no title", rev.Version)
+		}
+		p.required(fmt.Sprintf("revision %d: editorID", rev.Version), rev.EditorID)
+		p.timestamp(fmt.Sprintf("revision %d: editedAt", rev.Version), rev.EditedAt)
+	}
+}